@@ -286,14 +286,14 @@ func runSwitchTo(accountName string) {
 
 	cwd, _ := os.Getwd()
 	if !git.IsGitRepo(cwd) {
-		ui.ShowError("Not in a git repository")
+		startActivity(cfg, "switch", accountName).fail("Not in a git repository")
 		return
 	}
 
 	manager := account.NewManager(cfg)
 	acc := manager.Find(accountName)
 	if acc == nil {
-		ui.ShowError(fmt.Sprintf("Account '%s' not found", accountName))
+		startActivity(cfg, "switch", accountName).fail(fmt.Sprintf("Account '%s' not found", accountName))
 		return
 	}
 
@@ -326,7 +326,7 @@ func runAddAccount(cfg *config.AppConfig) {
 	// Validate for duplicate name early
 	validator := account.NewDuplicateValidator(cfg.Accounts)
 	if validator.CheckNameDuplicate(name) {
-		ui.ShowError(fmt.Sprintf("Account with name '%s' already exists", name))
+		startActivity(cfg, "add", name).fail(fmt.Sprintf("Account with name '%s' already exists", name))
 		return
 	}
 
@@ -340,9 +340,25 @@ func runAddAccount(cfg *config.AppConfig) {
 		{Title: account.IconBitbucket + " Bitbucket", Description: "bitbucket.org", Value: account.PlatformBitbucket},
 		{Title: account.IconGitea + " Gitea", Description: "Self-hosted Gitea", Value: account.PlatformGitea},
 		{Title: account.IconCodeberg + " Codeberg", Description: "codeberg.org", Value: account.PlatformCodeberg},
-		{Title: account.IconOther + " Other", Description: "Other Git platform", Value: account.PlatformOther},
+		{Title: account.IconAzure + " Azure DevOps", Description: "dev.azure.com", Value: account.PlatformAzure},
+		{Title: account.IconCodeCommit + " AWS CodeCommit", Description: "git-codecommit.<region>.amazonaws.com", Value: account.PlatformCodeCommit},
+		{Title: account.IconSourceHut + " SourceHut", Description: "git.sr.ht", Value: account.PlatformSourceHut},
+	}
+
+	// User-defined platforms from the config file
+	for _, name := range account.GetCustomPlatforms() {
+		info := account.GetPlatformInfo(name)
+		platformItems = append(platformItems, ui.SelectorItem{
+			Title:       info.Icon + " " + info.Name,
+			Description: fmt.Sprintf("Custom platform (%s)", info.Domain),
+			Value:       name,
+		})
 	}
 
+	platformItems = append(platformItems, ui.SelectorItem{
+		Title: account.IconOther + " Other", Description: "Other Git platform", Value: account.PlatformOther,
+	})
+
 	platformIdx, err := ui.RunSelector("Select Platform", platformItems)
 	if err != nil {
 		ui.ShowError(fmt.Sprintf("Selection error: %v", err))
@@ -360,6 +376,10 @@ func runAddAccount(cfg *config.AppConfig) {
 	if platformType == account.PlatformGitea || platformType == account.PlatformOther {
 		customDomain = ui.Prompt("Custom domain (e.g., git.company.com)")
 	}
+	if platformType == account.PlatformCodeCommit {
+		region := ui.PromptWithDefault("AWS region", "us-east-1")
+		customDomain = fmt.Sprintf("git-codecommit.%s.amazonaws.com", region)
+	}
 
 	// Interactive method selection
 	methodItems := []ui.SelectorItem{
@@ -440,6 +460,11 @@ func runAddAccount(cfg *config.AppConfig) {
 				HostAlias: ui.PromptWithDefault("SSH host alias", fmt.Sprintf("%s-%s", platformType, name)),
 			}
 		}
+
+		// CodeCommit authenticates SSH by the key ID assigned in IAM
+		if acc.SSH != nil && platformType == account.PlatformCodeCommit {
+			acc.SSH.User = ui.Prompt("SSH Key ID from IAM (e.g., APKAEIBAERJR2EXAMPLE)")
+		}
 	}
 
 	if methodChoice == "2" || methodChoice == "3" {
@@ -470,17 +495,21 @@ func runAddAccount(cfg *config.AppConfig) {
 		}
 	}
 
+	act := startActivity(cfg, "add", name)
+	act.entry.Platform = platformType
+
 	manager := account.NewManager(cfg)
 	if err := manager.Add(acc); err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to add account: %v", err))
+		act.fail(fmt.Sprintf("Failed to add account: %v", err))
 		return
 	}
 
 	if err := config.Save(cfg); err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to save config: %v", err))
+		act.fail(fmt.Sprintf("Failed to save config: %v", err))
 		return
 	}
 
+	act.done(nil)
 	ui.ShowSuccess(fmt.Sprintf("Account '%s' added successfully", name))
 }
 
@@ -515,17 +544,23 @@ func runEditAccount(cfg *config.AppConfig) {
 	}
 
 	acc := &cfg.Accounts[idx]
+	oldName := acc.Name
 
 	fmt.Println()
 	acc.Name = ui.PromptWithDefault("Account label", acc.Name)
 	acc.GitUserName = ui.PromptWithDefault("Git user.name", acc.GitUserName)
 	acc.GitEmail = ui.PromptWithDefault("Git user.email", acc.GitEmail)
 
+	act := startActivity(cfg, "edit", acc.Name)
+	if acc.Name != oldName {
+		act.entry.Detail = fmt.Sprintf("renamed from %s", oldName)
+	}
 	if err := config.Save(cfg); err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to save config: %v", err))
+		act.fail(fmt.Sprintf("Failed to save config: %v", err))
 		return
 	}
 
+	act.done(nil)
 	ui.ShowSuccess(fmt.Sprintf("Account '%s' updated", acc.Name))
 }
 
@@ -566,16 +601,18 @@ func runRemoveAccount(cfg *config.AppConfig) {
 		return
 	}
 
+	act := startActivity(cfg, "remove", acc.Name)
 	manager := account.NewManager(cfg)
 	if err := manager.Remove(acc.Name); err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to remove account: %v", err))
+		act.fail(fmt.Sprintf("Failed to remove account: %v", err))
 		return
 	}
 
 	if err := config.Save(cfg); err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to save config: %v", err))
+		act.fail(fmt.Sprintf("Failed to save config: %v", err))
 		return
 	}
 
+	act.done(nil)
 	ui.ShowSuccess(fmt.Sprintf("Account '%s' removed", acc.Name))
 }
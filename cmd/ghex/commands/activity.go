@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dwirx/ghex/internal/account"
+	"github.com/dwirx/ghex/internal/config"
+	"github.com/dwirx/ghex/internal/ui"
+)
+
+// activity times an operation and records it in the activity log
+type activity struct {
+	cfg   *config.AppConfig
+	entry config.ActivityLogEntry
+	start time.Time
+}
+
+// startActivity starts timing an operation. cfg may be nil for commands
+// that don't load the configuration.
+func startActivity(cfg *config.AppConfig, action, accountName string) *activity {
+	return &activity{
+		cfg:   cfg,
+		entry: config.ActivityLogEntry{Action: action, AccountName: accountName},
+		start: time.Now(),
+	}
+}
+
+// done records the operation; a nil err means success
+func (a *activity) done(err error) {
+	cfg := a.cfg
+	if cfg == nil {
+		if cfg, _ = config.Load(); cfg == nil {
+			cfg = config.NewAppConfig()
+		}
+	}
+	account.NewManager(cfg).RecordActivity(a.entry, a.start, err)
+}
+
+// fail records the operation as failed and shows msg
+func (a *activity) fail(msg string) {
+	a.done(errors.New(msg))
+	ui.ShowError(msg)
+}
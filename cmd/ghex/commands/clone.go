@@ -15,11 +15,15 @@ func runClone(repoURL, targetDir string) {
 	ui.ShowTitle()
 	ui.ShowInfo(fmt.Sprintf("Cloning: %s", repoURL))
 
+	act := startActivity(cfg, "clone", "")
+	act.entry.RepoPath = repoURL
+
 	urlInfo, err := git.ParseURL(repoURL)
 	if err != nil {
-		ui.ShowError(fmt.Sprintf("Invalid URL: %v", err))
+		act.fail(fmt.Sprintf("Invalid URL: %v", err))
 		return
 	}
+	act.entry.RepoPath = fmt.Sprintf("%s/%s", urlInfo.Owner, urlInfo.Repo)
 
 	if len(cfg.Accounts) > 0 {
 		fmt.Println(ui.Primary("Select account (or press Enter to skip):"))
@@ -34,11 +38,13 @@ func runClone(repoURL, targetDir string) {
 
 		if idx > 0 && idx <= len(cfg.Accounts) {
 			acc := cfg.Accounts[idx-1]
+			act.entry.AccountName = acc.Name
 
 			spinner := ui.NewSpinner("Cloning repository...")
 			spinner.Start()
 
 			clonedDir, err := git.CloneWithIdentity(repoURL, targetDir, acc.GitUserName, acc.GitEmail)
+			act.done(err)
 			if err != nil {
 				spinner.StopWithError(fmt.Sprintf("Clone failed: %v", err))
 				return
@@ -67,6 +73,7 @@ func runClone(repoURL, targetDir string) {
 	spinner.Start()
 
 	clonedDir, err := git.Clone(repoURL, targetDir)
+	act.done(err)
 	if err != nil {
 		spinner.StopWithError(fmt.Sprintf("Clone failed: %v", err))
 		return
@@ -1,11 +1,15 @@
 package commands
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
-	"strings"
 
+	"github.com/dwirx/ghex/internal/account"
+	"github.com/dwirx/ghex/internal/api"
+	"github.com/dwirx/ghex/internal/config"
 	"github.com/dwirx/ghex/internal/ui"
+	"github.com/dwirx/ghex/internal/update"
 	"github.com/dwirx/ghex/pkg/download"
 	"github.com/spf13/cobra"
 )
@@ -13,16 +17,28 @@ import (
 // NewDlxCmd creates the dlx (download) command group
 func NewDlxCmd() *cobra.Command {
 	dlxCmd := &cobra.Command{
-		Use:   "dlx [url]",
-		Short: "Universal file downloader",
-		Long:  "Download files from any URL (HTTP/HTTPS) or Git repositories",
-		Args:  cobra.MaximumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		Use:           "dlx [url]",
+		Short:         "Universal file downloader",
+		Long:          "Download files from any URL (HTTP/HTTPS) or Git repositories",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
 				output, _ := cmd.Flags().GetString("output")
 				outputDir, _ := cmd.Flags().GetString("dir")
 				overwrite, _ := cmd.Flags().GetBool("overwrite")
 				showInfo, _ := cmd.Flags().GetBool("info")
+				connections, _ := cmd.Flags().GetInt("connections")
+				verify, err := verificationFromFlags(cmd)
+				if err != nil {
+					return err
+				}
+				accountName, _ := cmd.Flags().GetString("account")
+				headers, err := downloadAuthHeaders(accountName, args[0], false)
+				if err != nil {
+					return err
+				}
 
 				opts := download.Options{
 					Output:          output,
@@ -31,11 +47,15 @@ func NewDlxCmd() *cobra.Command {
 					ShowProgress:    true,
 					ShowInfo:        showInfo,
 					FollowRedirects: true,
+					Connections:     connections,
+					Headers:         headers,
+					Verify:          verify,
+					Extract:         extractFromFlags(cmd),
 				}
-				_ = download.FromURL(args[0], opts)
-			} else {
-				runDlxMenu()
+				return download.FromURL(args[0], opts)
 			}
+			runDlxMenu()
+			return nil
 		},
 	}
 
@@ -44,92 +64,125 @@ func NewDlxCmd() *cobra.Command {
 	dlxCmd.Flags().StringP("dir", "d", "", "Output directory")
 	dlxCmd.Flags().BoolP("overwrite", "w", false, "Overwrite existing files")
 	dlxCmd.Flags().BoolP("info", "i", false, "Show file info before download")
+	dlxCmd.Flags().IntP("connections", "c", 1, "Parallel connections for large files")
+	dlxCmd.Flags().String("account", "", "Authenticate with this account's token")
+	addVerifyFlags(dlxCmd)
+	addExtractFlags(dlxCmd)
 
 	// Subcommands
 	dlxCmd.AddCommand(newDlxFileCmd())
 	dlxCmd.AddCommand(newDlxDirCmd())
 	dlxCmd.AddCommand(newDlxReleaseCmd())
 	dlxCmd.AddCommand(newDlxListCmd())
+	dlxCmd.AddCommand(newDlxInstallCmd())
+	dlxCmd.AddCommand(newDlxUpgradeCmd())
+	dlxCmd.AddCommand(newDlxUninstallCmd())
 
 	return dlxCmd
 }
 
 func newDlxFileCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "file [url]",
-		Short: "Download a single file from Git repository",
-		Args:  cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		Use:           "file [url]",
+		Short:         "Download a single file from Git repository",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			branch, _ := cmd.Flags().GetString("branch")
 			output, _ := cmd.Flags().GetString("output")
 			outputDir, _ := cmd.Flags().GetString("dir")
+			accountName, _ := cmd.Flags().GetString("account")
+			headers, err := downloadAuthHeaders(accountName, args[0], true)
+			if err != nil {
+				return err
+			}
 
 			opts := download.GitOptions{
 				Branch:    branch,
 				Output:    output,
 				OutputDir: outputDir,
+				Headers:   headers,
 			}
-			if err := download.GitFile(args[0], opts); err != nil {
-				ui.ShowError(err.Error())
-			}
+			return download.GitFile(args[0], opts)
 		},
 	}
 
 	cmd.Flags().StringP("branch", "b", "", "Branch/tag/commit")
 	cmd.Flags().StringP("output", "o", "", "Output filename")
 	cmd.Flags().StringP("dir", "d", "", "Output directory")
+	addAccountFlag(cmd)
 
 	return cmd
 }
 
 func newDlxDirCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "dir [url]",
-		Short: "Download a directory from Git repository",
-		Args:  cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		Use:           "dir [url]",
+		Short:         "Download a directory from Git repository",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			branch, _ := cmd.Flags().GetString("branch")
 			outputDir, _ := cmd.Flags().GetString("dir")
 			depth, _ := cmd.Flags().GetInt("depth")
+			accountName, _ := cmd.Flags().GetString("account")
+			headers, err := downloadAuthHeaders(accountName, args[0], true)
+			if err != nil {
+				return err
+			}
 
 			opts := download.GitOptions{
 				Branch:    branch,
 				OutputDir: outputDir,
 				Depth:     depth,
+				Headers:   headers,
 			}
-			if err := download.GitDirectory(args[0], opts); err != nil {
-				ui.ShowError(err.Error())
-			}
+			return download.GitDirectory(args[0], opts)
 		},
 	}
 
 	cmd.Flags().StringP("branch", "b", "", "Branch/tag/commit")
 	cmd.Flags().StringP("dir", "d", "", "Output directory")
 	cmd.Flags().IntP("depth", "n", 10, "Max directory depth")
+	addAccountFlag(cmd)
 
 	return cmd
 }
 
 func newDlxReleaseCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "release [repo-url]",
-		Short: "Download release assets from GitHub",
-		Args:  cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		Use:           "release [repo-url]",
+		Short:         "Download release assets from GitHub",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			version, _ := cmd.Flags().GetString("version")
 			asset, _ := cmd.Flags().GetString("asset")
 			outputDir, _ := cmd.Flags().GetString("dir")
 			listOnly, _ := cmd.Flags().GetBool("list")
+			verify, err := verificationFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			accountName, _ := cmd.Flags().GetString("account")
+			headers, err := downloadAuthHeaders(accountName, args[0], true)
+			if err != nil {
+				return err
+			}
 
 			opts := download.ReleaseOptions{
 				Version:   version,
 				Asset:     asset,
 				OutputDir: outputDir,
 				ListOnly:  listOnly,
+				Verify:    verify,
+				Extract:   extractFromFlags(cmd),
+				Headers:   headers,
 			}
-			if err := download.GitRelease(args[0], opts); err != nil {
-				ui.ShowError(err.Error())
-			}
+			return download.GitRelease(args[0], opts)
 		},
 	}
 
@@ -137,45 +190,318 @@ func newDlxReleaseCmd() *cobra.Command {
 	cmd.Flags().StringP("asset", "a", "", "Asset name filter")
 	cmd.Flags().StringP("dir", "d", "", "Output directory")
 	cmd.Flags().BoolP("list", "l", false, "List assets only")
+	addAccountFlag(cmd)
+	addVerifyFlags(cmd)
+	addExtractFlags(cmd)
 
 	return cmd
 }
 
+// addAccountFlag adds the --account flag of dlx commands for git URLs
+func addAccountFlag(cmd *cobra.Command) {
+	cmd.Flags().String("account", "", "Authenticate with this account's token (default: the account matching the URL)")
+}
+
+// downloadAuthHeaders returns the headers authenticating a download of url
+// as the named account or, when match is set and no name is given, as the
+// configured account matching the URL's host and owner. It returns nil for
+// anonymous downloads.
+func downloadAuthHeaders(name, url string, match bool) (map[string]string, error) {
+	if name == "" && !match {
+		return nil, nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		if name == "" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if name == "" {
+		acc := account.NewManager(cfg).FindForURL(url)
+		if acc == nil {
+			return nil, nil
+		}
+		headers, err := accountAuthHeaders(cfg, acc.Name)
+		if err != nil {
+			// An unusable match just means downloading anonymously
+			return nil, nil
+		}
+		ui.ShowInfo(fmt.Sprintf("Authenticating as %s", acc.Name))
+		return headers, nil
+	}
+
+	headers, err := accountAuthHeaders(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	ui.ShowInfo(fmt.Sprintf("Authenticating as %s", name))
+	return headers, nil
+}
+
+// addExtractFlags adds the archive extraction flags shared by dlx commands
+func addExtractFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("extract", "x", false, "Extract downloaded archives (tar.gz, tar.xz, tar.bz2, tar.zst, zip)")
+	cmd.Flags().Int("strip-components", 0, "Strip leading path elements when extracting")
+	cmd.Flags().StringSlice("include", nil, "Only extract entries matching these globs")
+	cmd.Flags().StringSlice("exclude", nil, "Skip entries matching these globs when extracting")
+}
+
+// extractFromFlags returns the extraction requested by the extract flags,
+// or nil without --extract
+func extractFromFlags(cmd *cobra.Command) *download.ExtractOptions {
+	if extract, _ := cmd.Flags().GetBool("extract"); !extract {
+		return nil
+	}
+	opts := &download.ExtractOptions{}
+	opts.StripComponents, _ = cmd.Flags().GetInt("strip-components")
+	opts.Include, _ = cmd.Flags().GetStringSlice("include")
+	opts.Exclude, _ = cmd.Flags().GetStringSlice("exclude")
+	return opts
+}
+
+// addVerifyFlags adds the checksum flags shared by dlx commands
+func addVerifyFlags(cmd *cobra.Command) {
+	cmd.Flags().String(update.AlgorithmSHA256, "", "Expected SHA-256 checksum of the file")
+	cmd.Flags().String(update.AlgorithmSHA512, "", "Expected SHA-512 checksum of the file")
+	cmd.Flags().String(update.AlgorithmBLAKE2b, "", "Expected BLAKE2b checksum of the file")
+	cmd.Flags().String("checksums", "", "Checksums file (URL or path) to verify against")
+}
+
+// verificationFromFlags returns the verification requested by the checksum
+// flags, or nil when none was given
+func verificationFromFlags(cmd *cobra.Command) (*download.Verification, error) {
+	var verify *download.Verification
+	for _, algorithm := range []string{update.AlgorithmSHA256, update.AlgorithmSHA512, update.AlgorithmBLAKE2b} {
+		value, _ := cmd.Flags().GetString(algorithm)
+		if value == "" {
+			continue
+		}
+		if verify != nil {
+			return nil, fmt.Errorf("only one of --sha256, --sha512 and --blake2b can be used")
+		}
+		if _, err := hex.DecodeString(value); err != nil {
+			return nil, fmt.Errorf("invalid --%s checksum: %s", algorithm, value)
+		}
+		verify = &download.Verification{Algorithm: algorithm, Checksum: value}
+	}
+
+	checksums, _ := cmd.Flags().GetString("checksums")
+	if checksums == "" {
+		return verify, nil
+	}
+	if verify != nil {
+		return nil, fmt.Errorf("--checksums can't be combined with --%s", verify.Algorithm)
+	}
+	return download.LoadChecksums(checksums, download.DefaultOptions())
+}
+
 func newDlxListCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list [file]",
-		Short: "Download files from a URL list file",
-		Args:  cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			if err := downloadFromFileList(args[0]); err != nil {
-				ui.ShowError(err.Error())
-			}
+		Short: "Download files from a URL list or manifest",
+		Long: `Download files from a plain URL list (one per line) or a YAML/JSON manifest
+concurrently. Manifest entries can set url, output, dir, sha256, sha512,
+blake2b, headers, account and extract; top-level dir, headers and account
+apply to every entry. Entries whose file exists with a matching checksum
+are skipped. Exits non-zero when any download fails.`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batch := download.DefaultBatchOptions()
+			batch.Parallel, _ = cmd.Flags().GetInt("parallel")
+			batch.Retries, _ = cmd.Flags().GetInt("retries")
+			batch.FailuresFile, _ = cmd.Flags().GetString("failures-file")
+
+			return downloadFromFileList(args[0], batch)
 		},
 	}
+
+	cmd.Flags().IntP("parallel", "p", 4, "Number of concurrent downloads")
+	cmd.Flags().IntP("retries", "r", 2, "Retries per URL with exponential backoff")
+	cmd.Flags().StringP("failures-file", "f", "", "Write failed URLs to this file for another run")
+
+	return cmd
 }
 
-func downloadFromFileList(filePath string) error {
-	data, err := os.ReadFile(filePath)
+// downloadFromFileList downloads the entries of a URL list or manifest
+func downloadFromFileList(filePath string, batch download.BatchOptions) error {
+	manifest, err := download.LoadManifest(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file list: %w", err)
+		return err
 	}
 
-	lines := strings.Split(string(data), "\n")
-	var urls []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "#") {
-			urls = append(urls, line)
+	var cfg *config.AppConfig
+	items := make([]download.Item, 0, len(manifest.Downloads))
+	for i := range manifest.Downloads {
+		entry := &manifest.Downloads[i]
+		opts := download.DefaultOptions()
+		opts.Timeout = 0 // large files take longer than the default
+		opts.ShowProgress = true
+		opts.Output = entry.Output
+		opts.OutputDir = entry.Dir
+		opts.Verify = entry.Verification()
+		opts.Headers = make(map[string]string)
+		for k, v := range entry.Headers {
+			opts.Headers[k] = v
+		}
+
+		if entry.Account != "" {
+			if cfg == nil {
+				if cfg, err = config.Load(); err != nil {
+					return fmt.Errorf("failed to load config: %w", err)
+				}
+			}
+			headers, err := accountAuthHeaders(cfg, entry.Account)
+			if err != nil {
+				return fmt.Errorf("%s: %w", entry.URL, err)
+			}
+			for k, v := range headers {
+				opts.Headers[k] = v
+			}
+		}
+		if entry.Extract {
+			opts.Extract = &download.ExtractOptions{}
+		}
+
+		item := download.Item{URL: entry.URL, Options: opts}
+		if !manifest.Plain {
+			item.Entry = entry
 		}
+		items = append(items, item)
 	}
 
-	if len(urls) == 0 {
-		return fmt.Errorf("no URLs found in file list")
+	_, err = download.BatchItems(items, batch)
+	return err
+}
+
+func newDlxInstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install [owner/repo]",
+		Short: "Install a binary from a GitHub release",
+		Long: `Download the release asset built for this OS and architecture, verify it
+against the release checksums, extract the binary and place it into a bin
+directory (default: ~/.local/bin). Installed tools are recorded for
+"ghex dlx upgrade" and "ghex dlx uninstall"; --list shows them.`,
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			installer, err := download.NewInstaller()
+			if err != nil {
+				return err
+			}
+			if list, _ := cmd.Flags().GetBool("list"); list || len(args) == 0 {
+				showInstalledTools(installer.Registry)
+				return nil
+			}
+
+			var opts download.InstallOptions
+			opts.Version, _ = cmd.Flags().GetString("version")
+			opts.BinDir, _ = cmd.Flags().GetString("bin-dir")
+			opts.Binary, _ = cmd.Flags().GetString("binary")
+			opts.Asset, _ = cmd.Flags().GetString("asset")
+			opts.Force, _ = cmd.Flags().GetBool("force")
+
+			_, err = installer.Install(args[0], opts)
+			return err
+		},
 	}
 
-	opts := download.DefaultOptions()
-	opts.ShowProgress = true
-	return download.Multiple(urls, opts)
+	cmd.Flags().StringP("version", "v", "", "Release version/tag (default: latest)")
+	cmd.Flags().StringP("bin-dir", "b", "", "Install directory (default: ~/.local/bin)")
+	cmd.Flags().String("binary", "", "Name of the binary (default: repository name)")
+	cmd.Flags().StringP("asset", "a", "", "Asset name filter instead of platform detection")
+	cmd.Flags().BoolP("force", "f", false, "Reinstall the same version or replace a binary ghex didn't install")
+	cmd.Flags().BoolP("list", "l", false, "List installed tools")
+
+	return cmd
+}
+
+func newDlxUpgradeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "upgrade [name]",
+		Short:         "Upgrade tools installed with dlx install (all when no name is given)",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			installer, err := download.NewInstaller()
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				_, err := installer.Upgrade(args[0])
+				return err
+			}
+
+			if len(installer.Registry.Tools) == 0 {
+				ui.ShowInfo("No tools installed with dlx install")
+				return nil
+			}
+			var names []string
+			for _, tool := range installer.Registry.Tools {
+				names = append(names, tool.Name)
+			}
+			var errs []error
+			upgraded := 0
+			for _, name := range names {
+				ok, err := installer.Upgrade(name)
+				if err != nil {
+					ui.ShowError(fmt.Sprintf("Failed to upgrade %s: %v", name, err))
+					errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				} else if ok {
+					upgraded++
+				}
+			}
+			ui.ShowInfo(fmt.Sprintf("Upgraded %d of %d tools", upgraded, len(names)))
+			return errors.Join(errs...)
+		},
+	}
+}
+
+func newDlxUninstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "uninstall [name]",
+		Short:         "Remove a tool installed with dlx install",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			installer, err := download.NewInstaller()
+			if err != nil {
+				return err
+			}
+			return installer.Uninstall(args[0])
+		},
+	}
+}
+
+// showInstalledTools lists the tools of the registry
+func showInstalledTools(registry *download.Registry) {
+	ui.ShowSection("Installed Tools")
+	if len(registry.Tools) == 0 {
+		ui.ShowInfo("No tools installed with dlx install")
+		return
+	}
+	for _, tool := range registry.Tools {
+		fmt.Printf("  %s %s %s\n", ui.Primary(tool.Name), tool.Version, ui.Dim(tool.Repo+" → "+tool.Path))
+	}
+}
+
+// accountAuthHeaders returns the headers authenticating as a configured account
+func accountAuthHeaders(cfg *config.AppConfig, name string) (map[string]string, error) {
+	acc := account.NewManager(cfg).Find(name)
+	if acc == nil {
+		return nil, fmt.Errorf("account '%s' not found", name)
+	}
+	client, err := api.NewClientForAccount(acc)
+	if err != nil {
+		return nil, fmt.Errorf("account '%s': %w", name, err)
+	}
+	return client.AuthHeaders(), nil
 }
 
 func runDlxMenu() {
@@ -248,9 +574,11 @@ func runDownloadGitFile() {
 	branch := ui.Prompt("Branch/tag/commit (optional, press Enter for default)")
 	output := ui.Prompt("Output filename (optional)")
 
+	headers, _ := downloadAuthHeaders("", url, true)
 	opts := download.GitOptions{
-		Branch: branch,
-		Output: output,
+		Branch:  branch,
+		Output:  output,
+		Headers: headers,
 	}
 
 	if err := download.GitFile(url, opts); err != nil {
@@ -268,10 +596,12 @@ func runDownloadGitDir() {
 	branch := ui.Prompt("Branch/tag/commit (optional)")
 	outputDir := ui.Prompt("Output directory (optional)")
 
+	headers, _ := downloadAuthHeaders("", url, true)
 	opts := download.GitOptions{
 		Branch:    branch,
 		OutputDir: outputDir,
 		Depth:     10,
+		Headers:   headers,
 	}
 
 	if err := download.GitDirectory(url, opts); err != nil {
@@ -290,10 +620,12 @@ func runDownloadRelease() {
 	asset := ui.Prompt("Asset name filter (optional)")
 	outputDir := ui.Prompt("Output directory (optional)")
 
+	headers, _ := downloadAuthHeaders("", url, true)
 	opts := download.ReleaseOptions{
 		Version:   version,
 		Asset:     asset,
 		OutputDir: outputDir,
+		Headers:   headers,
 	}
 
 	if err := download.GitRelease(url, opts); err != nil {
@@ -308,7 +640,7 @@ func runDownloadFromList() {
 		return
 	}
 
-	if err := downloadFromFileList(filePath); err != nil {
+	if err := downloadFromFileList(filePath, download.DefaultBatchOptions()); err != nil {
 		ui.ShowError(err.Error())
 	}
 }
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dwirx/ghex/internal/config"
+	"github.com/dwirx/ghex/internal/doctor"
+	"github.com/dwirx/ghex/internal/git"
+	"github.com/dwirx/ghex/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// NewDoctorCmd creates the doctor command
+func NewDoctorCmd() *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the git and SSH setup",
+		Long: `Check git and ssh versions, the credential helper, insteadOf rules, the
+global and repository identity, GIT_SSH_COMMAND and core.sshCommand
+overrides, the SSH config and ~/.ssh. Each finding comes with a suggested
+fix; --fix applies the safe ones after asking. --report prints a redacted
+Markdown report to attach to bug reports.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, _ := config.Load()
+			fix, _ := cmd.Flags().GetBool("fix")
+			report, _ := cmd.Flags().GetBool("report")
+			runDoctor(cfg, fix, report)
+		},
+	}
+	doctorCmd.Flags().Bool("fix", false, "Apply the safe fixes (asks first)")
+	doctorCmd.Flags().Bool("report", false, "Print a redacted Markdown report for bug reports")
+	return doctorCmd
+}
+
+// doctorEnv builds the doctor environment from the accounts
+func doctorEnv(cfg *config.AppConfig) *doctor.Env {
+	repoPath := ""
+	if cwd, err := os.Getwd(); err == nil && git.IsGitRepo(cwd) {
+		repoPath = cwd
+	}
+
+	env := doctor.NewEnv(repoPath)
+	for _, h := range accountHosts(cfg) {
+		env.Hosts = append(env.Hosts, h.Host)
+	}
+	env.Keys = accountKeyReferences(cfg)
+	for _, acc := range cfg.Accounts {
+		if acc.GitEmail != "" {
+			env.Emails = append(env.Emails, acc.GitEmail)
+		}
+		if acc.Token != nil {
+			env.TokenAccounts++
+		}
+	}
+	return env
+}
+
+// accountSecrets returns the tokens to redact from reports
+func accountSecrets(cfg *config.AppConfig) []string {
+	var secrets []string
+	for _, acc := range cfg.Accounts {
+		if acc.Token != nil {
+			secrets = append(secrets, acc.Token.Token)
+		}
+	}
+	return secrets
+}
+
+func runDoctor(cfg *config.AppConfig, fix, asReport bool) {
+	env := doctorEnv(cfg)
+	report := doctor.Run(env, Version)
+
+	if asReport {
+		gitConfig, _ := env.GitConfig("")
+		fmt.Print(report.Markdown(gitConfig, accountSecrets(cfg)))
+		return
+	}
+
+	ui.ShowSection("Doctor")
+	check := ""
+	for _, f := range report.Findings {
+		if f.Check != check {
+			check = f.Check
+			fmt.Printf("\n%s\n", ui.Primary(check))
+		}
+		fmt.Printf("  %s %s\n", severityIcon(f.Severity), f.Message)
+		if f.Fix != "" && f.Severity > doctor.SeverityOK {
+			fixNote := ""
+			if f.Fixable() {
+				fixNote = ui.Muted(" (--fix)")
+			}
+			fmt.Printf("    %s %s%s\n", ui.Muted("→"), f.Fix, fixNote)
+		}
+	}
+	fmt.Println()
+
+	fixable := report.Fixable()
+	switch {
+	case report.Worst() <= doctor.SeverityInfo:
+		ui.ShowSuccess("No problems found")
+		return
+	case len(fixable) == 0:
+		ui.ShowInfo("Apply the suggested fixes above, or attach 'ghex doctor --report' to a bug report")
+		return
+	case !fix:
+		ui.ShowInfo(fmt.Sprintf("%d finding(s) can be fixed with 'ghex doctor --fix'", len(fixable)))
+		return
+	}
+
+	if !ui.Confirm(fmt.Sprintf("Apply %d fix(es)?", len(fixable))) {
+		ui.ShowInfo("Cancelled")
+		return
+	}
+	for _, f := range fixable {
+		if err := f.Apply(); err != nil {
+			ui.ShowError(fmt.Sprintf("%s: %v", f.Fix, err))
+			continue
+		}
+		ui.ShowSuccess(f.Fix)
+	}
+}
+
+// severityIcon returns the symbol for a finding
+func severityIcon(s doctor.Severity) string {
+	switch s {
+	case doctor.SeverityOK:
+		return ui.Success("✓")
+	case doctor.SeverityInfo:
+		return ui.Primary("ℹ")
+	case doctor.SeverityWarning:
+		return ui.Warning("⚠")
+	default:
+		return ui.Error("✗")
+	}
+}
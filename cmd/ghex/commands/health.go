@@ -2,6 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/dwirx/ghex/internal/account"
 	"github.com/dwirx/ghex/internal/config"
@@ -24,13 +27,91 @@ func NewHealthCmd() *cobra.Command {
 
 // NewLogCmd creates the log command
 func NewLogCmd() *cobra.Command {
-	return &cobra.Command{
+	logCmd := &cobra.Command{
 		Use:   "log",
 		Short: "Show activity log",
+		Long:  "Show, filter and export the activity log. Times accept a date (2024-01-31), an RFC3339 time or an age such as 12h or 7d.",
+		Run: func(cmd *cobra.Command, args []string) {
+			filter, err := activityFilterFromFlags(cmd)
+			if err != nil {
+				ui.ShowError(err.Error())
+				return
+			}
+			filter.Limit, _ = cmd.Flags().GetInt("limit")
+			format, _ := cmd.Flags().GetString("format")
+			output, _ := cmd.Flags().GetString("output")
+			runActivityLog(filter, format, output)
+		},
+	}
+	logCmd.PersistentFlags().StringP("account", "a", "", "Only entries for this account")
+	logCmd.PersistentFlags().StringP("repo", "r", "", "Only entries whose repository contains this text")
+	logCmd.PersistentFlags().String("action", "", "Only this action (switch, add, remove, edit, test, ...)")
+	logCmd.PersistentFlags().String("since", "", "Only entries at or after this time")
+	logCmd.PersistentFlags().String("until", "", "Only entries at or before this time")
+	logCmd.PersistentFlags().Bool("failed", false, "Only failed operations")
+	logCmd.Flags().IntP("limit", "n", 20, "Maximum number of entries (0 for all)")
+	logCmd.Flags().String("format", "text", "Output format: text, jsonl or csv")
+	logCmd.Flags().StringP("output", "o", "", "Write to a file instead of stdout")
+
+	logCmd.AddCommand(&cobra.Command{
+		Use:   "stats",
+		Short: "Summarise the activity log",
+		Run: func(cmd *cobra.Command, args []string) {
+			filter, err := activityFilterFromFlags(cmd)
+			if err != nil {
+				ui.ShowError(err.Error())
+				return
+			}
+			runActivityStats(filter)
+		},
+	})
+	logCmd.AddCommand(newLogSettingsCmd())
+	return logCmd
+}
+
+// newLogSettingsCmd creates the log settings command
+func newLogSettingsCmd() *cobra.Command {
+	settingsCmd := &cobra.Command{
+		Use:   "settings",
+		Short: "Show or change activity log rotation and retention",
+		Long: `The activity log is kept in activity.jsonl next to config.json. It is rotated
+once it reaches --max-size-kb or its first entry is --max-age-days old, and
+rotated files are deleted after --retention-days. 0 restores a default and
+-1 disables the limit.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			runActivityLog()
+			cfg, err := config.Load()
+			if err != nil {
+				ui.ShowError(fmt.Sprintf("Failed to load config: %v", err))
+				return
+			}
+			runLogSettings(cfg, cmd)
 		},
 	}
+	settingsCmd.Flags().Int("retention-days", 0, "Delete rotated logs older than this many days")
+	settingsCmd.Flags().Int("max-size-kb", 0, "Rotate the log at this size in KB")
+	settingsCmd.Flags().Int("max-age-days", 0, "Rotate the log once its first entry is this many days old")
+	return settingsCmd
+}
+
+// activityFilterFromFlags reads the log filter flags
+func activityFilterFromFlags(cmd *cobra.Command) (account.ActivityFilter, error) {
+	var filter account.ActivityFilter
+	filter.Account, _ = cmd.Flags().GetString("account")
+	filter.Repo, _ = cmd.Flags().GetString("repo")
+	filter.Action, _ = cmd.Flags().GetString("action")
+	filter.FailedOnly, _ = cmd.Flags().GetBool("failed")
+
+	now := time.Now()
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	var err error
+	if filter.Since, err = account.ParseActivityTime(since, now); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = account.ParseActivityUntil(until, now); err != nil {
+		return filter, err
+	}
+	return filter, nil
 }
 
 func runHealthCheck() {
@@ -47,11 +128,7 @@ func runHealthCheck() {
 
 	ui.ShowSection("Health Check")
 
-	// Fix permissions for ALL SSH keys first
-	fixedCount, _ := ssh.FixAllKeyPermissions()
-	if fixedCount > 0 {
-		ui.ShowSuccess(fmt.Sprintf("✓ Fixed permissions for %d SSH key(s)", fixedCount))
-	}
+	warnPermissionIssues()
 
 	// Track summary
 	total := len(cfg.Accounts)
@@ -65,6 +142,9 @@ func runHealthCheck() {
 
 		fmt.Printf("\n%s %s %s (%s)\n", ui.Primary("Checking:"), acc.Name, platform.Icon, platform.Name)
 
+		act := startActivity(cfg, "health", acc.Name)
+		act.entry.Platform = platform.Type
+		var problems []string
 		accountHealthy := true
 
 		if acc.SSH != nil {
@@ -73,28 +153,40 @@ func runHealthCheck() {
 			spinner := ui.NewSpinner(fmt.Sprintf("  Testing SSH with %s...", acc.SSH.KeyPath))
 			spinner.Start()
 
-			ok, msg, _ := ssh.TestConnectionWithKey(platform.Host, expandedPath)
+			ok, msg, _ := ssh.TestConnectionWithOptions(platform.ConnectionOptions(expandedPath))
 			if ok {
 				spinner.StopWithSuccess(fmt.Sprintf("  SSH: %s", msg))
 			} else {
 				spinner.StopWithError(fmt.Sprintf("  SSH: %s", msg))
+				problems = append(problems, "SSH: "+msg)
 				accountHealthy = false
 			}
+
+			if age, known := account.KeyAge(acc.SSH, time.Now()); known && age > account.KeyRotationAge {
+				ui.ShowWarning(fmt.Sprintf("  SSH key is %d days old (rotate with: ghex ssh rotate --account %s)", int(age.Hours()/24), acc.Name))
+			}
 		}
 
 		if acc.Token != nil {
 			spinner := ui.NewSpinner("  Testing Token...")
 			spinner.Start()
 
-			ok, msg, _ := git.TestTokenAuth(acc.Token.Username, acc.Token.Token)
+			ok, msg, _ := git.TestTokenAuthForPlatform(platform.Type, platformDomain(&acc), acc.Token.Username, acc.Token.Token)
 			if ok {
 				spinner.StopWithSuccess(fmt.Sprintf("  Token: %s", msg))
 			} else {
 				spinner.StopWithError(fmt.Sprintf("  Token: %s", msg))
+				problems = append(problems, "Token: "+msg)
 				accountHealthy = false
 			}
 		}
 
+		var healthErr error
+		if len(problems) > 0 {
+			healthErr = fmt.Errorf("%s", strings.Join(problems, "; "))
+		}
+		act.done(healthErr)
+
 		if accountHealthy {
 			healthy++
 		} else if acc.SSH != nil && acc.Token != nil {
@@ -120,22 +212,32 @@ func runHealthCheck() {
 	)
 }
 
-func runActivityLog() {
+func runActivityLog(filter account.ActivityFilter, format, output string) {
 	cfg, err := config.Load()
 	if err != nil {
 		ui.ShowError(fmt.Sprintf("Failed to load config: %v", err))
 		return
 	}
 
-	if len(cfg.ActivityLog) == 0 {
-		ui.ShowInfo("No activity logged yet")
+	entries, err := account.NewManager(cfg).QueryActivity(filter)
+	if err != nil {
+		ui.ShowError(err.Error())
 		return
 	}
 
-	ui.ShowSection("Activity Log")
+	if format != "text" {
+		if err := exportActivity(entries, format, output); err != nil {
+			ui.ShowError(err.Error())
+		}
+		return
+	}
 
-	manager := account.NewManager(cfg)
-	entries := manager.GetRecentActivity(20)
+	if len(entries) == 0 {
+		ui.ShowInfo("No matching activity")
+		return
+	}
+
+	ui.ShowSection("Activity Log")
 
 	for _, entry := range entries {
 		status := ui.Success("✓")
@@ -156,6 +258,135 @@ func runActivityLog() {
 		if entry.Method != "" {
 			fmt.Printf(" (%s)", entry.Method)
 		}
+		if entry.Detail != "" {
+			fmt.Printf(" %s", ui.Muted(entry.Detail))
+		}
+		if entry.DurationMs > 0 {
+			fmt.Printf(" %s", ui.Dim((time.Duration(entry.DurationMs) * time.Millisecond).String()))
+		}
+		if entry.Error != "" {
+			fmt.Printf(" %s", ui.Error(entry.Error))
+		}
 		fmt.Println()
 	}
 }
+
+// exportActivity writes entries as JSON Lines or CSV to output or stdout
+func exportActivity(entries []config.ActivityLogEntry, format, output string) error {
+	w := os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var err error
+	switch format {
+	case "jsonl":
+		err = account.WriteActivityJSONL(w, entries)
+	case "csv":
+		err = account.WriteActivityCSV(w, entries)
+	default:
+		return fmt.Errorf("unknown format '%s' (use text, jsonl or csv)", format)
+	}
+	if err == nil && output != "" {
+		ui.ShowSuccess(fmt.Sprintf("Exported %d entries to %s", len(entries), output))
+	}
+	return err
+}
+
+func runLogSettings(cfg *config.AppConfig, cmd *cobra.Command) {
+	if cfg.Activity == nil {
+		cfg.Activity = &config.ActivitySettings{}
+	}
+	settings := []struct {
+		flag  string
+		value *int
+		def   int
+		unit  string
+	}{
+		{"retention-days", &cfg.Activity.RetentionDays, config.DefaultActivityRetentionDays, "days"},
+		{"max-size-kb", &cfg.Activity.MaxSizeKB, config.DefaultActivityMaxSizeKB, "KB"},
+		{"max-age-days", &cfg.Activity.MaxAgeDays, config.DefaultActivityMaxAgeDays, "days"},
+	}
+
+	changed := false
+	for _, s := range settings {
+		if cmd.Flags().Changed(s.flag) {
+			*s.value, _ = cmd.Flags().GetInt(s.flag)
+			changed = true
+		}
+	}
+
+	if changed {
+		if *cfg.Activity == (config.ActivitySettings{}) {
+			cfg.Activity = nil
+		}
+		if err := config.Save(cfg); err != nil {
+			ui.ShowError(fmt.Sprintf("Failed to save config: %v", err))
+			return
+		}
+		removed, err := config.OpenActivityStore(cfg).Prune()
+		if err != nil {
+			ui.ShowError(err.Error())
+			return
+		}
+		ui.ShowSuccess("Activity log settings saved")
+		if len(removed) > 0 {
+			ui.ShowInfo(fmt.Sprintf("Deleted %d rotated log(s) past the retention period", len(removed)))
+		}
+		return
+	}
+
+	ui.ShowSection("Activity Log Settings")
+	fmt.Printf("%s %s\n", ui.Muted("Log:"), config.GetActivityLogPath())
+	for _, s := range settings {
+		value := fmt.Sprintf("%d %s", *s.value, s.unit)
+		switch {
+		case *s.value == 0:
+			value = fmt.Sprintf("%d %s %s", s.def, s.unit, ui.Muted("(default)"))
+		case *s.value < 0:
+			value = "off"
+		}
+		fmt.Printf("  %-16s %s\n", s.flag, value)
+	}
+}
+
+func runActivityStats(filter account.ActivityFilter) {
+	cfg, err := config.Load()
+	if err != nil {
+		ui.ShowError(fmt.Sprintf("Failed to load config: %v", err))
+		return
+	}
+
+	entries, err := account.NewManager(cfg).QueryActivity(filter)
+	if err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+
+	stats := account.ComputeActivityStats(entries)
+	if stats.Total == 0 {
+		ui.ShowInfo("No matching activity")
+		return
+	}
+
+	ui.ShowSection("Activity Stats")
+	fmt.Printf("%s %d entries, %d failed (%.1f%%)\n", ui.Primary("📊"), stats.Total, stats.Failed, stats.FailureRate*100)
+
+	printCounts := func(title string, counts []account.ActivityCount) {
+		if len(counts) == 0 {
+			return
+		}
+		fmt.Printf("\n%s\n", ui.Primary(title))
+		for _, c := range counts {
+			fmt.Printf("  %4d  %s\n", c.Count, c.Name)
+		}
+	}
+	printCounts("Actions", stats.Actions)
+	printCounts("Switches per account", stats.SwitchesByAccount)
+	printCounts("Most-switched repositories", stats.TopRepos)
+}
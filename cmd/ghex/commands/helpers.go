@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/dwirx/ghex/internal/account"
 	"github.com/dwirx/ghex/internal/config"
 	"github.com/dwirx/ghex/internal/git"
 	"github.com/dwirx/ghex/internal/ssh"
@@ -19,6 +20,8 @@ type PlatformInfo struct {
 	Type     string
 	KeysURL  string
 	TokenURL string
+	SSHUser  string
+	SSHPort  int
 }
 
 // GetPlatformInfo returns platform information from account
@@ -56,15 +59,114 @@ func GetPlatformInfo(acc *config.Account) PlatformInfo {
 			info.Icon = "🏔️"
 			info.KeysURL = "https://codeberg.org/user/settings/keys"
 			info.TokenURL = "https://codeberg.org/user/settings/applications"
+		case "azure":
+			info.Host = git.GetPlatformSSHHost("azure", "")
+			info.Name = "Azure DevOps"
+			info.Icon = "🔷"
+			info.KeysURL = "https://dev.azure.com/_usersSettings/keys"
+			info.TokenURL = "https://dev.azure.com/_usersSettings/tokens"
+		case "codecommit":
+			info.Host = git.GetDefaultDomain("codecommit")
+			info.Name = "AWS CodeCommit"
+			info.Icon = "☁️"
+			info.KeysURL = "https://console.aws.amazon.com/iam/home#/security_credentials"
+			info.TokenURL = "https://console.aws.amazon.com/iam/home#/security_credentials"
+		case "sourcehut":
+			info.Host = "git.sr.ht"
+			info.Name = "SourceHut"
+			info.Icon = "🛖"
+			info.KeysURL = "https://meta.sr.ht/keys"
+			info.TokenURL = "https://meta.sr.ht/oauth2"
+		default:
+			if git.IsCustomPlatform(acc.Platform.Type) {
+				applyCustomPlatformInfo(&info, acc.Platform.Type, acc.Platform.Domain)
+			}
 		}
-		if acc.Platform.Domain != "" {
-			info.Host = acc.Platform.Domain
+		if acc.Platform.Domain != "" && !git.IsCustomPlatform(acc.Platform.Type) {
+			info.Host = git.GetPlatformSSHHost(acc.Platform.Type, acc.Platform.Domain)
 		}
 	}
 
+	if acc.SSH != nil && acc.SSH.User != "" {
+		info.SSHUser = acc.SSH.User
+	}
+
 	return info
 }
 
+// KeyUploadHint returns extra instructions for adding an SSH key on platforms
+// where pasting the public key alone is not enough
+func (p PlatformInfo) KeyUploadHint() string {
+	switch p.Type {
+	case "codecommit":
+		return "Upload it under IAM > Users > Security credentials > SSH keys for AWS CodeCommit, then set the SSH Key ID as the account's SSH user"
+	case "azure":
+		return "If Azure DevOps rejects the key type, generate an RSA key (ssh-keygen -t rsa -b 4096)"
+	}
+	return ""
+}
+
+// TokenHint returns extra instructions for creating a token on the platform
+func (p PlatformInfo) TokenHint() string {
+	switch p.Type {
+	case "codecommit":
+		return "Generate HTTPS Git credentials for AWS CodeCommit and use them as username/token"
+	case "azure":
+		return "Create a personal access token with Code (Read & write) scope"
+	case "sourcehut":
+		return "Generate a personal access token; use your ~username as username"
+	}
+	return ""
+}
+
+// applyCustomPlatformInfo fills platform info for a user-defined platform
+func applyCustomPlatformInfo(info *PlatformInfo, platformType, domain string) {
+	registered := account.GetPlatformInfo(platformType)
+	webHost := git.GetPlatformHTTPSHost(platformType, domain)
+
+	info.Name = registered.Name
+	info.Icon = registered.Icon
+	info.Host = git.GetPlatformSSHHost(platformType, domain)
+	info.SSHUser = git.GetPlatformSSHUser(platformType)
+	info.SSHPort = git.GetPlatformSSHPort(platformType)
+
+	switch git.GetPlatformFlavor(platformType) {
+	case git.FlavorGitHub:
+		info.KeysURL = fmt.Sprintf("https://%s/settings/keys", webHost)
+		info.TokenURL = fmt.Sprintf("https://%s/settings/tokens", webHost)
+	case git.FlavorGitLab:
+		info.KeysURL = fmt.Sprintf("https://%s/-/profile/keys", webHost)
+		info.TokenURL = fmt.Sprintf("https://%s/-/profile/personal_access_tokens", webHost)
+	case git.FlavorGitea:
+		info.KeysURL = fmt.Sprintf("https://%s/user/settings/keys", webHost)
+		info.TokenURL = fmt.Sprintf("https://%s/user/settings/applications", webHost)
+	case git.FlavorBitbucket:
+		info.KeysURL = fmt.Sprintf("https://%s/account/settings/ssh-keys/", webHost)
+		info.TokenURL = fmt.Sprintf("https://%s/account/settings/app-passwords/", webHost)
+	default:
+		info.KeysURL = fmt.Sprintf("https://%s", webHost)
+		info.TokenURL = fmt.Sprintf("https://%s", webHost)
+	}
+}
+
+// ConnectionOptions returns SSH connection options for testing an account key
+func (p PlatformInfo) ConnectionOptions(keyPath string) ssh.ConnectionOptions {
+	return ssh.ConnectionOptions{
+		Host:    p.Host,
+		User:    p.SSHUser,
+		Port:    p.SSHPort,
+		KeyPath: keyPath,
+	}
+}
+
+// platformDomain returns the custom domain configured for an account, if any
+func platformDomain(acc *config.Account) string {
+	if acc.Platform == nil {
+		return ""
+	}
+	return acc.Platform.Domain
+}
+
 // ExpandKeyPath expands ~ in key path to home directory
 func ExpandKeyPath(keyPath string) string {
 	if strings.HasPrefix(keyPath, "~") {
@@ -92,10 +194,8 @@ func TestAccountSSH(acc *config.Account, showDetails bool) bool {
 		return false
 	}
 
-	// Fix permissions for ALL SSH keys
-	fixedCount, _ := ssh.FixAllKeyPermissions()
-	if fixedCount > 0 && showDetails {
-		ui.ShowSuccess(fmt.Sprintf("✓ Fixed permissions for %d SSH key(s)", fixedCount))
+	if showDetails {
+		warnPermissionIssues()
 	}
 
 	if showDetails {
@@ -108,7 +208,7 @@ func TestAccountSSH(acc *config.Account, showDetails bool) bool {
 	spinner := ui.NewSpinner("Testing SSH connection...")
 	spinner.Start()
 
-	ok, msg, _ := ssh.TestConnectionWithKey(platform.Host, expandedPath)
+	ok, msg, _ := ssh.TestConnectionWithOptions(platform.ConnectionOptions(expandedPath))
 	if ok {
 		spinner.StopWithSuccess("✓ SSH connection test passed!")
 		if showDetails {
@@ -123,6 +223,9 @@ func TestAccountSSH(acc *config.Account, showDetails bool) bool {
 		ui.ShowWarning(fmt.Sprintf("Make sure your SSH key is added to %s:", platform.Name))
 		ui.ShowInfo(fmt.Sprintf("1. Copy your public key: cat %s.pub", keyPath))
 		ui.ShowInfo(fmt.Sprintf("2. Add it at: %s", platform.KeysURL))
+		if hint := platform.KeyUploadHint(); hint != "" {
+			ui.ShowInfo(fmt.Sprintf("3. %s", hint))
+		}
 		if msg != "" {
 			fmt.Println()
 			fmt.Println(ui.Muted(fmt.Sprintf("Details: %s", msg)))
@@ -144,7 +247,7 @@ func TestAccountToken(acc *config.Account, showDetails bool) bool {
 	spinner := ui.NewSpinner("Testing token authentication...")
 	spinner.Start()
 
-	ok, msg, _ := git.TestTokenAuth(acc.Token.Username, acc.Token.Token)
+	ok, msg, _ := git.TestTokenAuthForPlatform(platform.Type, platformDomain(acc), acc.Token.Username, acc.Token.Token)
 	if ok {
 		spinner.StopWithSuccess("✓ Token authentication test passed!")
 		if showDetails {
@@ -160,6 +263,9 @@ func TestAccountToken(acc *config.Account, showDetails bool) bool {
 		ui.ShowInfo("• Token has correct permissions (repo access)")
 		ui.ShowInfo("• Username is correct")
 		ui.ShowInfo(fmt.Sprintf("\nCreate a new token at: %s", platform.TokenURL))
+		if hint := platform.TokenHint(); hint != "" {
+			ui.ShowInfo(hint)
+		}
 		if msg != "" {
 			fmt.Println(ui.Muted(fmt.Sprintf("\nDetails: %s", msg)))
 		}
@@ -178,10 +284,8 @@ func TestSSHKeyDirect(keyPath, host string, showDetails bool) bool {
 		return false
 	}
 
-	// Fix permissions for ALL SSH keys
-	fixedCount, _ := ssh.FixAllKeyPermissions()
-	if fixedCount > 0 && showDetails {
-		ui.ShowSuccess(fmt.Sprintf("✓ Fixed permissions for %d SSH key(s)", fixedCount))
+	if showDetails {
+		warnPermissionIssues()
 	}
 
 	if showDetails {
@@ -34,6 +34,7 @@ func runInteractive() {
 			{Title: "📥 Download (dlx)", Description: "Download files from URLs or Git repos", Value: "dlx"},
 			{Title: "🧪 Test connection", Description: "Test SSH/Token authentication", Value: "test"},
 			{Title: "🏥 Health check", Description: "Check all account connections", Value: "health"},
+			{Title: "🩺 Doctor", Description: "Diagnose the git and SSH setup", Value: "doctor"},
 			{Title: "📜 Activity log", Description: "View recent activity", Value: "log"},
 			{Title: "🚪 Exit", Description: "Quit GHEX", Value: "exit"},
 		}
@@ -68,8 +69,10 @@ func runInteractive() {
 			runTestConnection(cfg)
 		case "health":
 			runHealthCheck()
+		case "doctor":
+			runDoctor(cfg, true, false)
 		case "log":
-			runActivityLog()
+			runActivityLog(account.ActivityFilter{Limit: 20}, "text", "")
 		case "exit":
 			ui.ShowSeparator()
 			ui.ShowSuccess("Thank you for using GHEX! 👋")
@@ -131,6 +134,6 @@ func showRepositoryContext(cfg *config.AppConfig) {
 func isGitURL(s string) bool {
 	return strings.HasPrefix(s, "http://") ||
 		strings.HasPrefix(s, "https://") ||
-		strings.HasPrefix(s, "git@") ||
-		strings.HasPrefix(s, "ssh://")
+		strings.HasPrefix(s, "git://") ||
+		git.IsSSHURL(s)
 }
@@ -6,7 +6,10 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/dwirx/ghex/internal/account"
+	"github.com/dwirx/ghex/internal/config"
 	"github.com/dwirx/ghex/internal/ui"
+	"github.com/dwirx/ghex/pkg/download"
 	"github.com/spf13/cobra"
 )
 
@@ -31,6 +34,7 @@ func NewRootCmd() *cobra.Command {
 	rootCmd.AddCommand(NewSwitchCmd())
 	rootCmd.AddCommand(NewHealthCmd())
 	rootCmd.AddCommand(NewLogCmd())
+	rootCmd.AddCommand(NewDoctorCmd())
 	rootCmd.AddCommand(NewAddCmd())
 	rootCmd.AddCommand(NewRemoveCmd())
 	rootCmd.AddCommand(NewEditCmd())
@@ -68,6 +72,10 @@ func Execute() {
 	}()
 
 	rootCmd := NewRootCmd()
+	account.AppVersion = Version
+
+	// Register user-defined platforms before any URL handling
+	registerCustomPlatforms()
 
 	// Handle URL arguments for clone
 	if len(os.Args) > 1 {
@@ -87,3 +95,16 @@ func Execute() {
 		os.Exit(1)
 	}
 }
+
+// registerCustomPlatforms loads custom platforms and the custom domains of
+// accounts from the config file
+func registerCustomPlatforms() {
+	cfg, err := config.Load()
+	if err != nil {
+		return
+	}
+	if err := account.RegisterCustomPlatforms(cfg.Platforms); err != nil {
+		ui.ShowWarning(err.Error())
+	}
+	download.RegisterAccountHosts(cfg.Accounts)
+}
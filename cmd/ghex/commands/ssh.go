@@ -1,10 +1,15 @@
 package commands
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/dwirx/ghex/internal/account"
+	"github.com/dwirx/ghex/internal/api"
 	"github.com/dwirx/ghex/internal/config"
 	"github.com/dwirx/ghex/internal/ssh"
 	"github.com/dwirx/ghex/internal/ui"
@@ -48,14 +53,19 @@ func NewSSHCmd() *cobra.Command {
 		},
 	}
 
-	sshCmd.AddCommand(&cobra.Command{
+	generateCmd := &cobra.Command{
 		Use:   "generate",
 		Short: "Generate a new SSH key",
 		Run: func(cmd *cobra.Command, args []string) {
 			cfg, _ := config.Load()
-			runGenerateSSHKey(cfg)
+			keyType, _ := cmd.Flags().GetString("type")
+			passphrase, _ := cmd.Flags().GetBool("passphrase")
+			runGenerateSSHKey(cfg, keyType, passphrase)
 		},
-	})
+	}
+	generateCmd.Flags().StringP("type", "t", "", fmt.Sprintf("Key type (%s)", strings.Join(ssh.KeyTypes(), ", ")))
+	generateCmd.Flags().BoolP("passphrase", "p", false, "Protect the key with a passphrase")
+	sshCmd.AddCommand(generateCmd)
 
 	sshCmd.AddCommand(&cobra.Command{
 		Use:   "import",
@@ -84,24 +94,59 @@ func NewSSHCmd() *cobra.Command {
 		},
 	})
 
-	sshCmd.AddCommand(&cobra.Command{
+	listCmd := &cobra.Command{
 		Use:   "list",
-		Short: "List SSH keys",
+		Short: "List SSH keys with their type, fingerprint and accounts",
+		Long:  "Inspect each private key in ~/.ssh and those used by accounts. Flags keys no account uses and accounts whose key is missing.",
 		Run: func(cmd *cobra.Command, args []string) {
-			runListSSHKeys()
+			cfg, _ := config.Load()
+			sortBy, _ := cmd.Flags().GetString("sort")
+			asJSON, _ := cmd.Flags().GetBool("json")
+			runListSSHKeys(cfg, sortBy, asJSON)
 		},
-	})
+	}
+	listCmd.Flags().String("sort", ssh.SortByName, "Sort by "+strings.Join(ssh.SortOrders(), ", "))
+	listCmd.Flags().Bool("json", false, "Print the inventory as JSON")
+	sshCmd.AddCommand(listCmd)
+
+	uploadCmd := &cobra.Command{
+		Use:   "upload",
+		Short: "Upload an account's public key to its platform",
+		Long:  "Add the account's SSH public key to the platform through its API, using the account token",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, _ := config.Load()
+			accountName, _ := cmd.Flags().GetString("account")
+			title, _ := cmd.Flags().GetString("title")
+			signing, _ := cmd.Flags().GetBool("signing")
+			runUploadSSHKey(cfg, accountName, title, signing)
+		},
+	}
+	uploadCmd.Flags().StringP("account", "a", "", "Account name")
+	uploadCmd.Flags().StringP("title", "t", "", "Key title shown on the platform")
+	uploadCmd.Flags().BoolP("signing", "s", false, "Also register the key for commit signing")
+	sshCmd.AddCommand(uploadCmd)
+
+	sshCmd.AddCommand(newSSHAgentCmd())
+	sshCmd.AddCommand(newSSHConfigCmd())
+	sshCmd.AddCommand(newSSHKnownHostsCmd())
+	sshCmd.AddCommand(newSSHDoctorCmd())
+	sshCmd.AddCommand(newSSHRotateCmd())
 
 	return sshCmd
 }
 
 func runSSHMenu(cfg *config.AppConfig) {
 	items := []ui.SelectorItem{
-		{Title: "🔑 Generate SSH key", Description: "Create a new Ed25519 SSH key pair", Value: "generate"},
+		{Title: "🔑 Generate SSH key", Description: "Create a new SSH key pair", Value: "generate"},
 		{Title: "📥 Import SSH key", Description: "Import an existing private key", Value: "import"},
 		{Title: "🌐 Switch SSH globally", Description: "Set default SSH key for github.com", Value: "global"},
 		{Title: "🧪 Test connection", Description: "Test SSH authentication", Value: "test"},
 		{Title: "📋 List SSH keys", Description: "Show all SSH keys in ~/.ssh", Value: "list"},
+		{Title: "☁️ Upload public key", Description: "Add an account key to its platform via API", Value: "upload"},
+		{Title: "🔄 Rotate SSH key", Description: "Replace an account key and archive the old one", Value: "rotate"},
+		{Title: "🗝️ ssh-agent", Description: "Load or unload account keys in ssh-agent", Value: "agent"},
+		{Title: "📄 SSH config", Description: "Show, diff or restore ghex SSH config changes", Value: "config"},
+		{Title: "🩺 Doctor", Description: "Check ~/.ssh file permissions", Value: "doctor"},
 		{Title: "🔙 Back", Description: "Return to main menu", Value: "back"},
 	}
 
@@ -112,7 +157,7 @@ func runSSHMenu(cfg *config.AppConfig) {
 
 	switch items[idx].Value {
 	case "generate":
-		runGenerateSSHKey(cfg)
+		runGenerateSSHKey(cfg, "", false)
 	case "import":
 		runImportSSHKey(cfg)
 	case "global":
@@ -120,13 +165,23 @@ func runSSHMenu(cfg *config.AppConfig) {
 	case "test":
 		runTestConnection(cfg)
 	case "list":
-		runListSSHKeys()
+		runListSSHKeys(cfg, ssh.SortByName, false)
+	case "upload":
+		runUploadSSHKey(cfg, "", "", false)
+	case "rotate":
+		runSSHRotate(cfg, "", rotateOptions{KeyType: ssh.KeyTypeEd25519})
+	case "agent":
+		runSSHAgentMenu(cfg)
+	case "config":
+		runSSHConfigMenu()
+	case "doctor":
+		runSSHDoctor(true)
 	case "back":
 		return
 	}
 }
 
-func runGenerateSSHKey(cfg *config.AppConfig) {
+func runGenerateSSHKey(cfg *config.AppConfig, keyType string, withPassphrase bool) {
 	if len(cfg.Accounts) == 0 {
 		ui.ShowWarning("No accounts configured. Add an account first.")
 		return
@@ -164,25 +219,193 @@ func runGenerateSSHKey(cfg *config.AppConfig) {
 		return
 	}
 
-	comment := acc.GitEmail
-	if comment == "" {
-		comment = acc.GitUserName
+	comment := keyComment(acc)
+
+	// Interactive mode: choose type and passphrase
+	if keyType == "" {
+		typeItems := []ui.SelectorItem{
+			{Title: "Ed25519", Description: "Recommended, short and fast", Value: ssh.KeyTypeEd25519},
+			{Title: "Ed25519-SK", Description: "Stored on a FIDO/U2F security key", Value: ssh.KeyTypeEd25519SK},
+			{Title: "RSA 4096", Description: "For servers without Ed25519 support", Value: ssh.KeyTypeRSA4096},
+			{Title: "ECDSA", Description: "NIST P-521 curve", Value: ssh.KeyTypeECDSA},
+		}
+		typeIdx, err := ui.RunSelector("Select Key Type", typeItems)
+		if err != nil || typeIdx < 0 {
+			ui.ShowInfo("Cancelled")
+			return
+		}
+		keyType = typeItems[typeIdx].Value
+		withPassphrase = ui.Confirm("Protect the key with a passphrase?")
+	}
+
+	act := startActivity(cfg, "keygen", acc.Name)
+	act.entry.Detail = fmt.Sprintf("%s %s", keyType, acc.SSH.KeyPath)
+
+	opts := ssh.KeyOptions{Type: keyType, Comment: comment, Passphrase: withPassphrase}
+
+	fmt.Println()
+	var spinner *ui.Spinner
+	if keyType == ssh.KeyTypeEd25519SK {
+		ui.ShowInfo("Touch your security key when it blinks...")
+	} else if opts.Passphrase {
+		ui.ShowInfo("Enter the passphrase when ssh-keygen asks for it")
+	} else {
+		spinner = ui.NewSpinner("Generating SSH key...")
+		spinner.Start()
+	}
+
+	err = ssh.GenerateKeyWithOptions(acc.SSH.KeyPath, opts)
+	act.done(err)
+	if err != nil {
+		if spinner != nil {
+			spinner.StopWithError(fmt.Sprintf("Failed to generate key: %v", err))
+		} else {
+			ui.ShowError(fmt.Sprintf("Failed to generate key: %v", err))
+		}
+		return
+	}
+
+	if spinner != nil {
+		spinner.StopWithSuccess(fmt.Sprintf("Generated SSH key: %s", acc.SSH.KeyPath))
+	} else {
+		ui.ShowSuccess(fmt.Sprintf("Generated SSH key: %s", acc.SSH.KeyPath))
+	}
+	acc.SSH.KeyCreatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := config.Save(cfg); err != nil {
+		ui.ShowWarning(fmt.Sprintf("Failed to save config: %v", err))
+	}
+	if opts.Passphrase {
+		ui.ShowInfo("Load it with: ghex ssh agent add --account " + acc.Name)
+	}
+	ui.ShowInfo(fmt.Sprintf("Public key: %s.pub", acc.SSH.KeyPath))
+
+	if acc.Token != nil && ui.Confirm(fmt.Sprintf("Upload the public key to %s now?", GetPlatformInfo(acc).Name)) {
+		runUploadSSHKey(cfg, acc.Name, "", false)
+	}
+}
+
+// keyComment returns the comment for keys generated for an account
+func keyComment(acc *config.Account) string {
+	if acc.GitEmail != "" {
+		return acc.GitEmail
+	}
+	if acc.GitUserName != "" {
+		return acc.GitUserName
+	}
+	return fmt.Sprintf("%s@github", acc.Name)
+}
+
+// defaultKeyTitle returns the platform key title for an account on this machine
+func defaultKeyTitle(acc *config.Account) string {
+	if hostname, _ := os.Hostname(); hostname != "" {
+		return fmt.Sprintf("ghex %s (%s)", acc.Name, hostname)
+	}
+	return fmt.Sprintf("ghex %s", acc.Name)
+}
+
+func runUploadSSHKey(cfg *config.AppConfig, accountName, title string, signing bool) {
+	if len(cfg.Accounts) == 0 {
+		ui.ShowWarning("No accounts configured. Add an account first.")
+		return
+	}
+
+	if accountName == "" {
+		items := make([]ui.SelectorItem, len(cfg.Accounts))
+		for i, acc := range cfg.Accounts {
+			desc := "No SSH configured"
+			if acc.SSH != nil {
+				desc = acc.SSH.KeyPath
+			}
+			items[i] = ui.SelectorItem{
+				Title:       acc.Name,
+				Description: desc,
+				Value:       acc.Name,
+			}
+		}
+
+		idx, err := ui.RunSelector("Select Account for Key Upload", items)
+		if err != nil {
+			ui.ShowError(fmt.Sprintf("Selection error: %v", err))
+			return
+		}
+		if idx < 0 {
+			ui.ShowInfo("Cancelled")
+			return
+		}
+		accountName = items[idx].Value
+	}
+
+	acc := account.NewManager(cfg).Find(accountName)
+	if acc == nil {
+		ui.ShowError(fmt.Sprintf("Account '%s' not found", accountName))
+		return
+	}
+	if acc.SSH == nil {
+		ui.ShowWarning("Account has no SSH configuration")
+		return
+	}
+
+	platformInfo := GetPlatformInfo(acc)
+
+	pubPath, err := ssh.EnsurePublicKey(acc.SSH.KeyPath)
+	if err != nil {
+		ui.ShowError(fmt.Sprintf("Failed to read public key: %v", err))
+		return
 	}
-	if comment == "" {
-		comment = fmt.Sprintf("%s@github", acc.Name)
+	pubKey, err := ssh.ReadPublicKey(pubPath)
+	if err != nil {
+		ui.ShowError(err.Error())
+		return
 	}
 
+	client, err := api.NewClientForAccount(acc)
+	if err != nil {
+		ui.ShowError(fmt.Sprintf("Cannot upload key: %v", err))
+		if errors.Is(err, api.ErrNoToken) || errors.Is(err, api.ErrUnsupportedPlatform) {
+			ui.ShowInfo(fmt.Sprintf("Add it manually at: %s", platformInfo.KeysURL))
+			ui.ShowInfo(fmt.Sprintf("Public key: %s", pubPath))
+		}
+		return
+	}
+
+	if title == "" {
+		title = defaultKeyTitle(acc)
+	}
+
+	fmt.Println()
+	ui.ShowInfo(fmt.Sprintf("🔑 Key: %s (%s)", pubPath, pubKey.Fingerprint()))
+	ui.ShowInfo(fmt.Sprintf("🌐 Platform: %s %s", platformInfo.Icon, platformInfo.Name))
 	fmt.Println()
-	spinner := ui.NewSpinner("Generating SSH key...")
+
+	spinner := ui.NewSpinner("Uploading public key...")
 	spinner.Start()
 
-	if err := ssh.GenerateKey(acc.SSH.KeyPath, comment); err != nil {
-		spinner.StopWithError(fmt.Sprintf("Failed to generate key: %v", err))
+	result, err := client.UploadKey(title, strings.TrimSpace(pubKey.Authorized()+" "+pubKey.Comment), signing)
+	if err != nil && result == nil {
+		spinner.StopWithError(fmt.Sprintf("Upload failed: %v", err))
+		if errors.Is(err, api.ErrUnauthorized) {
+			ui.ShowInfo("Make sure the token can manage SSH keys (e.g. admin:public_key, write:ssh_signing_key, api)")
+		}
 		return
 	}
 
-	spinner.StopWithSuccess(fmt.Sprintf("Generated SSH key: %s", acc.SSH.KeyPath))
-	ui.ShowInfo(fmt.Sprintf("Public key: %s.pub", acc.SSH.KeyPath))
+	if result.Added {
+		spinner.StopWithSuccess(fmt.Sprintf("Added key \"%s\" to %s", title, platformInfo.Name))
+	} else {
+		spinner.StopWithSuccess(fmt.Sprintf("Key already registered as \"%s\"", result.Existing.Title))
+	}
+
+	if signing {
+		switch {
+		case result.SigningAdded:
+			ui.ShowSuccess("Registered as signing key")
+		case result.SigningExisting:
+			ui.ShowInfo("Already registered as signing key")
+		}
+	}
+	if err != nil {
+		ui.ShowWarning(err.Error())
+	}
 }
 
 func runImportSSHKey(cfg *config.AppConfig) {
@@ -271,12 +494,11 @@ func runImportSSHKey(cfg *config.AppConfig) {
 	acc.SSH.KeyPath = destPath
 
 	// Ask if user wants to set as default
-	if ui.Confirm("Set as default SSH key for github.com?") {
-		host := "github.com"
-		if acc.Platform != nil && acc.Platform.Domain != "" {
-			host = acc.Platform.Domain
-		}
-		if err := ssh.EnsureConfigBlock(host, destPath, host); err != nil {
+	platformInfo := GetPlatformInfo(acc)
+	if ui.Confirm(fmt.Sprintf("Set as default SSH key for %s?", platformInfo.Host)) {
+		host := platformInfo.Host
+		hostOpts := ssh.HostOptions{User: platformInfo.SSHUser, Port: platformInfo.SSHPort}
+		if err := ssh.EnsureConfigBlockWithOptions(host, destPath, host, hostOpts); err != nil {
 			ui.ShowWarning(fmt.Sprintf("Failed to configure SSH: %v", err))
 		} else {
 			ui.ShowSuccess(fmt.Sprintf("Set as default Host %s", host))
@@ -296,10 +518,7 @@ func runImportSSHKey(cfg *config.AppConfig) {
 
 	// Ask if user wants to test connection
 	if ui.Confirm("Test SSH connection now?") {
-		host := "github.com"
-		if acc.Platform != nil && acc.Platform.Domain != "" {
-			host = acc.Platform.Domain
-		}
+		host := platformInfo.Host
 
 		// Expand destPath for testing
 		expandedDest := destPath
@@ -308,17 +527,13 @@ func runImportSSHKey(cfg *config.AppConfig) {
 			expandedDest = strings.Replace(expandedDest, "~", home, 1)
 		}
 
-		// Auto-fix permissions for ALL keys
-		fixedCount, _ := ssh.FixAllKeyPermissions()
-		if fixedCount > 0 {
-			ui.ShowInfo(fmt.Sprintf("Fixed permissions for %d SSH key(s)", fixedCount))
-		}
+		warnPermissionIssues()
 
 		ui.ShowInfo(fmt.Sprintf("Testing with key: %s", destPath))
 		spinner := ui.NewSpinner(fmt.Sprintf("Testing SSH connection to %s...", host))
 		spinner.Start()
 
-		ok, msg, _ := ssh.TestConnectionWithKey(host, expandedDest)
+		ok, msg, _ := ssh.TestConnectionWithOptions(platformInfo.ConnectionOptions(expandedDest))
 		if ok {
 			spinner.StopWithSuccess(fmt.Sprintf("SSH: %s", msg))
 		} else {
@@ -364,20 +579,20 @@ func runSwitchGlobalSSH(cfg *config.AppConfig) {
 			return
 		}
 
+		act := startActivity(cfg, "global-ssh", "")
+		act.entry.Platform = account.PlatformGitHub
+		act.entry.Detail = keys[idx]
 		if err := ssh.EnsureConfigBlock("github.com", keys[idx], "github.com"); err != nil {
-			ui.ShowError(fmt.Sprintf("Failed to configure SSH: %v", err))
+			act.fail(fmt.Sprintf("Failed to configure SSH: %v", err))
 			return
 		}
+		act.done(nil)
 
 		ui.ShowSuccess(fmt.Sprintf("Set global SSH to: %s", keys[idx]))
 
 		// Ask to test connection
 		if ui.Confirm("Test SSH connection now?") {
-			// Auto-fix permissions for ALL keys
-			fixedCount, _ := ssh.FixAllKeyPermissions()
-			if fixedCount > 0 {
-				ui.ShowInfo(fmt.Sprintf("Fixed permissions for %d SSH key(s)", fixedCount))
-			}
+			warnPermissionIssues()
 
 			ui.ShowInfo(fmt.Sprintf("Testing with key: %s", keys[idx]))
 			spinner := ui.NewSpinner("Testing SSH connection to github.com...")
@@ -399,19 +614,7 @@ func runSwitchGlobalSSH(cfg *config.AppConfig) {
 	// Build items for selector
 	items := make([]ui.SelectorItem, len(sshAccounts))
 	for i, acc := range sshAccounts {
-		platformName := "GitHub"
-		if acc.Platform != nil {
-			switch acc.Platform.Type {
-			case "gitlab":
-				platformName = "GitLab"
-			case "bitbucket":
-				platformName = "Bitbucket"
-			case "gitea":
-				platformName = "Gitea"
-			case "codeberg":
-				platformName = "Codeberg"
-			}
-		}
+		platformName := GetPlatformInfo(&acc).Name
 		items[i] = ui.SelectorItem{
 			Title:       acc.Name,
 			Description: fmt.Sprintf("%s • %s", platformName, acc.SSH.KeyPath),
@@ -432,31 +635,10 @@ func runSwitchGlobalSSH(cfg *config.AppConfig) {
 	acc := sshAccounts[idx]
 
 	// Get platform-specific host
-	host := "github.com"
-	platformName := "GitHub"
-	platformIcon := "🐙"
-	if acc.Platform != nil {
-		switch acc.Platform.Type {
-		case "gitlab":
-			host = "gitlab.com"
-			platformName = "GitLab"
-			platformIcon = "🦊"
-		case "bitbucket":
-			host = "bitbucket.org"
-			platformName = "Bitbucket"
-			platformIcon = "🪣"
-		case "gitea":
-			platformName = "Gitea"
-			platformIcon = "🍵"
-		case "codeberg":
-			host = "codeberg.org"
-			platformName = "Codeberg"
-			platformIcon = "🏔️"
-		}
-		if acc.Platform.Domain != "" {
-			host = acc.Platform.Domain
-		}
-	}
+	platformInfo := GetPlatformInfo(&acc)
+	host := platformInfo.Host
+	platformName := platformInfo.Name
+	platformIcon := platformInfo.Icon
 
 	keyPath := acc.SSH.KeyPath
 
@@ -480,11 +662,20 @@ func runSwitchGlobalSSH(cfg *config.AppConfig) {
 			spinner := ui.NewSpinner("Generating SSH key...")
 			spinner.Start()
 
-			if err := ssh.GenerateKey(keyPath, comment); err != nil {
+			keygen := startActivity(cfg, "keygen", acc.Name)
+			keygen.entry.Detail = fmt.Sprintf("%s %s", ssh.KeyTypeEd25519, keyPath)
+			err := ssh.GenerateKey(keyPath, comment)
+			keygen.done(err)
+			if err != nil {
 				spinner.StopWithError(fmt.Sprintf("Failed to generate key: %v", err))
 				return
 			}
 			spinner.StopWithSuccess(fmt.Sprintf("Generated SSH key: %s", keyPath))
+
+			acc.SSH.KeyCreatedAt = time.Now().UTC().Format(time.RFC3339)
+			if err := config.Save(cfg); err != nil {
+				ui.ShowWarning(fmt.Sprintf("Failed to save config: %v", err))
+			}
 		} else {
 			ui.ShowInfo("Aborted")
 			return
@@ -492,47 +683,38 @@ func runSwitchGlobalSSH(cfg *config.AppConfig) {
 	}
 
 	fmt.Println()
-	if err := ssh.EnsureConfigBlock(host, keyPath, host); err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to configure SSH: %v", err))
+	act := startActivity(cfg, "global-ssh", acc.Name)
+	act.entry.Method = string(account.MethodSSH)
+	act.entry.Platform = platformInfo.Type
+	act.entry.Detail = fmt.Sprintf("%s %s", host, keyPath)
+	hostOpts := ssh.HostOptions{User: platformInfo.SSHUser, Port: platformInfo.SSHPort}
+	if err := ssh.EnsureConfigBlockWithOptions(host, keyPath, host, hostOpts); err != nil {
+		act.fail(fmt.Sprintf("Failed to configure SSH: %v", err))
 		return
 	}
+	act.done(nil)
 
-	ui.ShowSuccess(fmt.Sprintf("Updated ~/.ssh/config → Host %s %s (%s) using: %s", platformIcon, platformName, host, keyPath))
+	ui.ShowSuccess(fmt.Sprintf("Updated ~/.ssh/config.d/ghex → Host %s %s (%s) using: %s", platformIcon, platformName, host, keyPath))
 
 	// Ask to test connection
 	if ui.Confirm("Test SSH connection now?") {
-		// Auto-fix permissions for ALL keys
-		fixedCount, _ := ssh.FixAllKeyPermissions()
-		if fixedCount > 0 {
-			ui.ShowInfo(fmt.Sprintf("Fixed permissions for %d SSH key(s)", fixedCount))
-		}
+		warnPermissionIssues()
 
 		ui.ShowInfo(fmt.Sprintf("Testing with key: %s", keyPath))
 		spinner := ui.NewSpinner(fmt.Sprintf("Testing SSH connection to %s (%s)...", platformName, host))
 		spinner.Start()
 
-		ok, msg, _ := ssh.TestConnectionWithKey(host, expandedPath)
+		ok, msg, _ := ssh.TestConnectionWithOptions(platformInfo.ConnectionOptions(expandedPath))
 		if ok {
 			spinner.StopWithSuccess(fmt.Sprintf("SSH: %s", msg))
 		} else {
 			spinner.StopWithError(fmt.Sprintf("SSH: %s", msg))
 			ui.ShowWarning(fmt.Sprintf("Make sure your SSH key is added to %s:", platformName))
 			ui.ShowInfo(fmt.Sprintf("1. Copy your public key: cat %s.pub", keyPath))
-			platformType := "github"
-			if acc.Platform != nil {
-				platformType = acc.Platform.Type
-			}
-			switch platformType {
-			case "gitlab":
-				ui.ShowInfo("2. Add it at: https://gitlab.com/-/profile/keys")
-			case "bitbucket":
-				ui.ShowInfo("2. Add it at: https://bitbucket.org/account/settings/ssh-keys/")
-			case "codeberg":
-				ui.ShowInfo("2. Add it at: https://codeberg.org/user/settings/keys")
-			case "gitea":
+			if platformInfo.KeysURL != "" {
+				ui.ShowInfo(fmt.Sprintf("2. Add it at: %s", platformInfo.KeysURL))
+			} else {
 				ui.ShowInfo("2. Add it at your Gitea instance: /user/settings/keys")
-			default:
-				ui.ShowInfo("2. Add it at: https://github.com/settings/keys")
 			}
 		}
 	}
@@ -541,11 +723,7 @@ func runSwitchGlobalSSH(cfg *config.AppConfig) {
 func runTestConnection(cfg *config.AppConfig) {
 	ui.ShowSection("Test Connection")
 
-	// Fix permissions for ALL SSH keys first
-	fixedCount, _ := ssh.FixAllKeyPermissions()
-	if fixedCount > 0 {
-		ui.ShowInfo(fmt.Sprintf("Fixed permissions for %d SSH key(s)", fixedCount))
-	}
+	warnPermissionIssues()
 
 	// If no accounts, offer to test SSH keys directly
 	if len(cfg.Accounts) == 0 {
@@ -578,24 +756,9 @@ func runTestConnection(cfg *config.AppConfig) {
 		if acc.Token != nil {
 			methods = append(methods, "🔐 Token")
 		}
-		platformName := "GitHub"
-		platformIcon := "🐙"
-		if acc.Platform != nil {
-			switch acc.Platform.Type {
-			case "gitlab":
-				platformName = "GitLab"
-				platformIcon = "🦊"
-			case "bitbucket":
-				platformName = "Bitbucket"
-				platformIcon = "🪣"
-			case "gitea":
-				platformName = "Gitea"
-				platformIcon = "🍵"
-			case "codeberg":
-				platformName = "Codeberg"
-				platformIcon = "🏔️"
-			}
-		}
+		platformInfo := GetPlatformInfo(&acc)
+		platformName := platformInfo.Name
+		platformIcon := platformInfo.Icon
 		items[i+1] = ui.SelectorItem{
 			Title:       acc.Name,
 			Description: fmt.Sprintf("%s %s • %s", platformIcon, platformName, strings.Join(methods, ", ")),
@@ -627,33 +790,6 @@ func runTestConnection(cfg *config.AppConfig) {
 	// Get the account (index is offset by 1 because of the direct test option)
 	acc := cfg.Accounts[idx-1]
 
-	// Get platform info
-	host := "github.com"
-	platformName := "GitHub"
-	platformIcon := "🐙"
-	if acc.Platform != nil {
-		switch acc.Platform.Type {
-		case "gitlab":
-			host = "gitlab.com"
-			platformName = "GitLab"
-			platformIcon = "🦊"
-		case "bitbucket":
-			host = "bitbucket.org"
-			platformName = "Bitbucket"
-			platformIcon = "🪣"
-		case "gitea":
-			platformName = "Gitea"
-			platformIcon = "🍵"
-		case "codeberg":
-			host = "codeberg.org"
-			platformName = "Codeberg"
-			platformIcon = "🏔️"
-		}
-		if acc.Platform.Domain != "" {
-			host = acc.Platform.Domain
-		}
-	}
-
 	// If both methods available, ask which to test
 	if acc.SSH != nil && acc.Token != nil {
 		methodItems := []ui.SelectorItem{
@@ -672,13 +808,13 @@ func runTestConnection(cfg *config.AppConfig) {
 
 		switch methodItems[methodIdx].Value {
 		case "ssh":
-			testSSHConnection(acc, host, platformName, platformIcon)
+			testSSHConnection(cfg, acc)
 		case "token":
-			testTokenConnection(acc, platformName)
+			testTokenConnection(cfg, acc)
 		case "both":
-			testSSHConnection(acc, host, platformName, platformIcon)
+			testSSHConnection(cfg, acc)
 			fmt.Println()
-			testTokenConnection(acc, platformName)
+			testTokenConnection(cfg, acc)
 		}
 		return
 	}
@@ -686,42 +822,120 @@ func runTestConnection(cfg *config.AppConfig) {
 	fmt.Println()
 
 	if acc.SSH != nil {
-		testSSHConnection(acc, host, platformName, platformIcon)
+		testSSHConnection(cfg, acc)
 	}
 
 	if acc.Token != nil {
-		testTokenConnection(acc, platformName)
+		testTokenConnection(cfg, acc)
+	}
+}
+
+// testSSHConnection tests an account's SSH key and logs the result
+func testSSHConnection(cfg *config.AppConfig, acc config.Account) {
+	act := startActivity(cfg, "test", acc.Name)
+	act.entry.Method = string(account.MethodSSH)
+	act.entry.Platform = GetPlatformInfo(&acc).Type
+
+	var err error
+	if !TestAccountSSH(&acc, true) {
+		err = errors.New("SSH connection test failed")
 	}
+	act.done(err)
 }
 
-// testSSHConnection uses helper function to test SSH connection
-func testSSHConnection(acc config.Account, host, platformName, platformIcon string) {
-	TestAccountSSH(&acc, true)
+// testTokenConnection tests an account's token and logs the result
+func testTokenConnection(cfg *config.AppConfig, acc config.Account) {
+	act := startActivity(cfg, "test", acc.Name)
+	act.entry.Method = string(account.MethodToken)
+	act.entry.Platform = GetPlatformInfo(&acc).Type
+
+	var err error
+	if !TestAccountToken(&acc, true) {
+		err = errors.New("token authentication failed")
+	}
+	act.done(err)
 }
 
-// testTokenConnection uses helper function to test token connection
-func testTokenConnection(acc config.Account, platformName string) {
-	TestAccountToken(&acc, true)
+// accountKeyReferences returns the key path of every account using SSH
+func accountKeyReferences(cfg *config.AppConfig) []ssh.KeyReference {
+	var refs []ssh.KeyReference
+	for _, acc := range cfg.Accounts {
+		if acc.SSH != nil && acc.SSH.KeyPath != "" {
+			refs = append(refs, ssh.KeyReference{Account: acc.Name, KeyPath: acc.SSH.KeyPath})
+		}
+	}
+	return refs
 }
 
-func runListSSHKeys() {
-	keys, err := ssh.ListPrivateKeys()
+func runListSSHKeys(cfg *config.AppConfig, sortBy string, asJSON bool) {
+	inv, err := ssh.BuildInventory(accountKeyReferences(cfg))
 	if err != nil {
 		ui.ShowError(fmt.Sprintf("Failed to list SSH keys: %v", err))
 		return
 	}
+	if err := ssh.SortKeys(inv.Keys, sortBy); err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
 
-	if len(keys) == 0 {
+	if asJSON {
+		data, err := json.MarshalIndent(inv, "", "  ")
+		if err != nil {
+			ui.ShowError(fmt.Sprintf("Failed to encode inventory: %v", err))
+			return
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(inv.Keys) == 0 && len(inv.Missing) == 0 {
 		ui.ShowWarning("No SSH keys found in ~/.ssh")
 		return
 	}
 
 	ui.ShowSection("SSH Keys")
-	for _, key := range keys {
-		fmt.Printf("  • %s\n", ui.Accent(key))
+	orphaned := 0
+	for _, key := range inv.Keys {
+		fmt.Printf("  • %s\n", ui.Accent(key.Path))
+		if key.Error != "" {
+			fmt.Printf("    %s\n", ui.Error(key.Error))
+			continue
+		}
+
+		details := fmt.Sprintf("%s %d bits  %s", key.Type, key.Bits, key.Fingerprint)
+		if key.Comment != "" {
+			details += "  " + key.Comment
+		}
+		fmt.Printf("    %s\n", ui.Muted(details))
+
+		status := []string{"modified " + key.Modified.Format("2006-01-02")}
+		if key.Encrypted {
+			status = append(status, "passphrase")
+		}
+		switch key.PublicKeyStatus {
+		case ssh.PubKeyMismatch:
+			status = append(status, ui.Error(".pub does not match"))
+		case ssh.PubKeyMissing:
+			status = append(status, ui.Error(".pub missing"))
+		case ssh.PubKeyUnknown:
+			status = append(status, ".pub not verified (encrypted key)")
+		}
+		fmt.Printf("    %s\n", strings.Join(status, ", "))
+
+		if key.Orphaned {
+			orphaned++
+			fmt.Printf("    %s\n", ui.Muted("not used by any account"))
+		} else {
+			fmt.Printf("    accounts: %s\n", ui.Success(strings.Join(key.Accounts, ", ")))
+		}
+	}
+
+	for _, ref := range inv.Missing {
+		ui.ShowWarning(fmt.Sprintf("Account %s points to missing key %s", ref.Account, ref.KeyPath))
 	}
+
 	fmt.Println()
-	ui.ShowInfo(fmt.Sprintf("Total: %d keys", len(keys)))
+	ui.ShowInfo(fmt.Sprintf("Total: %d keys, %d not used by any account", len(inv.Keys), orphaned))
 }
 
 // testSSHKeyDirectly allows testing any SSH key directly without an account
@@ -0,0 +1,227 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/dwirx/ghex/internal/account"
+	"github.com/dwirx/ghex/internal/config"
+	"github.com/dwirx/ghex/internal/ssh"
+	"github.com/dwirx/ghex/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// newSSHAgentCmd creates the ssh agent command group
+func newSSHAgentCmd() *cobra.Command {
+	agentCmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Manage account keys in ssh-agent",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, _ := config.Load()
+			runSSHAgentMenu(cfg)
+		},
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add",
+		Short: "Load an account key into ssh-agent",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, _ := config.Load()
+			accountName, _ := cmd.Flags().GetString("account")
+			lifetime, _ := cmd.Flags().GetString("lifetime")
+			runAgentAdd(cfg, accountName, lifetime)
+		},
+	}
+	addCmd.Flags().StringP("account", "a", "", "Account name")
+	addCmd.Flags().StringP("lifetime", "l", "", "Unload the key after this duration (e.g. 30m, 8h)")
+	agentCmd.AddCommand(addCmd)
+
+	removeCmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Unload an account key from ssh-agent",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, _ := config.Load()
+			accountName, _ := cmd.Flags().GetString("account")
+			runAgentRemove(cfg, accountName)
+		},
+	}
+	removeCmd.Flags().StringP("account", "a", "", "Account name")
+	agentCmd.AddCommand(removeCmd)
+
+	agentCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List keys loaded in ssh-agent",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, _ := config.Load()
+			runAgentList(cfg)
+		},
+	})
+
+	return agentCmd
+}
+
+func runSSHAgentMenu(cfg *config.AppConfig) {
+	items := []ui.SelectorItem{
+		{Title: "➕ Add key", Description: "Load an account key into ssh-agent", Value: "add"},
+		{Title: "➖ Remove key", Description: "Unload an account key", Value: "remove"},
+		{Title: "📋 List keys", Description: "Show loaded keys and their accounts", Value: "list"},
+		{Title: "🔙 Back", Description: "Return to SSH menu", Value: "back"},
+	}
+
+	idx, err := ui.RunSelector("ssh-agent", items)
+	if err != nil || idx < 0 {
+		return
+	}
+
+	switch items[idx].Value {
+	case "add":
+		runAgentAdd(cfg, "", "")
+	case "remove":
+		runAgentRemove(cfg, "")
+	case "list":
+		runAgentList(cfg)
+	}
+}
+
+// selectSSHAccount finds an account with SSH configured by name, or asks for one
+func selectSSHAccount(cfg *config.AppConfig, accountName, title string) *config.Account {
+	if accountName != "" {
+		acc := account.NewManager(cfg).Find(accountName)
+		if acc == nil {
+			ui.ShowError(fmt.Sprintf("Account '%s' not found", accountName))
+			return nil
+		}
+		if acc.SSH == nil {
+			ui.ShowWarning("Account has no SSH configuration")
+			return nil
+		}
+		return acc
+	}
+
+	var items []ui.SelectorItem
+	for _, acc := range cfg.Accounts {
+		if acc.SSH == nil {
+			continue
+		}
+		items = append(items, ui.SelectorItem{
+			Title:       acc.Name,
+			Description: acc.SSH.KeyPath,
+			Value:       acc.Name,
+		})
+	}
+	if len(items) == 0 {
+		ui.ShowWarning("No accounts with SSH configured")
+		return nil
+	}
+
+	idx, err := ui.RunSelector(title, items)
+	if err != nil {
+		ui.ShowError(fmt.Sprintf("Selection error: %v", err))
+		return nil
+	}
+	if idx < 0 {
+		ui.ShowInfo("Cancelled")
+		return nil
+	}
+	return account.NewManager(cfg).Find(items[idx].Value)
+}
+
+func runAgentAdd(cfg *config.AppConfig, accountName, lifetimeStr string) {
+	if !ssh.IsAgentRunning() {
+		ui.ShowError("ssh-agent is not running")
+		ui.ShowInfo("Start one with: eval \"$(ssh-agent -s)\"")
+		return
+	}
+
+	acc := selectSSHAccount(cfg, accountName, "Select Account Key to Load")
+	if acc == nil {
+		return
+	}
+
+	if lifetimeStr == "" {
+		lifetimeStr = acc.SSH.AgentLifetime
+	}
+	lifetime, err := account.ParseAgentLifetime(lifetimeStr)
+	if err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+
+	keyPath := ExpandKeyPath(acc.SSH.KeyPath)
+	if ssh.IsKeyInAgent(keyPath) {
+		ui.ShowInfo(fmt.Sprintf("Key for '%s' is already loaded", acc.Name))
+		return
+	}
+
+	if err := ssh.AddToAgent(keyPath, lifetime); err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+
+	if lifetime > 0 {
+		ui.ShowSuccess(fmt.Sprintf("Loaded %s for %s", acc.SSH.KeyPath, lifetime))
+	} else {
+		ui.ShowSuccess(fmt.Sprintf("Loaded %s", acc.SSH.KeyPath))
+	}
+
+	if !acc.SSH.AddToAgent && ui.Confirm(fmt.Sprintf("Load this key automatically when switching to '%s'?", acc.Name)) {
+		acc.SSH.AddToAgent = true
+		acc.SSH.AgentLifetime = lifetimeStr
+		if err := config.Save(cfg); err != nil {
+			ui.ShowWarning(fmt.Sprintf("Failed to save config: %v", err))
+		}
+	}
+}
+
+func runAgentRemove(cfg *config.AppConfig, accountName string) {
+	acc := selectSSHAccount(cfg, accountName, "Select Account Key to Unload")
+	if acc == nil {
+		return
+	}
+
+	keyPath := ExpandKeyPath(acc.SSH.KeyPath)
+	if !ssh.IsKeyInAgent(keyPath) {
+		ui.ShowInfo(fmt.Sprintf("Key for '%s' is not loaded", acc.Name))
+		return
+	}
+
+	if err := ssh.RemoveFromAgent(keyPath); err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+	ui.ShowSuccess(fmt.Sprintf("Unloaded %s", acc.SSH.KeyPath))
+}
+
+func runAgentList(cfg *config.AppConfig) {
+	keys, err := ssh.ListAgentKeys()
+	if err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+
+	if len(keys) == 0 {
+		ui.ShowInfo("ssh-agent has no keys loaded")
+		return
+	}
+
+	// Map fingerprints to accounts
+	owners := make(map[string]string)
+	for _, acc := range cfg.Accounts {
+		if acc.SSH == nil {
+			continue
+		}
+		if fp, err := ssh.KeyFingerprint(acc.SSH.KeyPath); err == nil {
+			owners[fp] = acc.Name
+		}
+	}
+
+	ui.ShowSection("ssh-agent Keys")
+	for _, key := range keys {
+		owner := ui.Muted("(no account)")
+		if name, ok := owners[key.Fingerprint]; ok {
+			owner = ui.Accent(name)
+		}
+		fmt.Printf("  • %s %s %s %s\n", owner, key.Type, key.Fingerprint, ui.Muted(key.Comment))
+	}
+	fmt.Println()
+	ui.ShowInfo(fmt.Sprintf("Total: %d keys", len(keys)))
+}
@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dwirx/ghex/internal/ssh"
+	"github.com/dwirx/ghex/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// newSSHConfigCmd creates the ssh config command group
+func newSSHConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and revert ghex changes to the SSH config",
+		Run: func(cmd *cobra.Command, args []string) {
+			runSSHConfigShow()
+		},
+	}
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Show the ghex-managed SSH config",
+		Run: func(cmd *cobra.Command, args []string) {
+			runSSHConfigShow()
+		},
+	})
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "diff [snapshot]",
+		Short: "Show changes since the latest (or given) snapshot",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runSSHConfigDiff(firstArg(args))
+		},
+	})
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "restore [snapshot]",
+		Short: "Restore the SSH config from a snapshot",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runSSHConfigRestore(firstArg(args))
+		},
+	})
+
+	return configCmd
+}
+
+// firstArg returns the first argument or ""
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+func runSSHConfigShow() {
+	path := ssh.GetManagedConfigPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			ui.ShowInfo("ghex has not written any SSH config yet")
+			return
+		}
+		ui.ShowError(fmt.Sprintf("Failed to read %s: %v", path, err))
+		return
+	}
+
+	ui.ShowSection("Managed SSH Config")
+	fmt.Println(ui.Muted(path))
+	fmt.Println()
+	fmt.Println(strings.TrimRight(string(data), "\n"))
+	fmt.Println()
+
+	user, err := ssh.LoadConfig(ssh.GetSSHConfigPath())
+	if err == nil && !user.Includes(path) {
+		ui.ShowWarning(fmt.Sprintf("~/.ssh/config does not include this file; add: Include %s", ssh.ManagedInclude))
+	}
+
+	if snapshots, _ := ssh.ListSnapshots(); len(snapshots) > 0 {
+		ui.ShowInfo(fmt.Sprintf("%d snapshots available (latest %s)", len(snapshots), snapshots[0].Time.Format("2006-01-02 15:04:05")))
+	}
+}
+
+func runSSHConfigDiff(id string) {
+	snap, err := ssh.GetSnapshot(id)
+	if err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+
+	diff, err := snap.Diff()
+	if err != nil {
+		ui.ShowError(fmt.Sprintf("Failed to compare: %v", err))
+		return
+	}
+	if diff == "" {
+		ui.ShowInfo(fmt.Sprintf("No changes since snapshot %s", snap.ID))
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			fmt.Println(ui.Accent(line))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Println(ui.Muted(line))
+		case strings.HasPrefix(line, "+"):
+			fmt.Println(ui.Success(line))
+		case strings.HasPrefix(line, "-"):
+			fmt.Println(ui.Error(line))
+		default:
+			fmt.Println(line)
+		}
+	}
+}
+
+func runSSHConfigRestore(id string) {
+	if id == "" {
+		snapshots, err := ssh.ListSnapshots()
+		if err != nil {
+			ui.ShowError(err.Error())
+			return
+		}
+		if len(snapshots) == 0 {
+			ui.ShowWarning("No SSH config snapshots found")
+			return
+		}
+
+		var items []ui.SelectorItem
+		for _, s := range snapshots {
+			items = append(items, ui.SelectorItem{
+				Title:       s.Time.Format("2006-01-02 15:04:05"),
+				Description: s.ID,
+				Value:       s.ID,
+			})
+		}
+		idx, err := ui.RunSelector("Select Snapshot to Restore", items)
+		if err != nil || idx < 0 {
+			ui.ShowInfo("Cancelled")
+			return
+		}
+		id = items[idx].Value
+	}
+
+	snap, err := ssh.GetSnapshot(id)
+	if err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+
+	if diff, err := snap.Diff(); err == nil && diff == "" {
+		ui.ShowInfo("SSH config already matches this snapshot")
+		return
+	}
+
+	if !ui.Confirm(fmt.Sprintf("Restore SSH config from %s?", snap.Time.Format("2006-01-02 15:04:05"))) {
+		ui.ShowInfo("Cancelled")
+		return
+	}
+
+	if err := snap.Restore(); err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+	ui.ShowSuccess("SSH config restored (the previous state was saved as a new snapshot)")
+}
+
+func runSSHConfigMenu() {
+	items := []ui.SelectorItem{
+		{Title: "📄 Show", Description: "Show the ghex-managed SSH config", Value: "show"},
+		{Title: "🔍 Diff", Description: "Show changes since the latest snapshot", Value: "diff"},
+		{Title: "⏪ Restore", Description: "Restore the SSH config from a snapshot", Value: "restore"},
+		{Title: "🔙 Back", Description: "Return to SSH menu", Value: "back"},
+	}
+
+	idx, err := ui.RunSelector("SSH Config", items)
+	if err != nil || idx < 0 {
+		return
+	}
+
+	switch items[idx].Value {
+	case "show":
+		runSSHConfigShow()
+	case "diff":
+		runSSHConfigDiff("")
+	case "restore":
+		runSSHConfigRestore("")
+	}
+}
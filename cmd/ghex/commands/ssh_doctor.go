@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/dwirx/ghex/internal/ssh"
+	"github.com/dwirx/ghex/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// newSSHDoctorCmd creates the ssh doctor command
+func newSSHDoctorCmd() *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check ~/.ssh file permissions",
+		Long:  "Report files in ~/.ssh whose permissions ssh would reject or that others could modify. With --fix, ask before changing them and record each change.",
+		Run: func(cmd *cobra.Command, args []string) {
+			fix, _ := cmd.Flags().GetBool("fix")
+			runSSHDoctor(fix)
+		},
+	}
+	doctorCmd.Flags().Bool("fix", false, "Fix the reported permissions (asks first)")
+	return doctorCmd
+}
+
+// warnPermissionIssues points to ssh doctor when ~/.ssh has permission problems
+func warnPermissionIssues() {
+	issues, _ := ssh.CheckPermissions()
+	if len(issues) > 0 {
+		ui.ShowWarning(fmt.Sprintf("%d permission problem(s) in ~/.ssh (run: ghex ssh doctor)", len(issues)))
+	}
+}
+
+func runSSHDoctor(fix bool) {
+	ui.ShowSection("SSH Doctor")
+
+	issues, err := ssh.CheckPermissions()
+	if err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+	if len(issues) == 0 {
+		ui.ShowSuccess("All ~/.ssh permissions look good")
+		return
+	}
+
+	for _, issue := range issues {
+		fmt.Printf("  %s %s\n", ui.Error("✗"), issue.Path)
+		fmt.Printf("    %s: %s, mode %s (%s) → %s\n", issue.Kind, issue.Reason, ssh.FormatMode(issue.Mode), issue.Mode.Perm(), ssh.FormatMode(issue.Want))
+	}
+	fmt.Println()
+
+	if !fix {
+		ui.ShowInfo("Run 'ghex ssh doctor --fix' to fix these permissions")
+		return
+	}
+
+	if !ui.Confirm(fmt.Sprintf("Change permissions of %d file(s)?", len(issues))) {
+		ui.ShowInfo("Cancelled")
+		return
+	}
+
+	changes, err := ssh.FixPermissions(issues)
+	for _, c := range changes {
+		ui.ShowSuccess(fmt.Sprintf("%s: %s → %s", c.Path, c.OldMode, c.NewMode))
+	}
+	if err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+	ui.ShowInfo(fmt.Sprintf("Changes recorded in %s", ssh.GetPermissionLogPath()))
+}
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dwirx/ghex/internal/config"
+	"github.com/dwirx/ghex/internal/ssh"
+	"github.com/dwirx/ghex/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// knownHost is a host and port to sync
+type knownHost struct {
+	Host string
+	Port int
+}
+
+// newSSHKnownHostsCmd creates the ssh known-hosts command group
+func newSSHKnownHostsCmd() *cobra.Command {
+	knownHostsCmd := &cobra.Command{
+		Use:   "known-hosts",
+		Short: "Manage pinned host keys in known_hosts",
+	}
+
+	syncCmd := &cobra.Command{
+		Use:   "sync [host[:port]...]",
+		Short: "Verify host keys and record them in known_hosts",
+		Long: `Scan each host with ssh-keyscan, verify its keys against the published
+fingerprints (or those pinned for custom platforms) and add them to
+~/.ssh/known_hosts. Without arguments, the hosts of all configured accounts
+are synced.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, _ := config.Load()
+			check, _ := cmd.Flags().GetBool("check")
+			replace, _ := cmd.Flags().GetBool("replace")
+			runKnownHostsSync(cfg, args, check, replace)
+		},
+	}
+	syncCmd.Flags().Bool("check", false, "Only verify, don't write known_hosts")
+	syncCmd.Flags().Bool("replace", false, "Replace known_hosts entries that contradict the verified keys")
+	knownHostsCmd.AddCommand(syncCmd)
+
+	return knownHostsCmd
+}
+
+// accountHosts returns the distinct SSH hosts of all accounts
+func accountHosts(cfg *config.AppConfig) []knownHost {
+	var hosts []knownHost
+	seen := make(map[knownHost]bool)
+	for i := range cfg.Accounts {
+		info := GetPlatformInfo(&cfg.Accounts[i])
+		h := knownHost{Host: info.Host, Port: info.SSHPort}
+		if !seen[h] {
+			seen[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// parseKnownHostArg parses "host" or "host:port"
+func parseKnownHostArg(arg string) (knownHost, error) {
+	host, portStr, found := strings.Cut(arg, ":")
+	if !found {
+		return knownHost{Host: arg}, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 {
+		return knownHost{}, fmt.Errorf("invalid port in %q", arg)
+	}
+	return knownHost{Host: host, Port: port}, nil
+}
+
+func runKnownHostsSync(cfg *config.AppConfig, args []string, check, replace bool) {
+	var hosts []knownHost
+	for _, arg := range args {
+		h, err := parseKnownHostArg(arg)
+		if err != nil {
+			ui.ShowError(err.Error())
+			return
+		}
+		hosts = append(hosts, h)
+	}
+	if len(hosts) == 0 {
+		hosts = accountHosts(cfg)
+	}
+	if len(hosts) == 0 {
+		ui.ShowWarning("No accounts configured. Pass hosts to sync, e.g. ghex ssh known-hosts sync github.com")
+		return
+	}
+
+	ui.ShowSection("Known Hosts")
+	failed := 0
+	for _, h := range hosts {
+		if !syncKnownHost(h, check, replace) {
+			failed++
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		ui.ShowError(fmt.Sprintf("%d of %d hosts could not be verified", failed, len(hosts)))
+		return
+	}
+	ui.ShowSuccess(fmt.Sprintf("Checked %d hosts", len(hosts)))
+}
+
+// syncKnownHost verifies and records one host, returning false on failure
+func syncKnownHost(h knownHost, check, replace bool) bool {
+	label := h.Host
+	if h.Port > 0 && h.Port != 22 {
+		label = fmt.Sprintf("%s:%d", h.Host, h.Port)
+	}
+
+	pinned := len(ssh.PinnedHostKeys(h.Host)) > 0
+	if check {
+		keys, err := ssh.ScanHostKeys(h.Host, h.Port)
+		if err == nil {
+			_, err = ssh.VerifyHostKeys(keys, ssh.PinnedHostKeys(h.Host))
+		}
+		if err != nil {
+			ui.ShowError(fmt.Sprintf("%s: %v", label, err))
+			return false
+		}
+		if !pinned {
+			ui.ShowWarning(fmt.Sprintf("%s: no pinned fingerprints", label))
+			printHostKeys(keys)
+			return true
+		}
+		ui.ShowSuccess(fmt.Sprintf("%s: host keys match pinned fingerprints", label))
+		return true
+	}
+
+	trust := false
+	if !pinned {
+		keys, err := ssh.ScanHostKeys(h.Host, h.Port)
+		if err != nil {
+			ui.ShowError(fmt.Sprintf("%s: %v", label, err))
+			return false
+		}
+		ui.ShowWarning(fmt.Sprintf("%s has no pinned fingerprints. Compare these with the ones your provider publishes:", label))
+		printHostKeys(keys)
+		if !ui.Confirm(fmt.Sprintf("Trust these keys for %s?", label)) {
+			ui.ShowInfo("Skipped")
+			return true
+		}
+		trust = true
+	}
+
+	result, err := ssh.SyncKnownHosts(h.Host, h.Port, trust, replace)
+	if err != nil {
+		ui.ShowError(fmt.Sprintf("%s: %v", label, err))
+		return false
+	}
+
+	switch {
+	case result.Removed > 0:
+		ui.ShowSuccess(fmt.Sprintf("%s: replaced %d stale entries with %d verified keys", label, result.Removed, result.Added))
+	case result.Added > 0:
+		ui.ShowSuccess(fmt.Sprintf("%s: added %d verified keys", label, result.Added))
+	default:
+		ui.ShowSuccess(fmt.Sprintf("%s: known_hosts is up to date", label))
+	}
+	return true
+}
+
+// printHostKeys prints host key types and fingerprints
+func printHostKeys(keys []*ssh.PublicKey) {
+	for _, k := range keys {
+		fmt.Printf("    %s %s\n", ui.Muted(k.Type), k.Fingerprint())
+	}
+}
@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dwirx/ghex/internal/api"
+	"github.com/dwirx/ghex/internal/config"
+	"github.com/dwirx/ghex/internal/platform"
+	"github.com/dwirx/ghex/internal/ssh"
+	"github.com/dwirx/ghex/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// rotateOptions controls ghex ssh rotate
+type rotateOptions struct {
+	KeyType    string
+	Passphrase bool
+	Upload     bool // upload the new key through the platform API
+	RemoveOld  bool // delete the old key from the platform
+}
+
+// newSSHRotateCmd creates the ssh rotate command
+func newSSHRotateCmd() *cobra.Command {
+	rotateCmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Replace an account's SSH key with a new one",
+		Long: `Generate a new key for the account, point the account and its managed
+Host blocks at it, test it and archive the old key in ~/.ssh/archive.
+With --upload the new key is added to the platform first; with --remove-old
+the old key is deleted from the platform afterwards. Keys shared with other
+accounts are not rotated.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, _ := config.Load()
+			accountName, _ := cmd.Flags().GetString("account")
+			opts := rotateOptions{}
+			opts.KeyType, _ = cmd.Flags().GetString("type")
+			opts.Passphrase, _ = cmd.Flags().GetBool("passphrase")
+			opts.Upload, _ = cmd.Flags().GetBool("upload")
+			opts.RemoveOld, _ = cmd.Flags().GetBool("remove-old")
+			runSSHRotate(cfg, accountName, opts)
+		},
+	}
+	rotateCmd.Flags().StringP("account", "a", "", "Account name")
+	rotateCmd.Flags().StringP("type", "t", ssh.KeyTypeEd25519, "Key type: "+strings.Join(ssh.KeyTypes(), ", "))
+	rotateCmd.Flags().BoolP("passphrase", "p", false, "Protect the new key with a passphrase")
+	rotateCmd.Flags().Bool("upload", false, "Upload the new public key via the platform API")
+	rotateCmd.Flags().Bool("remove-old", false, "Delete the old public key from the platform")
+	return rotateCmd
+}
+
+func runSSHRotate(cfg *config.AppConfig, accountName string, opts rotateOptions) {
+	acc := selectSSHAccount(cfg, accountName, "Select Account for Key Rotation")
+	if acc == nil {
+		return
+	}
+
+	oldPath := acc.SSH.KeyPath
+	now := time.Now()
+	newPath := ssh.RotatedKeyPath(oldPath, now)
+	platformInfo := GetPlatformInfo(acc)
+
+	act := startActivity(cfg, "rotate", acc.Name)
+	act.entry.Platform = platformInfo.Type
+	act.entry.Detail = fmt.Sprintf("%s → %s", oldPath, newPath)
+
+	if !platform.FileExists(ExpandKeyPath(oldPath)) {
+		act.fail(fmt.Sprintf("Key not found: %s (use 'ghex ssh generate' instead)", oldPath))
+		return
+	}
+	if platform.FileExists(ExpandKeyPath(newPath)) {
+		act.fail(fmt.Sprintf("%s already exists", newPath))
+		return
+	}
+	// Other accounts' Host blocks and platform registrations would break
+	if shared := ssh.KeySharedWith(accountKeyReferences(cfg), acc.Name, oldPath); len(shared) > 0 {
+		act.fail(fmt.Sprintf("%s is also used by %s; give %s its own key with 'ghex ssh generate' instead", oldPath, strings.Join(shared, ", "), acc.Name))
+		return
+	}
+	oldPub, _ := ssh.ReadPublicKey(ExpandKeyPath(oldPath) + ".pub")
+
+	var client *api.Client
+	if opts.Upload || opts.RemoveOld {
+		var err error
+		if client, err = api.NewClientForAccount(acc); err != nil {
+			act.fail(fmt.Sprintf("Cannot use the %s API: %v", platformInfo.Name, err))
+			return
+		}
+	}
+
+	ui.ShowSection("Rotate SSH Key")
+	ui.ShowInfo(fmt.Sprintf("Account: %s (%s %s)", acc.Name, platformInfo.Icon, platformInfo.Name))
+	ui.ShowInfo(fmt.Sprintf("Old key: %s", oldPath))
+	ui.ShowInfo(fmt.Sprintf("New key: %s", newPath))
+	fmt.Println()
+
+	keyOpts := ssh.KeyOptions{Type: opts.KeyType, Comment: keyComment(acc), Passphrase: opts.Passphrase}
+	if opts.KeyType == ssh.KeyTypeEd25519SK {
+		ui.ShowInfo("Touch your security key when it blinks...")
+	} else if keyOpts.Passphrase {
+		ui.ShowInfo("Enter the passphrase when ssh-keygen asks for it")
+	}
+	if err := ssh.GenerateKeyWithOptions(newPath, keyOpts); err != nil {
+		act.fail(fmt.Sprintf("Failed to generate key: %v", err))
+		return
+	}
+	ui.ShowSuccess(fmt.Sprintf("Generated SSH key: %s", newPath))
+
+	newPub, err := ssh.ReadPublicKey(ExpandKeyPath(newPath) + ".pub")
+	if err != nil {
+		act.fail(err.Error())
+		return
+	}
+
+	if client != nil && opts.Upload {
+		spinner := ui.NewSpinner("Uploading new public key...")
+		spinner.Start()
+		_, err := client.UploadKey(defaultKeyTitle(acc), strings.TrimSpace(newPub.Authorized()+" "+newPub.Comment), false)
+		if err != nil {
+			spinner.StopWithError(fmt.Sprintf("Upload failed: %v", err))
+		} else {
+			spinner.StopWithSuccess(fmt.Sprintf("Added new key to %s", platformInfo.Name))
+		}
+	}
+
+	spinner := ui.NewSpinner(fmt.Sprintf("Testing new key against %s...", platformInfo.Host))
+	spinner.Start()
+	ok, msg, _ := ssh.TestConnectionWithOptions(platformInfo.ConnectionOptions(ExpandKeyPath(newPath)))
+	if ok {
+		spinner.StopWithSuccess(fmt.Sprintf("SSH: %s", msg))
+	} else {
+		spinner.StopWithError(fmt.Sprintf("SSH: %s", msg))
+		if platformInfo.KeysURL != "" {
+			ui.ShowInfo(fmt.Sprintf("Add %s.pub at: %s", newPath, platformInfo.KeysURL))
+		}
+		if !ui.Confirm("Switch to the new key anyway?") {
+			os.Remove(ExpandKeyPath(newPath))
+			os.Remove(ExpandKeyPath(newPath) + ".pub")
+			act.done(fmt.Errorf("new key failed the SSH test: %s", msg))
+			ui.ShowInfo(fmt.Sprintf("Kept %s; the new key was removed", oldPath))
+			return
+		}
+	}
+
+	accountHosts := []string{platformInfo.Host}
+	if acc.SSH.HostAlias != "" {
+		accountHosts = append(accountHosts, acc.SSH.HostAlias)
+	}
+	hosts, err := ssh.ReplaceIdentityFile(oldPath, newPath, accountHosts)
+	if err != nil {
+		ui.ShowWarning(fmt.Sprintf("Failed to update SSH config: %v", err))
+	}
+	for _, host := range hosts {
+		ui.ShowSuccess(fmt.Sprintf("Updated Host %s", host))
+	}
+
+	acc.SSH.KeyPath = newPath
+	acc.SSH.KeyCreatedAt = now.UTC().Format(time.RFC3339)
+	if err := config.Save(cfg); err != nil {
+		act.fail(fmt.Sprintf("Failed to save config: %v", err))
+		return
+	}
+	act.done(nil)
+
+	if ssh.IsKeyInAgent(ExpandKeyPath(oldPath)) {
+		ssh.RemoveFromAgent(ExpandKeyPath(oldPath))
+	}
+	archived, err := ssh.ArchiveKey(oldPath, now)
+	if err != nil {
+		ui.ShowWarning(fmt.Sprintf("Failed to archive old key: %v", err))
+	} else {
+		ui.ShowSuccess(fmt.Sprintf("Archived old key: %s", archived))
+	}
+
+	if client != nil && opts.RemoveOld {
+		removeOldPlatformKey(client, oldPub, platformInfo.Name)
+	}
+
+	fmt.Println()
+	ui.ShowSuccess(fmt.Sprintf("Rotated SSH key for %s", acc.Name))
+	if keyOpts.Passphrase {
+		ui.ShowInfo("Load it with: ghex ssh agent add --account " + acc.Name)
+	}
+}
+
+// removeOldPlatformKey deletes a rotated-out key from the platform
+func removeOldPlatformKey(client *api.Client, oldPub *ssh.PublicKey, platformName string) {
+	if oldPub == nil {
+		ui.ShowWarning("Old public key not found; remove it from the platform manually")
+		return
+	}
+
+	keys, err := client.ListSSHKeys()
+	if err != nil {
+		ui.ShowWarning(fmt.Sprintf("Failed to list keys on %s: %v", platformName, err))
+		return
+	}
+	key := api.FindKey(keys, oldPub.Authorized())
+	if key == nil {
+		ui.ShowInfo(fmt.Sprintf("Old key is not registered on %s", platformName))
+		return
+	}
+	if err := client.DeleteSSHKey(key.ID); err != nil {
+		ui.ShowWarning(fmt.Sprintf("Failed to remove old key \"%s\": %v", key.Title, err))
+		if errors.Is(err, api.ErrUnauthorized) {
+			ui.ShowInfo("Make sure the token can manage SSH keys (e.g. admin:public_key, api)")
+		}
+		return
+	}
+	ui.ShowSuccess(fmt.Sprintf("Removed old key \"%s\" from %s", key.Title, platformName))
+}
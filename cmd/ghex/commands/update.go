@@ -43,9 +43,11 @@ func runUpdate() {
 		return
 	}
 
+	act := startActivity(nil, "update", "")
+
 	updater, err := update.NewUpdater(Version)
 	if err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to initialize updater: %v", err))
+		act.fail(fmt.Sprintf("Failed to initialize updater: %v", err))
 		return
 	}
 
@@ -53,7 +55,7 @@ func runUpdate() {
 	ui.ShowInfo("Checking for updates...")
 	release, hasUpdate, err := updater.CheckForUpdate()
 	if err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to check for updates: %v", err))
+		act.fail(fmt.Sprintf("Failed to check for updates: %v", err))
 		return
 	}
 
@@ -79,14 +81,16 @@ func runUpdate() {
 		return
 	}
 
+	act.entry.Detail = fmt.Sprintf("v%s → %s", Version, release.TagName)
+
 	// Check permissions before asking for confirmation
 	permErr, err := update.CheckUpdatePermissions()
 	if err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to check permissions: %v", err))
+		act.fail(fmt.Sprintf("Failed to check permissions: %v", err))
 		return
 	}
 	if permErr != nil {
-		ui.ShowError(permErr.Instruction)
+		act.fail(permErr.Instruction)
 		return
 	}
 
@@ -110,6 +114,7 @@ func runUpdate() {
 	})
 	fmt.Println() // New line after progress
 
+	act.done(err)
 	if err != nil {
 		ui.ShowError(fmt.Sprintf("Update failed: %v", err))
 		if updater.HasBackup() {
@@ -123,25 +128,28 @@ func runUpdate() {
 }
 
 func runRollback() {
+	act := startActivity(nil, "rollback", "")
+	act.entry.Detail = fmt.Sprintf("from v%s", Version)
+
 	updater, err := update.NewUpdater(Version)
 	if err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to initialize updater: %v", err))
+		act.fail(fmt.Sprintf("Failed to initialize updater: %v", err))
 		return
 	}
 
 	if !updater.HasBackup() {
-		ui.ShowError("No backup available for rollback")
+		act.fail("No backup available for rollback")
 		return
 	}
 
 	// Check permissions before rollback
 	permErr, err := update.CheckUpdatePermissions()
 	if err != nil {
-		ui.ShowError(fmt.Sprintf("Failed to check permissions: %v", err))
+		act.fail(fmt.Sprintf("Failed to check permissions: %v", err))
 		return
 	}
 	if permErr != nil {
-		ui.ShowError(permErr.Instruction)
+		act.fail(permErr.Instruction)
 		return
 	}
 
@@ -158,7 +166,9 @@ func runRollback() {
 	}
 
 	ui.ShowInfo("Rolling back to previous version...")
-	if err := updater.Rollback(); err != nil {
+	err = updater.Rollback()
+	act.done(err)
+	if err != nil {
 		ui.ShowError(fmt.Sprintf("Rollback failed: %v", err))
 		return
 	}
@@ -11,14 +11,18 @@ import (
 	"github.com/dwirx/ghex/internal/ssh"
 )
 
+// AppVersion is the ghex version recorded in activity log entries
+var AppVersion string
+
 // Manager handles account operations
 type Manager struct {
-	cfg *config.AppConfig
+	cfg      *config.AppConfig
+	activity *config.ActivityStore
 }
 
 // NewManager creates a new account manager
 func NewManager(cfg *config.AppConfig) *Manager {
-	return &Manager{cfg: cfg}
+	return &Manager{cfg: cfg, activity: config.OpenActivityStore(cfg)}
 }
 
 // Add adds a new account to the configuration
@@ -79,8 +83,13 @@ const (
 	MethodToken SwitchMethod = "token"
 )
 
-// Switch switches the current repository to use a specific account
-func (m *Manager) Switch(accountName string, method SwitchMethod, repoPath string) error {
+// Switch switches the current repository to use a specific account.
+// The attempt is logged whether it succeeds or not.
+func (m *Manager) Switch(accountName string, method SwitchMethod, repoPath string) (err error) {
+	entry := config.ActivityLogEntry{Action: "switch", AccountName: accountName, Method: string(method)}
+	start := time.Now()
+	defer func() { m.RecordActivity(entry, start, err) }()
+
 	account := m.Find(accountName)
 	if account == nil {
 		return fmt.Errorf("account '%s' not found", accountName)
@@ -110,6 +119,8 @@ func (m *Manager) Switch(accountName string, method SwitchMethod, repoPath strin
 		platformType = account.Platform.Type
 		domain = account.Platform.Domain
 	}
+	entry.RepoPath = repoFullPath
+	entry.Platform = platformType
 
 	switch method {
 	case MethodSSH:
@@ -123,14 +134,35 @@ func (m *Manager) Switch(accountName string, method SwitchMethod, repoPath strin
 			return fmt.Errorf("failed to set SSH key permissions: %w", err)
 		}
 
-		// Configure SSH host
-		sshHost := git.GetPlatformSSHHost(platformType, domain)
-		if err := ssh.EnsureConfigBlock(sshHost, keyPath, sshHost); err != nil {
+		hostOpts := ssh.HostOptions{
+			User: git.GetPlatformSSHUser(platformType),
+			Port: git.GetPlatformSSHPort(platformType),
+		}
+		if account.SSH.User != "" {
+			hostOpts.User = account.SSH.User
+		} else if platformType == PlatformCodeCommit {
+			return fmt.Errorf("account '%s' needs its SSH key ID as SSH user for AWS CodeCommit", accountName)
+		}
+
+		// Configure SSH host (taken from the URL, CodeCommit hosts depend on the repo region)
+		newURL := git.CarrySSHPort(git.BuildRemoteURL(platformType, domain, repoFullPath, true), remoteURL)
+		sshHost := git.ExtractHost(newURL)
+		if err := ssh.EnsureConfigBlockWithOptions(sshHost, keyPath, sshHost, hostOpts); err != nil {
 			return fmt.Errorf("failed to configure SSH: %w", err)
 		}
 
+		// Optionally load the key into ssh-agent
+		if account.SSH.AddToAgent && !ssh.IsKeyInAgent(keyPath) {
+			lifetime, err := ParseAgentLifetime(account.SSH.AgentLifetime)
+			if err != nil {
+				return err
+			}
+			if err := ssh.AddToAgent(keyPath, lifetime); err != nil {
+				return err
+			}
+		}
+
 		// Set remote URL to SSH format
-		newURL := git.BuildRemoteURL(platformType, domain, repoFullPath, true)
 		if err := git.SetRemoteURL(newURL, "origin", repoPath); err != nil {
 			return fmt.Errorf("failed to set remote URL: %w", err)
 		}
@@ -145,14 +177,14 @@ func (m *Manager) Switch(accountName string, method SwitchMethod, repoPath strin
 			return fmt.Errorf("failed to set up credential store: %w", err)
 		}
 
-		// Write credentials
-		host := git.GetPlatformSSHHost(platformType, domain)
+		// Write credentials for the host the HTTPS remote points at
+		newURL := git.BuildRemoteURL(platformType, domain, repoFullPath, false)
+		host := git.ExtractHost(newURL)
 		if err := git.WriteCredentials(account.Token.Username, account.Token.Token, host); err != nil {
 			return fmt.Errorf("failed to write credentials: %w", err)
 		}
 
 		// Set remote URL to HTTPS format
-		newURL := git.BuildRemoteURL(platformType, domain, repoFullPath, false)
 		if err := git.SetRemoteURL(newURL, "origin", repoPath); err != nil {
 			return fmt.Errorf("failed to set remote URL: %w", err)
 		}
@@ -166,47 +198,47 @@ func (m *Manager) Switch(accountName string, method SwitchMethod, repoPath strin
 		return fmt.Errorf("failed to set git identity: %w", err)
 	}
 
-	// Log activity
-	m.LogActivity(config.ActivityLogEntry{
-		Timestamp:   time.Now().UTC().Format(time.RFC3339),
-		Action:      "switch",
-		AccountName: accountName,
-		RepoPath:    repoFullPath,
-		Method:      string(method),
-		Platform:    platformType,
-		Success:     true,
-	})
-
 	return nil
 }
 
-// LogActivity adds an activity log entry
-func (m *Manager) LogActivity(entry config.ActivityLogEntry) {
-	if entry.Timestamp == "" {
-		entry.Timestamp = time.Now().UTC().Format(time.RFC3339)
+// ParseAgentLifetime parses an ssh-agent lifetime such as "30m" or "8h"
+// An empty value means the key stays loaded until the agent exits
+func ParseAgentLifetime(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	lifetime, err := time.ParseDuration(value)
+	if err != nil || lifetime < time.Second {
+		return 0, fmt.Errorf("invalid agent lifetime '%s' (use e.g. 30m, 8h)", value)
 	}
-	m.cfg.ActivityLog = append(m.cfg.ActivityLog, entry)
+	return lifetime, nil
 }
 
-// GetRecentActivity returns the most recent activity entries
-func (m *Manager) GetRecentActivity(limit int) []config.ActivityLogEntry {
-	if limit <= 0 || limit > len(m.cfg.ActivityLog) {
-		limit = len(m.cfg.ActivityLog)
+// LogActivity appends an entry to the activity log. Logging is best effort
+// and never fails the operation being logged.
+func (m *Manager) LogActivity(entry config.ActivityLogEntry) {
+	if entry.Timestamp == "" {
+		entry.Timestamp = time.Now().UTC().Format(time.RFC3339)
 	}
-
-	// Return most recent entries (from the end)
-	start := len(m.cfg.ActivityLog) - limit
-	if start < 0 {
-		start = 0
+	if entry.Version == "" {
+		entry.Version = AppVersion
 	}
+	_ = m.activity.Append(entry)
+}
 
-	result := make([]config.ActivityLogEntry, limit)
-	copy(result, m.cfg.ActivityLog[start:])
-
-	// Reverse to get most recent first
-	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
-		result[i], result[j] = result[j], result[i]
+// RecordActivity logs an operation that began at start; a nil err means success
+func (m *Manager) RecordActivity(entry config.ActivityLogEntry, start time.Time, err error) {
+	entry.Timestamp = start.UTC().Format(time.RFC3339)
+	entry.DurationMs = time.Since(start).Milliseconds()
+	entry.Success = err == nil
+	if err != nil {
+		entry.Error = err.Error()
 	}
+	m.LogActivity(entry)
+}
 
-	return result
+// GetRecentActivity returns up to limit activity entries (0 for all), most recent first
+func (m *Manager) GetRecentActivity(limit int) []config.ActivityLogEntry {
+	entries, _ := m.activity.Recent(limit)
+	return entries
 }
@@ -1,7 +1,10 @@
 package account
 
 import (
+	"fmt"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/dwirx/ghex/internal/config"
 )
@@ -198,8 +201,15 @@ func TestListAccounts(t *testing.T) {
 	}
 }
 
+// setupConfigHome points the config directory at a temporary directory
+func setupConfigHome(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("APPDATA", os.Getenv("XDG_CONFIG_HOME"))
+}
+
 // TestLogActivity tests activity logging
 func TestLogActivity(t *testing.T) {
+	setupConfigHome(t)
 	cfg := config.NewAppConfig()
 	manager := NewManager(cfg)
 
@@ -211,17 +221,44 @@ func TestLogActivity(t *testing.T) {
 
 	manager.LogActivity(entry)
 
-	if len(cfg.ActivityLog) != 1 {
-		t.Fatalf("Expected 1 activity log entry, got %d", len(cfg.ActivityLog))
+	if len(cfg.ActivityLog) != 0 {
+		t.Errorf("Expected the entry to stay out of config.json, got %d", len(cfg.ActivityLog))
+	}
+
+	recent := manager.GetRecentActivity(0)
+	if len(recent) != 1 {
+		t.Fatalf("Expected 1 activity log entry, got %d", len(recent))
 	}
 
-	if cfg.ActivityLog[0].Timestamp == "" {
+	if recent[0].Timestamp == "" {
 		t.Error("Expected timestamp to be set automatically")
 	}
 }
 
+// TestSwitchLogsFailures tests that failed switches are logged
+func TestSwitchLogsFailures(t *testing.T) {
+	setupConfigHome(t)
+	AppVersion = "1.2.3"
+	defer func() { AppVersion = "" }()
+
+	manager := NewManager(config.NewAppConfig())
+	if err := manager.Switch("missing", MethodSSH, t.TempDir()); err == nil {
+		t.Fatal("Expected an error for a missing account")
+	}
+
+	recent := manager.GetRecentActivity(1)
+	if len(recent) != 1 {
+		t.Fatalf("Expected the failed switch to be logged, got %d entries", len(recent))
+	}
+	e := recent[0]
+	if e.Action != "switch" || e.Success || e.Error != "account 'missing' not found" || e.Method != "ssh" || e.Version != "1.2.3" {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+}
+
 // TestGetRecentActivity tests getting recent activity
 func TestGetRecentActivity(t *testing.T) {
+	setupConfigHome(t)
 	cfg := config.NewAppConfig()
 	manager := NewManager(cfg)
 
@@ -229,7 +266,7 @@ func TestGetRecentActivity(t *testing.T) {
 	for i := 1; i <= 5; i++ {
 		manager.LogActivity(config.ActivityLogEntry{
 			Action:      "test",
-			AccountName: "account",
+			AccountName: fmt.Sprintf("account%d", i),
 		})
 	}
 
@@ -238,6 +275,9 @@ func TestGetRecentActivity(t *testing.T) {
 	if len(recent) != 3 {
 		t.Errorf("Expected 3 recent activities, got %d", len(recent))
 	}
+	if recent[0].AccountName != "account5" {
+		t.Errorf("Expected the most recent activity first, got %s", recent[0].AccountName)
+	}
 
 	// Get more than available
 	recent = manager.GetRecentActivity(10)
@@ -252,6 +292,34 @@ func TestGetRecentActivity(t *testing.T) {
 	}
 }
 
+// TestParseAgentLifetime tests ssh-agent lifetime parsing
+func TestParseAgentLifetime(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"30m", 30 * time.Minute, false},
+		{"8h", 8 * time.Hour, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"forever", 0, true},
+		{"500ms", 0, true},
+		{"-1h", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAgentLifetime(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAgentLifetime(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseAgentLifetime(%q) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
 // TestSwitchMethodConstants tests switch method constants
 func TestSwitchMethodConstants(t *testing.T) {
 	if MethodSSH != "ssh" {
@@ -261,3 +329,39 @@ func TestSwitchMethodConstants(t *testing.T) {
 		t.Errorf("Expected MethodToken to be 'token', got '%s'", MethodToken)
 	}
 }
+
+// TestFindForURL tests picking a token account for a repository URL
+func TestFindForURL(t *testing.T) {
+	token := func(user string) *config.TokenConfig { return &config.TokenConfig{Username: user, Token: "t"} }
+	cfg := config.NewAppConfig()
+	cfg.Accounts = []config.Account{
+		{Name: "personal", Token: token("alice")},
+		{Name: "work", Token: token("bob")},
+		{Name: "ssh-only", GitUserName: "carol"},
+		{Name: "company", Token: token("dev"), Platform: &config.PlatformConfig{Type: "gitlab", Domain: "gitlab.company.com"}},
+	}
+	manager := NewManager(cfg)
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://github.com/alice/repo/blob/main/README.md", "personal"},
+		{"https://github.com/Bob/private", "work"},
+		{"https://raw.githubusercontent.com/bob/repo/main/file", "work"},
+		{"https://api.github.com/repos/alice/repo/releases/latest", "personal"},
+		{"https://github.com/carol/repo", ""}, // ambiguous and carol has no token
+		{"https://gitlab.company.com/group/project/-/blob/main/x", "company"},
+		{"https://gitlab.com/alice/repo", ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if acc := manager.FindForURL(tt.url); acc != nil {
+			got = acc.Name
+		}
+		if got != tt.expected {
+			t.Errorf("FindForURL(%q) = %q, want %q", tt.url, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package account
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dwirx/ghex/internal/config"
+)
+
+// ActivityFilter selects activity log entries. Zero values match everything.
+type ActivityFilter struct {
+	Account    string
+	Repo       string // substring of the repository path
+	Action     string
+	Since      time.Time
+	Until      time.Time
+	FailedOnly bool
+	Limit      int // 0 for no limit
+}
+
+// Match reports whether an entry passes the filter
+func (f ActivityFilter) Match(e config.ActivityLogEntry) bool {
+	if f.Account != "" && !strings.EqualFold(e.AccountName, f.Account) {
+		return false
+	}
+	if f.Repo != "" && !strings.Contains(strings.ToLower(e.RepoPath), strings.ToLower(f.Repo)) {
+		return false
+	}
+	if f.Action != "" && !strings.EqualFold(e.Action, f.Action) {
+		return false
+	}
+	if f.FailedOnly && e.Success {
+		return false
+	}
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		t, err := time.Parse(time.RFC3339, e.Timestamp)
+		if err != nil {
+			return false
+		}
+		if !f.Since.IsZero() && t.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && t.After(f.Until) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterActivity returns the matching entries, most recent first
+func FilterActivity(entries []config.ActivityLogEntry, f ActivityFilter) []config.ActivityLogEntry {
+	var result []config.ActivityLogEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !f.Match(entries[i]) {
+			continue
+		}
+		result = append(result, entries[i])
+		if f.Limit > 0 && len(result) == f.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// QueryActivity returns the logged entries matching f, most recent first
+func (m *Manager) QueryActivity(f ActivityFilter) ([]config.ActivityLogEntry, error) {
+	var result []config.ActivityLogEntry
+	err := m.activity.Each(func(e config.ActivityLogEntry) bool {
+		if !f.Since.IsZero() {
+			// Entries are newest first, so nothing older can match
+			if t, err := time.Parse(time.RFC3339, e.Timestamp); err == nil && t.Before(f.Since) {
+				return false
+			}
+		}
+		if f.Match(e) {
+			result = append(result, e)
+		}
+		return f.Limit <= 0 || len(result) < f.Limit
+	})
+	return result, err
+}
+
+// ParseActivityTime parses a --since/--until value: a date (2006-01-02),
+// an RFC3339 time, or an age such as 30m, 12h or 7d
+func ParseActivityTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s' (use e.g. 2024-01-31, 12h, 7d)", value)
+}
+
+// ParseActivityUntil is like ParseActivityTime, but a date means the end of
+// that day so that --since and --until with the same date cover the day
+func ParseActivityUntil(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return ParseActivityTime(value, now)
+}
+
+// ActivityCount is a key with its number of entries
+type ActivityCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// ActivityStats summarises activity log entries
+type ActivityStats struct {
+	Total             int             `json:"total"`
+	Failed            int             `json:"failed"`
+	FailureRate       float64         `json:"failureRate"` // 0..1
+	Actions           []ActivityCount `json:"actions"`
+	SwitchesByAccount []ActivityCount `json:"switchesByAccount"`
+	TopRepos          []ActivityCount `json:"topRepos"` // most-switched repositories
+}
+
+// maxTopRepos is the number of repositories listed in ActivityStats
+const maxTopRepos = 5
+
+// ComputeActivityStats summarises entries
+func ComputeActivityStats(entries []config.ActivityLogEntry) ActivityStats {
+	stats := ActivityStats{Total: len(entries)}
+	actions := make(map[string]int)
+	accounts := make(map[string]int)
+	repos := make(map[string]int)
+
+	for _, e := range entries {
+		if !e.Success {
+			stats.Failed++
+		}
+		actions[e.Action]++
+		if e.Action == "switch" && e.Success {
+			accounts[e.AccountName]++
+			if e.RepoPath != "" {
+				repos[e.RepoPath]++
+			}
+		}
+	}
+	if stats.Total > 0 {
+		stats.FailureRate = float64(stats.Failed) / float64(stats.Total)
+	}
+
+	stats.Actions = sortedCounts(actions, 0)
+	stats.SwitchesByAccount = sortedCounts(accounts, 0)
+	stats.TopRepos = sortedCounts(repos, maxTopRepos)
+	return stats
+}
+
+// sortedCounts orders counts by count, then name, keeping at most limit
+func sortedCounts(counts map[string]int, limit int) []ActivityCount {
+	result := make([]ActivityCount, 0, len(counts))
+	for name, n := range counts {
+		result = append(result, ActivityCount{Name: name, Count: n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// WriteActivityJSONL writes one JSON object per entry
+func WriteActivityJSONL(w io.Writer, entries []config.ActivityLogEntry) error {
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to write activity: %w", err)
+		}
+	}
+	return nil
+}
+
+// activityCSVHeader is the header row of CSV exports
+var activityCSVHeader = []string{"timestamp", "action", "account", "repo", "method", "platform", "success", "error", "detail", "durationMs", "version"}
+
+// WriteActivityCSV writes entries as CSV with a header row
+func WriteActivityCSV(w io.Writer, entries []config.ActivityLogEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(activityCSVHeader); err != nil {
+		return fmt.Errorf("failed to write activity: %w", err)
+	}
+	for _, e := range entries {
+		record := []string{
+			e.Timestamp, e.Action, e.AccountName, e.RepoPath, e.Method, e.Platform,
+			strconv.FormatBool(e.Success), e.Error, e.Detail, strconv.FormatInt(e.DurationMs, 10), e.Version,
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write activity: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
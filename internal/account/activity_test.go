@@ -0,0 +1,133 @@
+package account
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dwirx/ghex/internal/config"
+)
+
+// testActivity returns entries in logging order (oldest first)
+func testActivity() []config.ActivityLogEntry {
+	return []config.ActivityLogEntry{
+		{Timestamp: "2024-01-01T10:00:00Z", Action: "switch", AccountName: "work", RepoPath: "acme/api", Success: true},
+		{Timestamp: "2024-01-02T10:00:00Z", Action: "switch", AccountName: "personal", RepoPath: "me/dotfiles", Success: true},
+		{Timestamp: "2024-01-03T10:00:00Z", Action: "switch", AccountName: "work", RepoPath: "acme/api", Success: false, Error: "no key"},
+		{Timestamp: "2024-01-04T10:00:00Z", Action: "add", AccountName: "client", Success: true},
+		{Timestamp: "2024-01-05T10:00:00Z", Action: "switch", AccountName: "work", RepoPath: "acme/web", Success: true},
+	}
+}
+
+// TestFilterActivity tests filters, ordering and limits
+func TestFilterActivity(t *testing.T) {
+	entries := testActivity()
+
+	tests := []struct {
+		name     string
+		filter   ActivityFilter
+		expected []string // timestamps' days
+	}{
+		{"all", ActivityFilter{}, []string{"05", "04", "03", "02", "01"}},
+		{"account", ActivityFilter{Account: "WORK"}, []string{"05", "03", "01"}},
+		{"repo", ActivityFilter{Repo: "acme"}, []string{"05", "03", "01"}},
+		{"action", ActivityFilter{Action: "add"}, []string{"04"}},
+		{"failed", ActivityFilter{FailedOnly: true}, []string{"03"}},
+		{"limit", ActivityFilter{Account: "work", Limit: 2}, []string{"05", "03"}},
+		{"range", ActivityFilter{
+			Since: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			Until: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+		}, []string{"03", "02"}},
+	}
+
+	for _, tt := range tests {
+		got := FilterActivity(entries, tt.filter)
+		var days []string
+		for _, e := range got {
+			days = append(days, e.Timestamp[8:10])
+		}
+		if strings.Join(days, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: got %v, expected %v", tt.name, days, tt.expected)
+		}
+	}
+}
+
+// TestParseActivityTime tests dates, timestamps and relative ages
+func TestParseActivityTime(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"2024-03-01":           time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2024-03-01T08:00:00Z": time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC),
+		"7d":                   time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC),
+		"90m":                  time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC),
+		"":                     {},
+	}
+	for in, expected := range tests {
+		got, err := ParseActivityTime(in, now)
+		if err != nil || !got.Equal(expected) {
+			t.Errorf("ParseActivityTime(%q) = %v, %v; expected %v", in, got, err, expected)
+		}
+	}
+	if _, err := ParseActivityTime("last week", now); err == nil {
+		t.Error("Expected an error for an invalid time")
+	}
+
+	// A date as --until includes the whole day
+	until, err := ParseActivityUntil("2024-03-01", now)
+	if err != nil || !until.Equal(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)) {
+		t.Errorf("ParseActivityUntil(2024-03-01) = %v, %v; expected the end of the day", until, err)
+	}
+	if until, err := ParseActivityUntil("90m", now); err != nil || !until.Equal(time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("ParseActivityUntil(90m) = %v, %v", until, err)
+	}
+	filter := ActivityFilter{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Until: until}
+	if !filter.Match(config.ActivityLogEntry{Timestamp: "2024-03-01T18:00:00Z"}) {
+		t.Error("Expected an entry from the day to match --since and --until with the same date")
+	}
+}
+
+// TestComputeActivityStats tests per-account switches, top repos and failure rate
+func TestComputeActivityStats(t *testing.T) {
+	stats := ComputeActivityStats(testActivity())
+
+	if stats.Total != 5 || stats.Failed != 1 || stats.FailureRate != 0.2 {
+		t.Errorf("Unexpected totals: %+v", stats)
+	}
+	if len(stats.SwitchesByAccount) != 2 || stats.SwitchesByAccount[0] != (ActivityCount{"work", 2}) {
+		t.Errorf("Unexpected switches by account: %+v", stats.SwitchesByAccount)
+	}
+	if len(stats.TopRepos) != 3 || stats.TopRepos[0] != (ActivityCount{"acme/api", 1}) {
+		t.Errorf("Unexpected top repos: %+v", stats.TopRepos)
+	}
+	if stats.Actions[0] != (ActivityCount{"switch", 4}) {
+		t.Errorf("Unexpected actions: %+v", stats.Actions)
+	}
+}
+
+// TestWriteActivity tests JSONL and CSV export
+func TestWriteActivity(t *testing.T) {
+	entries := testActivity()[2:4]
+
+	var jsonl bytes.Buffer
+	if err := WriteActivityJSONL(&jsonl, entries); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"error":"no key"`) {
+		t.Errorf("Unexpected JSONL:\n%s", jsonl.String())
+	}
+
+	var out bytes.Buffer
+	if err := WriteActivityCSV(&out, entries); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0][0] != "timestamp" || records[1][6] != "false" || records[2][1] != "add" {
+		t.Errorf("Unexpected CSV: %v", records)
+	}
+}
@@ -1,6 +1,7 @@
 package account
 
 import (
+	"net/url"
 	"strings"
 
 	"github.com/dwirx/ghex/internal/config"
@@ -44,7 +45,7 @@ func (m *Manager) DetectActiveWithScore(repoPath string) (*MatchScore, error) {
 	}
 
 	// Determine auth type and platform from remote URL
-	isSSH := strings.HasPrefix(remoteURL, "git@") || strings.HasPrefix(remoteURL, "ssh://")
+	isSSH := git.IsSSHURL(remoteURL)
 	detectedPlatform := DetectPlatformFromURL(remoteURL)
 
 	var bestMatch *MatchScore
@@ -122,7 +123,7 @@ func (m *Manager) DetectActive(repoPath string) (string, error) {
 	}
 
 	// Determine auth type from remote URL
-	isSSH := strings.HasPrefix(remoteURL, "git@") || strings.HasPrefix(remoteURL, "ssh://")
+	isSSH := git.IsSSHURL(remoteURL)
 
 	// Try to match account based on git identity and remote URL
 	for _, account := range m.cfg.Accounts {
@@ -196,7 +197,7 @@ func GetRemoteInfo(repoPath string) (*RemoteInfo, error) {
 		return nil, err
 	}
 
-	isSSH := strings.HasPrefix(remoteURL, "git@") || strings.HasPrefix(remoteURL, "ssh://")
+	isSSH := git.IsSSHURL(remoteURL)
 	authType := "https"
 	if isSSH {
 		authType = "ssh"
@@ -218,3 +219,61 @@ func GetRemoteInfo(repoPath string) (*RemoteInfo, error) {
 		Repo:      repo,
 	}, nil
 }
+
+// hostAliases maps hosts serving a platform's files and API to its web host
+var hostAliases = map[string]string{
+	"raw.githubusercontent.com": "github.com",
+	"api.github.com":            "github.com",
+	"www.github.com":            "github.com",
+	"api.bitbucket.org":         "bitbucket.org",
+}
+
+// FindForURL returns the token account to use for a repository URL: the
+// account on the URL's host whose name or username matches the owner, else
+// the only token account on that host. It returns nil when none fits.
+func (m *Manager) FindForURL(rawURL string) *config.Account {
+	host := strings.ToLower(git.ExtractHost(rawURL))
+	if alias, ok := hostAliases[host]; ok {
+		host = alias
+	}
+	if host == "" {
+		return nil
+	}
+
+	owner := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(segments) > 1 && segments[0] == "repos" {
+			segments = segments[1:] // API URLs
+		}
+		owner = segments[0]
+	}
+
+	var candidates []*config.Account
+	for i := range m.cfg.Accounts {
+		acc := &m.cfg.Accounts[i]
+		if acc.Token == nil || acc.Token.Token == "" {
+			continue
+		}
+		platformType, domain := PlatformGitHub, ""
+		if acc.Platform != nil && acc.Platform.Type != "" {
+			platformType, domain = acc.Platform.Type, acc.Platform.Domain
+		}
+		custom, _ := git.MatchCustomPlatform(host)
+		if !strings.EqualFold(git.GetPlatformHTTPSHost(platformType, domain), host) &&
+			!(domain == "" && custom != "" && strings.EqualFold(custom, platformType)) {
+			continue
+		}
+
+		if owner != "" && (strings.EqualFold(acc.Token.Username, owner) || strings.EqualFold(acc.Name, owner) ||
+			strings.EqualFold(acc.GitUserName, owner)) {
+			return acc
+		}
+		candidates = append(candidates, acc)
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	return nil
+}
@@ -18,6 +18,9 @@ const (
 // StaleThreshold is the duration after which health data is considered stale
 const StaleThreshold = 24 * time.Hour
 
+// KeyRotationAge is the SSH key age after which rotation is suggested
+const KeyRotationAge = 365 * 24 * time.Hour
+
 // HealthIndicators contains health check indicators for an account
 type HealthIndicators struct {
 	SSHKeyExists bool
@@ -101,6 +104,22 @@ func CheckSSHKeyHealth(keyPath string) HealthIndicators {
 	return indicators
 }
 
+// KeyAge returns the age of an account's SSH key, from KeyCreatedAt or else
+// the key file's modification time
+func KeyAge(sshCfg *config.SshConfig, now time.Time) (time.Duration, bool) {
+	if sshCfg == nil {
+		return 0, false
+	}
+	if t, err := time.Parse(time.RFC3339, sshCfg.KeyCreatedAt); err == nil {
+		return now.Sub(t), true
+	}
+	info, err := os.Stat(platform.ExpandPath(sshCfg.KeyPath))
+	if err != nil {
+		return 0, false
+	}
+	return now.Sub(info.ModTime()), true
+}
+
 // CheckTokenHealth placeholder for token validation
 // Note: Actual token validation requires API call to the platform
 func CheckTokenHealth(token *config.TokenConfig, platformType string) HealthIndicators {
@@ -1,6 +1,8 @@
 package account
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -106,6 +108,30 @@ func TestCheckSSHKeyHealth(t *testing.T) {
 	}
 }
 
+// TestKeyAge tests key age from KeyCreatedAt with a file time fallback
+func TestKeyAge(t *testing.T) {
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+	age, ok := KeyAge(&config.SshConfig{KeyPath: "/nonexistent", KeyCreatedAt: "2025-10-18T00:00:00Z"}, now)
+	if !ok || age != 365*24*time.Hour {
+		t.Errorf("Expected one year from KeyCreatedAt, got %v (%v)", age, ok)
+	}
+
+	key := filepath.Join(t.TempDir(), "id_test")
+	os.WriteFile(key, []byte("key"), 0600)
+	os.Chtimes(key, now.Add(-48*time.Hour), now.Add(-48*time.Hour))
+	if age, ok := KeyAge(&config.SshConfig{KeyPath: key}, now); !ok || age != 48*time.Hour {
+		t.Errorf("Expected file time fallback, got %v (%v)", age, ok)
+	}
+
+	if _, ok := KeyAge(&config.SshConfig{KeyPath: "/nonexistent"}, now); ok {
+		t.Error("Expected unknown age for a missing key")
+	}
+	if _, ok := KeyAge(nil, now); ok {
+		t.Error("Expected unknown age without SSH config")
+	}
+}
+
 // TestCheckTokenHealth tests token health checking
 func TestCheckTokenHealth(t *testing.T) {
 	// Test with nil token
@@ -1,27 +1,38 @@
 package account
 
 import (
+	"fmt"
 	"strings"
+
+	"github.com/dwirx/ghex/internal/config"
+	"github.com/dwirx/ghex/internal/git"
+	"github.com/dwirx/ghex/internal/ssh"
 )
 
 // Platform type constants
 const (
-	PlatformGitHub    = "github"
-	PlatformGitLab    = "gitlab"
-	PlatformBitbucket = "bitbucket"
-	PlatformGitea     = "gitea"
-	PlatformCodeberg  = "codeberg"
-	PlatformOther     = "other"
+	PlatformGitHub     = "github"
+	PlatformGitLab     = "gitlab"
+	PlatformBitbucket  = "bitbucket"
+	PlatformGitea      = "gitea"
+	PlatformCodeberg   = "codeberg"
+	PlatformAzure      = "azure"
+	PlatformCodeCommit = "codecommit"
+	PlatformSourceHut  = "sourcehut"
+	PlatformOther      = "other"
 )
 
 // Platform icons
 const (
-	IconGitHub    = "🐙"
-	IconGitLab    = "🦊"
-	IconBitbucket = "🪣"
-	IconGitea     = "🍵"
-	IconCodeberg  = "🏔️"
-	IconOther     = "🔗"
+	IconGitHub     = "🐙"
+	IconGitLab     = "🦊"
+	IconBitbucket  = "🪣"
+	IconGitea      = "🍵"
+	IconCodeberg   = "🏔️"
+	IconAzure      = "🔷"
+	IconCodeCommit = "☁️"
+	IconSourceHut  = "🛖"
+	IconOther      = "🔗"
 )
 
 // PlatformInfo contains display information for a platform
@@ -64,6 +75,24 @@ var platformRegistry = map[string]PlatformInfo{
 		Name:   "Codeberg",
 		Domain: "codeberg.org",
 	},
+	PlatformAzure: {
+		Type:   PlatformAzure,
+		Icon:   IconAzure,
+		Name:   "Azure DevOps",
+		Domain: "dev.azure.com",
+	},
+	PlatformCodeCommit: {
+		Type:   PlatformCodeCommit,
+		Icon:   IconCodeCommit,
+		Name:   "AWS CodeCommit",
+		Domain: "git-codecommit.us-east-1.amazonaws.com",
+	},
+	PlatformSourceHut: {
+		Type:   PlatformSourceHut,
+		Icon:   IconSourceHut,
+		Name:   "SourceHut",
+		Domain: "git.sr.ht",
+	},
 	PlatformOther: {
 		Type:   PlatformOther,
 		Icon:   IconOther,
@@ -105,6 +134,11 @@ func GetPlatformDisplay(platformType string, customDomain string) string {
 
 // DetectPlatformFromURL identifies platform type from remote URL
 func DetectPlatformFromURL(url string) string {
+	// User-defined platforms take precedence over substring matching
+	if name, ok := git.MatchCustomPlatform(git.ExtractHost(url)); ok {
+		return name
+	}
+
 	url = strings.ToLower(url)
 
 	// Check for known domains
@@ -120,6 +154,15 @@ func DetectPlatformFromURL(url string) string {
 	if strings.Contains(url, "codeberg.org") {
 		return PlatformCodeberg
 	}
+	if strings.Contains(url, "dev.azure.com") || strings.Contains(url, "visualstudio.com") {
+		return PlatformAzure
+	}
+	if strings.Contains(url, "git-codecommit.") && strings.Contains(url, "amazonaws.com") {
+		return PlatformCodeCommit
+	}
+	if strings.Contains(url, "sr.ht") {
+		return PlatformSourceHut
+	}
 	if strings.Contains(url, "gitea") {
 		return PlatformGitea
 	}
@@ -135,10 +178,118 @@ func GetSupportedPlatforms() []string {
 		PlatformBitbucket,
 		PlatformGitea,
 		PlatformCodeberg,
+		PlatformAzure,
+		PlatformCodeCommit,
+		PlatformSourceHut,
 		PlatformOther,
 	}
 }
 
+// customPlatformNames tracks registry entries added from the configuration
+var customPlatformNames []string
+
+// RegisterCustomPlatforms registers user-defined platforms from the configuration
+// so they are used for detection, switching, cloning and downloads.
+// Invalid entries are skipped and reported in the returned error.
+func RegisterCustomPlatforms(platforms []config.CustomPlatform) error {
+	for _, name := range customPlatformNames {
+		delete(platformRegistry, name)
+	}
+	customPlatformNames = nil
+	git.ResetCustomPlatforms()
+	ssh.ResetHostKeys()
+
+	var problems []string
+	for _, p := range platforms {
+		flavor := strings.ToLower(p.Flavor)
+		if flavor == "" {
+			flavor = git.FlavorGeneric
+		}
+		if !isValidFlavor(flavor) {
+			problems = append(problems, fmt.Sprintf("platform '%s' has unknown flavor '%s'", p.Name, p.Flavor))
+			continue
+		}
+
+		err := git.RegisterPlatform(p.Name, git.PlatformURLConfig{
+			Flavor:        flavor,
+			HostPatterns:  p.Hosts,
+			SSHHost:       p.SSHHost,
+			SSHUser:       p.SSHUser,
+			SSHPort:       p.SSHPort,
+			SSHTemplate:   p.SSHTemplate,
+			HTTPSTemplate: p.HTTPSTemplate,
+			APIURL:        p.ApiUrl,
+		})
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		if len(p.HostKeys) > 0 {
+			hosts := p.Hosts
+			if p.SSHHost != "" {
+				hosts = append([]string{p.SSHHost}, hosts...)
+			}
+			for _, host := range hosts {
+				if err := ssh.RegisterHostKeys(host, p.HostKeys); err != nil {
+					problems = append(problems, err.Error())
+					break
+				}
+			}
+		}
+
+		name := strings.ToLower(p.Name)
+		displayName := p.DisplayName
+		if displayName == "" {
+			displayName = p.Name
+		}
+		platformRegistry[name] = PlatformInfo{
+			Type:   name,
+			Icon:   flavorIcon(flavor),
+			Name:   displayName,
+			Domain: git.GetDefaultDomain(name),
+		}
+		customPlatformNames = append(customPlatformNames, name)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid custom platforms: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// GetCustomPlatforms returns the names of registered user-defined platforms
+func GetCustomPlatforms() []string {
+	names := make([]string, len(customPlatformNames))
+	copy(names, customPlatformNames)
+	return names
+}
+
+// isValidFlavor checks if a custom platform flavor is supported
+func isValidFlavor(flavor string) bool {
+	switch flavor {
+	case git.FlavorGitHub, git.FlavorGitLab, git.FlavorGitea, git.FlavorBitbucket, git.FlavorGeneric:
+		return true
+	}
+	return false
+}
+
+// flavorIcon returns the icon used for custom platforms of a flavor
+func flavorIcon(flavor string) string {
+	switch flavor {
+	case git.FlavorGitHub:
+		return IconGitHub
+	case git.FlavorGitLab:
+		return IconGitLab
+	case git.FlavorGitea:
+		return IconGitea
+	case git.FlavorBitbucket:
+		return IconBitbucket
+	default:
+		return IconOther
+	}
+}
+
 // IsValidPlatform checks if a platform type is valid
 func IsValidPlatform(platformType string) bool {
 	_, ok := platformRegistry[strings.ToLower(platformType)]
@@ -2,6 +2,9 @@ package account
 
 import (
 	"testing"
+
+	"github.com/dwirx/ghex/internal/config"
+	"github.com/dwirx/ghex/internal/git"
 )
 
 // TestGetPlatformInfo tests platform info retrieval
@@ -16,6 +19,9 @@ func TestGetPlatformInfo(t *testing.T) {
 		{PlatformBitbucket, IconBitbucket, "Bitbucket"},
 		{PlatformGitea, IconGitea, "Gitea"},
 		{PlatformCodeberg, IconCodeberg, "Codeberg"},
+		{PlatformAzure, IconAzure, "Azure DevOps"},
+		{PlatformCodeCommit, IconCodeCommit, "AWS CodeCommit"},
+		{PlatformSourceHut, IconSourceHut, "SourceHut"},
 		{PlatformOther, IconOther, "Other"},
 		{"unknown", IconOther, "Other"}, // Unknown defaults to Other
 		{"GITHUB", IconGitHub, "GitHub"}, // Case insensitive
@@ -98,6 +104,19 @@ func TestDetectPlatformFromURL(t *testing.T) {
 		// Gitea
 		{"https://gitea.example.com/user/repo.git", PlatformGitea},
 
+		// Azure DevOps
+		{"git@ssh.dev.azure.com:v3/org/project/repo", PlatformAzure},
+		{"https://org@dev.azure.com/org/project/_git/repo", PlatformAzure},
+		{"https://org.visualstudio.com/project/_git/repo", PlatformAzure},
+
+		// AWS CodeCommit
+		{"ssh://git-codecommit.us-east-1.amazonaws.com/v1/repos/repo", PlatformCodeCommit},
+		{"https://git-codecommit.eu-west-1.amazonaws.com/v1/repos/repo", PlatformCodeCommit},
+
+		// SourceHut
+		{"git@git.sr.ht:~user/repo", PlatformSourceHut},
+		{"https://git.sr.ht/~user/repo", PlatformSourceHut},
+
 		// Other
 		{"https://custom.git.server/user/repo.git", PlatformOther},
 		{"git@custom.server:user/repo.git", PlatformOther},
@@ -117,8 +136,8 @@ func TestDetectPlatformFromURL(t *testing.T) {
 func TestGetSupportedPlatforms(t *testing.T) {
 	platforms := GetSupportedPlatforms()
 
-	if len(platforms) != 6 {
-		t.Errorf("Expected 6 supported platforms, got %d", len(platforms))
+	if len(platforms) != 9 {
+		t.Errorf("Expected 9 supported platforms, got %d", len(platforms))
 	}
 
 	// Check all expected platforms are present
@@ -127,8 +146,11 @@ func TestGetSupportedPlatforms(t *testing.T) {
 		PlatformGitLab:    false,
 		PlatformBitbucket: false,
 		PlatformGitea:     false,
-		PlatformCodeberg:  false,
-		PlatformOther:     false,
+		PlatformCodeberg:   false,
+		PlatformAzure:      false,
+		PlatformCodeCommit: false,
+		PlatformSourceHut:  false,
+		PlatformOther:      false,
 	}
 
 	for _, p := range platforms {
@@ -155,6 +177,9 @@ func TestIsValidPlatform(t *testing.T) {
 		{"bitbucket", true},
 		{"gitea", true},
 		{"codeberg", true},
+		{"azure", true},
+		{"codecommit", true},
+		{"sourcehut", true},
 		{"other", true},
 		{"GITHUB", true}, // Case insensitive
 		{"invalid", false},
@@ -193,6 +218,165 @@ func TestPlatformConstants(t *testing.T) {
 	}
 }
 
+// TestMultiSegmentPlatformURLs tests parsing and building Azure DevOps, CodeCommit and SourceHut URLs
+func TestMultiSegmentPlatformURLs(t *testing.T) {
+	tests := []struct {
+		url      string
+		platform string
+		domain   string
+		owner    string
+		repo     string
+		sshURL   string
+		httpsURL string
+	}{
+		{
+			url:      "git@ssh.dev.azure.com:v3/org/project/repo",
+			platform: PlatformAzure,
+			owner:    "org/project",
+			repo:     "repo",
+			sshURL:   "git@ssh.dev.azure.com:v3/org/project/repo",
+			httpsURL: "https://dev.azure.com/org/project/_git/repo",
+		},
+		{
+			url:      "https://org@dev.azure.com/org/project/_git/repo",
+			platform: PlatformAzure,
+			owner:    "org/project",
+			repo:     "repo",
+			sshURL:   "git@ssh.dev.azure.com:v3/org/project/repo",
+			httpsURL: "https://dev.azure.com/org/project/_git/repo",
+		},
+		{
+			url:      "https://legacy.visualstudio.com/DefaultCollection/project/_git/repo",
+			platform: PlatformAzure,
+			domain:   "legacy.visualstudio.com",
+			owner:    "legacy/project",
+			repo:     "repo",
+			sshURL:   "git@vs-ssh.visualstudio.com:v3/legacy/project/repo",
+			httpsURL: "https://legacy.visualstudio.com/project/_git/repo",
+		},
+		{
+			url:      "ssh://git-codecommit.eu-west-1.amazonaws.com/v1/repos/repo",
+			platform: PlatformCodeCommit,
+			owner:    "eu-west-1",
+			repo:     "repo",
+			sshURL:   "ssh://git-codecommit.eu-west-1.amazonaws.com/v1/repos/repo",
+			httpsURL: "https://git-codecommit.eu-west-1.amazonaws.com/v1/repos/repo",
+		},
+		{
+			url:      "git@git.sr.ht:~user/repo",
+			platform: PlatformSourceHut,
+			owner:    "~user",
+			repo:     "repo",
+			sshURL:   "git@git.sr.ht:~user/repo",
+			httpsURL: "https://git.sr.ht/~user/repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			owner, repo, err := git.ParseRepoFromURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseRepoFromURL(%s) failed: %v", tt.url, err)
+			}
+			if owner != tt.owner || repo != tt.repo {
+				t.Errorf("ParseRepoFromURL(%s) = %s, %s, expected %s, %s", tt.url, owner, repo, tt.owner, tt.repo)
+			}
+
+			repoPath := owner + "/" + repo
+			if got := git.BuildRemoteURL(tt.platform, tt.domain, repoPath, true); got != tt.sshURL {
+				t.Errorf("SSH URL = %s, expected %s", got, tt.sshURL)
+			}
+			if got := git.BuildRemoteURL(tt.platform, tt.domain, repoPath, false); got != tt.httpsURL {
+				t.Errorf("HTTPS URL = %s, expected %s", got, tt.httpsURL)
+			}
+		})
+	}
+}
+
+// TestRegisterCustomPlatforms tests detection and URL building for user-defined platforms
+func TestRegisterCustomPlatforms(t *testing.T) {
+	defer RegisterCustomPlatforms(nil)
+
+	err := RegisterCustomPlatforms([]config.CustomPlatform{
+		{
+			Name:        "forgejo-work",
+			DisplayName: "Work Forgejo",
+			Flavor:      "gitea",
+			Hosts:       []string{"code.company.com"},
+		},
+		{
+			Name:    "gitlab-lab",
+			Flavor:  "gitlab",
+			Hosts:   []string{"*.lab.internal"},
+			SSHHost: "ssh.lab.internal",
+			SSHPort: 2222,
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterCustomPlatforms failed: %v", err)
+	}
+
+	detectTests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://code.company.com/team/app.git", "forgejo-work"},
+		{"git@code.company.com:team/app.git", "forgejo-work"},
+		{"https://git.lab.internal/group/sub/app.git", "gitlab-lab"},
+		{"ssh://git@ssh.lab.internal:2222/group/app.git", "gitlab-lab"},
+		{"https://github.com/user/repo.git", PlatformGitHub},
+	}
+	for _, tt := range detectTests {
+		if got := DetectPlatformFromURL(tt.url); got != tt.expected {
+			t.Errorf("DetectPlatformFromURL(%s) = %s, expected %s", tt.url, got, tt.expected)
+		}
+	}
+
+	info := GetPlatformInfo("forgejo-work")
+	if info.Name != "Work Forgejo" || info.Icon != IconGitea {
+		t.Errorf("Unexpected platform info for custom platform: %+v", info)
+	}
+
+	if !IsValidPlatform("gitlab-lab") {
+		t.Error("Expected custom platform to be valid")
+	}
+
+	sshURL := git.BuildRemoteURL("gitlab-lab", "git.lab.internal", "group/app", true)
+	if sshURL != "ssh://git@ssh.lab.internal:2222/group/app.git" {
+		t.Errorf("Unexpected SSH URL for custom platform: %s", sshURL)
+	}
+
+	httpsURL := git.BuildRemoteURL("forgejo-work", "", "team/app", false)
+	if httpsURL != "https://code.company.com/team/app.git" {
+		t.Errorf("Unexpected HTTPS URL for custom platform: %s", httpsURL)
+	}
+}
+
+// TestRegisterCustomPlatformsInvalid tests that invalid platforms are reported and skipped
+func TestRegisterCustomPlatformsInvalid(t *testing.T) {
+	defer RegisterCustomPlatforms(nil)
+
+	err := RegisterCustomPlatforms([]config.CustomPlatform{
+		{Name: "github", Hosts: []string{"example.com"}},        // Built-in name
+		{Name: "nohosts"},                                         // Missing hosts
+		{Name: "weird", Flavor: "svn", Hosts: []string{"x.com"}}, // Unknown flavor
+		{Name: "ok", Hosts: []string{"ok.example.com"}},
+	})
+	if err == nil {
+		t.Error("Expected error for invalid custom platforms")
+	}
+
+	if names := GetCustomPlatforms(); len(names) != 1 || names[0] != "ok" {
+		t.Errorf("Expected only the valid platform to be registered, got %v", names)
+	}
+
+	// Re-registering replaces previous custom platforms
+	_ = RegisterCustomPlatforms(nil)
+	if IsValidPlatform("ok") {
+		t.Error("Expected custom platform to be removed after reset")
+	}
+}
+
 // TestIconConstants tests icon constants
 func TestIconConstants(t *testing.T) {
 	if IconGitHub != "🐙" {
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dwirx/ghex/internal/config"
+	"github.com/dwirx/ghex/internal/git"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Client is an authenticated client for a platform's REST API
+type Client struct {
+	HTTPClient *http.Client
+	BaseURL    string // API base URL, e.g. https://api.github.com
+	Flavor     string // github, gitlab, gitea or bitbucket
+	Username   string
+	Token      string
+}
+
+// NewClient creates a client for a platform flavor and API base URL
+func NewClient(flavor, baseURL, username, token string) *Client {
+	return &Client{
+		HTTPClient: &http.Client{
+			Timeout: defaultTimeout,
+		},
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		Flavor:   flavor,
+		Username: username,
+		Token:    token,
+	}
+}
+
+// NewClientForAccount creates a client from an account's platform and token
+func NewClientForAccount(acc *config.Account) (*Client, error) {
+	if acc.Token == nil || acc.Token.Token == "" {
+		return nil, ErrNoToken
+	}
+
+	platformType := "github"
+	domain := ""
+	apiURL := ""
+	if acc.Platform != nil {
+		platformType = acc.Platform.Type
+		domain = acc.Platform.Domain
+		apiURL = acc.Platform.ApiUrl
+	}
+
+	flavor := git.GetPlatformFlavor(platformType)
+	if apiURL == "" {
+		apiURL = git.GetPlatformAPIURL(platformType)
+	}
+	if apiURL == "" {
+		var err error
+		apiURL, err = defaultAPIURL(flavor, platformType, domain)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewClient(flavor, apiURL, acc.Token.Username, acc.Token.Token), nil
+}
+
+// defaultAPIURL returns the API base URL for a platform flavor and domain
+func defaultAPIURL(flavor, platformType, domain string) (string, error) {
+	host := git.GetPlatformHTTPSHost(platformType, domain)
+
+	switch flavor {
+	case git.FlavorGitHub:
+		if domain == "" || strings.EqualFold(domain, "github.com") {
+			return "https://api.github.com", nil
+		}
+		return fmt.Sprintf("https://%s/api/v3", domain), nil
+	case git.FlavorGitLab:
+		return fmt.Sprintf("https://%s/api/v4", host), nil
+	case git.FlavorGitea:
+		if domain == "" && platformType == "gitea" {
+			return "", fmt.Errorf("%w: gitea requires a custom domain", ErrUnsupportedPlatform)
+		}
+		return fmt.Sprintf("https://%s/api/v1", host), nil
+	case git.FlavorBitbucket:
+		return "https://api.bitbucket.org/2.0", nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedPlatform, platformType)
+}
+
+// AuthHeaders returns the flavor-specific authentication headers
+func (c *Client) AuthHeaders() map[string]string {
+	switch c.Flavor {
+	case git.FlavorGitLab:
+		return map[string]string{"PRIVATE-TOKEN": c.Token}
+	case git.FlavorGitea:
+		return map[string]string{"Authorization": "token " + c.Token}
+	case git.FlavorBitbucket:
+		credentials := base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Token))
+		return map[string]string{"Authorization": "Basic " + credentials}
+	default:
+		return map[string]string{"Authorization": "Bearer " + c.Token}
+	}
+}
+
+// authorize adds the flavor-specific authentication headers
+func (c *Client) authorize(req *http.Request) {
+	for k, v := range c.AuthHeaders() {
+		req.Header.Set(k, v)
+	}
+	switch c.Flavor {
+	case git.FlavorGitLab, git.FlavorGitea, git.FlavorBitbucket:
+	default:
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+}
+
+// do sends a request with an optional JSON body and decodes a JSON response into out
+func (c *Client) do(method, path string, body, out interface{}) error {
+	_, err := c.doWithHeader(method, path, body, out)
+	return err
+}
+
+// doWithHeader is like do but also returns the response headers, which
+// carry the pagination of list endpoints
+func (c *Client) doWithHeader(method, path string, body, out interface{}) (http.Header, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+	req.Header.Set("User-Agent", "ghex")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authorize(req)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w: HTTP %d", ErrUnauthorized, resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%w: HTTP %d %s", ErrRequestFailed, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return resp.Header, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return resp.Header, nil
+}
+
+// maxPages bounds the pages fetched from a list endpoint
+const maxPages = 100
+
+// nextPage returns the path of the page after path from the Link header of
+// GitHub and Gitea or GitLab's X-Next-Page, or "" on the last page
+func (c *Client) nextPage(path string, header http.Header) (string, error) {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if ok && strings.Contains(params, `rel="next"`) {
+			return c.apiPath(strings.Trim(strings.TrimSpace(target), "<>"))
+		}
+	}
+	if page := header.Get("X-Next-Page"); page != "" {
+		u, err := url.Parse(path)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrRequestFailed, err)
+		}
+		query := u.Query()
+		query.Set("page", page)
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+	return "", nil
+}
+
+// apiPath turns an absolute next-page URL into a path below BaseURL,
+// refusing other hosts so the token isn't sent there
+func (c *Client) apiPath(next string) (string, error) {
+	if !strings.HasPrefix(next, c.BaseURL+"/") {
+		return "", fmt.Errorf("%w: next page outside the API: %s", ErrRequestFailed, next)
+	}
+	return strings.TrimPrefix(next, c.BaseURL), nil
+}
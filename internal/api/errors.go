@@ -0,0 +1,13 @@
+// Package api talks to git hosting platform REST APIs on behalf of an account
+package api
+
+import "errors"
+
+// Error types for platform API operations
+var (
+	ErrUnsupportedPlatform = errors.New("platform API is not supported")
+	ErrSigningUnsupported  = errors.New("platform does not support SSH signing keys")
+	ErrNoToken             = errors.New("account has no token configured")
+	ErrUnauthorized        = errors.New("token was rejected or lacks the required scope")
+	ErrRequestFailed       = errors.New("platform API request failed")
+)
@@ -0,0 +1,319 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/dwirx/ghex/internal/git"
+	"github.com/dwirx/ghex/internal/ssh"
+)
+
+// GitLab key usage types
+const (
+	gitlabUsageAuth           = "auth"
+	gitlabUsageSigning        = "signing"
+	gitlabUsageAuthAndSigning = "auth_and_signing"
+)
+
+// SSHKey is a public key registered on a platform
+type SSHKey struct {
+	ID      string
+	Title   string
+	Key     string
+	Signing bool // usable for commit signing
+
+	signingOnly bool // GitLab key that can't be used for authentication
+}
+
+// UploadResult describes what UploadKey changed on the platform
+type UploadResult struct {
+	Fingerprint     string
+	Added           bool    // authentication key was added
+	Existing        *SSHKey // identical key already registered
+	SigningAdded    bool    // key was registered as signing key
+	SigningExisting bool    // key was already a signing key
+}
+
+// apiKey is the key representation shared by GitHub, GitLab and Gitea
+type apiKey struct {
+	ID        int64  `json:"id"`
+	Title     string `json:"title"`
+	Key       string `json:"key"`
+	UsageType string `json:"usage_type,omitempty"`
+}
+
+// bitbucketKey is Bitbucket's SSH key representation
+type bitbucketKey struct {
+	UUID  string `json:"uuid"`
+	Label string `json:"label"`
+	Key   string `json:"key"`
+}
+
+// SupportsSigningKeys reports whether keys can be registered for commit signing
+func (c *Client) SupportsSigningKeys() bool {
+	return c.Flavor == git.FlavorGitHub || c.Flavor == git.FlavorGitLab
+}
+
+// ListSSHKeys returns the authentication keys of the token's user
+func (c *Client) ListSSHKeys() ([]SSHKey, error) {
+	all, err := c.allKeys()
+	if err != nil {
+		return nil, err
+	}
+	var keys []SSHKey
+	for _, k := range all {
+		if !k.signingOnly {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+// allKeys returns every key on the keys endpoint, including GitLab keys
+// registered for signing only
+func (c *Client) allKeys() ([]SSHKey, error) {
+	if c.Flavor == git.FlavorBitbucket {
+		var keys []SSHKey
+		path := c.bitbucketKeysPath() + "?pagelen=100"
+		for i := 0; path != ""; i++ {
+			if i == maxPages {
+				return nil, fmt.Errorf("%w: more than %d pages of keys", ErrRequestFailed, maxPages)
+			}
+			var page struct {
+				Values []bitbucketKey `json:"values"`
+				Next   string         `json:"next"`
+			}
+			if err := c.do("GET", path, nil, &page); err != nil {
+				return nil, err
+			}
+			for _, k := range page.Values {
+				keys = append(keys, SSHKey{ID: k.UUID, Title: k.Label, Key: k.Key})
+			}
+			path = ""
+			if page.Next != "" {
+				next, err := c.apiPath(page.Next)
+				if err != nil {
+					return nil, err
+				}
+				path = next
+			}
+		}
+		return keys, nil
+	}
+
+	raw, err := c.listKeys("/user/keys?per_page=100")
+	if err != nil {
+		return nil, err
+	}
+	return convertKeys(raw), nil
+}
+
+// listKeys fetches every page of a GitHub, GitLab or Gitea key list
+func (c *Client) listKeys(path string) ([]apiKey, error) {
+	var keys []apiKey
+	for i := 0; path != ""; i++ {
+		if i == maxPages {
+			return nil, fmt.Errorf("%w: more than %d pages of keys", ErrRequestFailed, maxPages)
+		}
+		var page []apiKey
+		header, err := c.doWithHeader("GET", path, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, page...)
+		if path, err = c.nextPage(path, header); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// ListSigningKeys returns the keys registered for commit signing
+func (c *Client) ListSigningKeys() ([]SSHKey, error) {
+	switch c.Flavor {
+	case git.FlavorGitHub:
+		raw, err := c.listKeys("/user/ssh_signing_keys?per_page=100")
+		if err != nil {
+			return nil, err
+		}
+		keys := convertKeys(raw)
+		for i := range keys {
+			keys[i].Signing = true
+		}
+		return keys, nil
+	case git.FlavorGitLab:
+		all, err := c.allKeys()
+		if err != nil {
+			return nil, err
+		}
+		var keys []SSHKey
+		for _, k := range all {
+			if k.Signing {
+				keys = append(keys, k)
+			}
+		}
+		return keys, nil
+	}
+	return nil, ErrSigningUnsupported
+}
+
+// AddSSHKey registers a public key for authentication
+func (c *Client) AddSSHKey(title, publicKey string) (*SSHKey, error) {
+	return c.addKey(title, publicKey, gitlabUsageAuth)
+}
+
+// AddSigningKey registers a public key for commit signing
+func (c *Client) AddSigningKey(title, publicKey string) (*SSHKey, error) {
+	switch c.Flavor {
+	case git.FlavorGitHub:
+		var created apiKey
+		body := map[string]string{"title": title, "key": publicKey}
+		if err := c.do("POST", "/user/ssh_signing_keys", body, &created); err != nil {
+			return nil, err
+		}
+		key := convertKey(created)
+		key.Signing = true
+		return &key, nil
+	case git.FlavorGitLab:
+		return c.addKey(title, publicKey, gitlabUsageSigning)
+	}
+	return nil, ErrSigningUnsupported
+}
+
+// addKey registers an authentication key; usage is only sent to GitLab
+func (c *Client) addKey(title, publicKey, usage string) (*SSHKey, error) {
+	if c.Flavor == git.FlavorBitbucket {
+		var created bitbucketKey
+		body := map[string]string{"label": title, "key": publicKey}
+		if err := c.do("POST", c.bitbucketKeysPath(), body, &created); err != nil {
+			return nil, err
+		}
+		return &SSHKey{ID: created.UUID, Title: created.Label, Key: created.Key}, nil
+	}
+
+	body := map[string]string{"title": title, "key": publicKey}
+	if c.Flavor == git.FlavorGitLab {
+		body["usage_type"] = usage
+	}
+
+	var created apiKey
+	if err := c.do("POST", "/user/keys", body, &created); err != nil {
+		return nil, err
+	}
+	key := convertKey(created)
+	return &key, nil
+}
+
+// DeleteSSHKey removes an authentication key by ID
+func (c *Client) DeleteSSHKey(id string) error {
+	if c.Flavor == git.FlavorBitbucket {
+		return c.do("DELETE", c.bitbucketKeysPath()+"/"+url.PathEscape(id), nil, nil)
+	}
+	return c.do("DELETE", "/user/keys/"+url.PathEscape(id), nil, nil)
+}
+
+// UploadKey registers a public key unless an identical key already exists,
+// optionally also as a signing key
+func (c *Client) UploadKey(title, publicKey string, signing bool) (*UploadResult, error) {
+	fingerprint, err := ssh.Fingerprint(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	result := &UploadResult{Fingerprint: fingerprint}
+
+	if signing && !c.SupportsSigningKeys() {
+		return nil, ErrSigningUnsupported
+	}
+
+	keys, err := c.allKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	result.Existing = FindKey(keys, publicKey)
+	if result.Existing != nil && result.Existing.signingOnly {
+		// GitLab rejects a second key with the same fingerprint
+		return nil, fmt.Errorf("key is registered for signing only; re-add it on GitLab with usage \"Authentication & Signing\"")
+	}
+
+	if result.Existing == nil {
+		usage := gitlabUsageAuth
+		if signing && c.Flavor == git.FlavorGitLab {
+			// GitLab stores one key per fingerprint, with a usage type
+			usage = gitlabUsageAuthAndSigning
+		}
+		if _, err := c.addKey(title, publicKey, usage); err != nil {
+			return nil, fmt.Errorf("failed to add key: %w", err)
+		}
+		result.Added = true
+		if usage == gitlabUsageAuthAndSigning {
+			result.SigningAdded = true
+		}
+	}
+
+	if !signing || result.SigningAdded {
+		return result, nil
+	}
+
+	if c.Flavor == git.FlavorGitLab {
+		// Existing GitLab keys can't change usage through the API
+		if result.Existing.Signing {
+			result.SigningExisting = true
+			return result, nil
+		}
+		return result, fmt.Errorf("key is registered for authentication only; re-add it on GitLab with usage \"Authentication & Signing\"")
+	}
+
+	signingKeys, err := c.ListSigningKeys()
+	if err != nil {
+		return result, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	if FindKey(signingKeys, publicKey) != nil {
+		result.SigningExisting = true
+		return result, nil
+	}
+	if _, err := c.AddSigningKey(title, publicKey); err != nil {
+		return result, fmt.Errorf("failed to add signing key: %w", err)
+	}
+	result.SigningAdded = true
+	return result, nil
+}
+
+// FindKey returns the key with the same fingerprint as publicKey, if any
+func FindKey(keys []SSHKey, publicKey string) *SSHKey {
+	want, err := ssh.Fingerprint(publicKey)
+	if err != nil {
+		return nil
+	}
+	for i := range keys {
+		if fp, err := ssh.Fingerprint(keys[i].Key); err == nil && fp == want {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// bitbucketKeysPath returns the SSH keys endpoint for the Bitbucket user
+func (c *Client) bitbucketKeysPath() string {
+	return "/users/" + url.PathEscape(c.Username) + "/ssh-keys"
+}
+
+// convertKeys converts API keys to SSHKey values
+func convertKeys(raw []apiKey) []SSHKey {
+	keys := make([]SSHKey, len(raw))
+	for i, k := range raw {
+		keys[i] = convertKey(k)
+	}
+	return keys
+}
+
+// convertKey converts an API key to an SSHKey
+func convertKey(k apiKey) SSHKey {
+	return SSHKey{
+		ID:          strconv.FormatInt(k.ID, 10),
+		Title:       k.Title,
+		Key:         k.Key,
+		Signing:     k.UsageType == gitlabUsageSigning || k.UsageType == gitlabUsageAuthAndSigning,
+		signingOnly: k.UsageType == gitlabUsageSigning,
+	}
+}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/dwirx/ghex/internal/config"
+	"github.com/dwirx/ghex/internal/git"
+)
+
+const (
+	testKey      = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMrDP6Fc254OR/75BAFP7osbj3sNdNByR3mucXRJZZLL test@example"
+	testKeyNoTag = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMrDP6Fc254OR/75BAFP7osbj3sNdNByR3mucXRJZZLL"
+	otherKey     = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIKAfLSQUXk1NhY65peFDvigjySDsA3z0fjNXfVInce1R other"
+)
+
+// fakePlatform records key uploads for a GitHub/GitLab-style API
+type fakePlatform struct {
+	keys        []apiKey
+	signingKeys []apiKey
+	posts       []map[string]string
+	deletes     []string
+	authHeader  string
+	pageSize    int    // serve /user/keys in pages of this size
+	nextHeader  string // "Link" or "X-Next-Page"
+}
+
+func (f *fakePlatform) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.authHeader = r.Header.Get("Authorization") + r.Header.Get("PRIVATE-TOKEN")
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/user/keys":
+			json.NewEncoder(w).Encode(f.page(w, r))
+		case r.Method == "GET" && r.URL.Path == "/user/ssh_signing_keys":
+			json.NewEncoder(w).Encode(f.signingKeys)
+		case r.Method == "POST":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatalf("invalid request body: %v", err)
+			}
+			body["path"] = r.URL.Path
+			f.posts = append(f.posts, body)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(apiKey{ID: 42, Title: body["title"], Key: body["key"]})
+		case r.Method == "DELETE":
+			f.deletes = append(f.deletes, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// page returns the requested page of keys and advertises the next one
+func (f *fakePlatform) page(w http.ResponseWriter, r *http.Request) []apiKey {
+	if f.pageSize == 0 {
+		return f.keys
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	start := min((page-1)*f.pageSize, len(f.keys))
+	end := min(start+f.pageSize, len(f.keys))
+	if end < len(f.keys) {
+		if f.nextHeader == "Link" {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/user/keys?per_page=100&page=%d>; rel="next", <http://%s/user/keys?page=1>; rel="first"`, r.Host, page+1, r.Host))
+		} else {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+	}
+	return f.keys[start:end]
+}
+
+// TestUploadKeyGitHub tests adding authentication and signing keys on GitHub
+func TestUploadKeyGitHub(t *testing.T) {
+	fake := &fakePlatform{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	client := NewClient(git.FlavorGitHub, server.URL, "user", "secret")
+	result, err := client.UploadKey("laptop", testKey, true)
+	if err != nil {
+		t.Fatalf("UploadKey failed: %v", err)
+	}
+
+	if !result.Added || !result.SigningAdded {
+		t.Errorf("Expected key to be added for auth and signing: %+v", result)
+	}
+	if len(fake.posts) != 2 || fake.posts[0]["path"] != "/user/keys" || fake.posts[1]["path"] != "/user/ssh_signing_keys" {
+		t.Errorf("Unexpected requests: %v", fake.posts)
+	}
+	if fake.authHeader != "Bearer secret" {
+		t.Errorf("Unexpected auth header: %s", fake.authHeader)
+	}
+}
+
+// TestUploadKeyExisting tests that identical keys are detected by fingerprint
+func TestUploadKeyExisting(t *testing.T) {
+	fake := &fakePlatform{
+		keys: []apiKey{
+			{ID: 1, Title: "other", Key: otherKey},
+			{ID: 2, Title: "laptop", Key: testKeyNoTag}, // Platforms drop the comment
+		},
+	}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	client := NewClient(git.FlavorGitea, server.URL, "user", "secret")
+	result, err := client.UploadKey("laptop", testKey, false)
+	if err != nil {
+		t.Fatalf("UploadKey failed: %v", err)
+	}
+
+	if result.Added || result.Existing == nil || result.Existing.ID != "2" {
+		t.Errorf("Expected existing key to be detected: %+v", result)
+	}
+	if len(fake.posts) != 0 {
+		t.Errorf("Expected no uploads, got %v", fake.posts)
+	}
+	if fake.authHeader != "token secret" {
+		t.Errorf("Unexpected auth header: %s", fake.authHeader)
+	}
+}
+
+// TestUploadKeyGitLabSigning tests that GitLab keys are added with a combined usage type
+func TestUploadKeyGitLabSigning(t *testing.T) {
+	fake := &fakePlatform{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	client := NewClient(git.FlavorGitLab, server.URL, "user", "secret")
+	result, err := client.UploadKey("laptop", testKey, true)
+	if err != nil {
+		t.Fatalf("UploadKey failed: %v", err)
+	}
+
+	if !result.Added || !result.SigningAdded {
+		t.Errorf("Expected key to be added for auth and signing: %+v", result)
+	}
+	if len(fake.posts) != 1 || fake.posts[0]["usage_type"] != "auth_and_signing" {
+		t.Errorf("Unexpected requests: %v", fake.posts)
+	}
+}
+
+// TestUploadKeyPaginated tests that keys on later pages are found
+func TestUploadKeyPaginated(t *testing.T) {
+	for _, tt := range []struct {
+		flavor     string
+		nextHeader string
+	}{
+		{git.FlavorGitHub, "Link"},
+		{git.FlavorGitLab, "X-Next-Page"},
+	} {
+		fake := &fakePlatform{
+			keys: []apiKey{
+				{ID: 1, Title: "other", Key: otherKey},
+				{ID: 2, Title: "other", Key: otherKey},
+				{ID: 3, Title: "laptop", Key: testKeyNoTag},
+			},
+			pageSize:   2,
+			nextHeader: tt.nextHeader,
+		}
+		server := httptest.NewServer(fake.handler(t))
+
+		client := NewClient(tt.flavor, server.URL, "user", "secret")
+		result, err := client.UploadKey("laptop", testKey, false)
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: UploadKey failed: %v", tt.flavor, err)
+		}
+		if result.Added || result.Existing == nil || result.Existing.ID != "3" {
+			t.Errorf("%s: expected key on page 2 to be detected: %+v", tt.flavor, result)
+		}
+		if len(fake.posts) != 0 {
+			t.Errorf("%s: expected no uploads, got %v", tt.flavor, fake.posts)
+		}
+	}
+}
+
+// TestNextPageOtherHost tests that next links outside the API are refused
+func TestNextPageOtherHost(t *testing.T) {
+	client := NewClient(git.FlavorGitHub, "https://api.github.com", "user", "secret")
+	header := http.Header{"Link": {`<https://evil.example/user/keys?page=2>; rel="next"`}}
+	if _, err := client.nextPage("/user/keys", header); err == nil {
+		t.Error("Expected next page on another host to be refused")
+	}
+}
+
+// TestUploadKeyGitLabSigningOnly tests that a signing-only GitLab key is not
+// taken for an authentication key
+func TestUploadKeyGitLabSigningOnly(t *testing.T) {
+	fake := &fakePlatform{
+		keys: []apiKey{{ID: 2, Title: "laptop", Key: testKeyNoTag, UsageType: "signing"}},
+	}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	client := NewClient(git.FlavorGitLab, server.URL, "user", "secret")
+	if _, err := client.UploadKey("laptop", testKey, false); err == nil {
+		t.Error("Expected signing-only key to be reported")
+	}
+	if len(fake.posts) != 0 {
+		t.Errorf("Expected no uploads, got %v", fake.posts)
+	}
+
+	keys, err := client.ListSSHKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Expected signing-only key to be left out of auth keys: %+v", keys)
+	}
+	signing, err := client.ListSigningKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signing) != 1 {
+		t.Errorf("Expected signing-only key in signing keys: %+v", signing)
+	}
+}
+
+// TestUploadKeySigningUnsupported tests signing on platforms without signing keys
+func TestUploadKeySigningUnsupported(t *testing.T) {
+	client := NewClient(git.FlavorBitbucket, "http://127.0.0.1:0", "user", "secret")
+	if _, err := client.UploadKey("laptop", testKey, true); !errors.Is(err, ErrSigningUnsupported) {
+		t.Errorf("Expected ErrSigningUnsupported, got %v", err)
+	}
+}
+
+// TestDeleteSSHKey tests key removal paths for GitHub-style APIs and Bitbucket
+func TestDeleteSSHKey(t *testing.T) {
+	fake := &fakePlatform{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	if err := NewClient(git.FlavorGitHub, server.URL, "user", "secret").DeleteSSHKey("42"); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewClient(git.FlavorBitbucket, server.URL, "user", "secret").DeleteSSHKey("{uuid}"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.deletes) != 2 || fake.deletes[0] != "/user/keys/42" || fake.deletes[1] != "/users/user/ssh-keys/{uuid}" {
+		t.Errorf("Unexpected DELETE requests: %v", fake.deletes)
+	}
+}
+
+// TestUploadKeyUnauthorized tests that rejected tokens are reported
+func TestUploadKeyUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewClient(git.FlavorGitHub, server.URL, "user", "bad")
+	if _, err := client.UploadKey("laptop", testKey, false); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+// TestNewClientForAccount tests API URL selection per platform
+func TestNewClientForAccount(t *testing.T) {
+	tests := []struct {
+		platform *config.PlatformConfig
+		baseURL  string
+		flavor   string
+	}{
+		{nil, "https://api.github.com", git.FlavorGitHub},
+		{&config.PlatformConfig{Type: "github", Domain: "github.company.com"}, "https://github.company.com/api/v3", git.FlavorGitHub},
+		{&config.PlatformConfig{Type: "gitlab"}, "https://gitlab.com/api/v4", git.FlavorGitLab},
+		{&config.PlatformConfig{Type: "codeberg"}, "https://codeberg.org/api/v1", git.FlavorGitea},
+		{&config.PlatformConfig{Type: "gitea", Domain: "git.company.com"}, "https://git.company.com/api/v1", git.FlavorGitea},
+		{&config.PlatformConfig{Type: "bitbucket"}, "https://api.bitbucket.org/2.0", git.FlavorBitbucket},
+		{&config.PlatformConfig{Type: "gitlab", ApiUrl: "https://gl.internal/api/v4/"}, "https://gl.internal/api/v4", git.FlavorGitLab},
+	}
+
+	for _, tt := range tests {
+		acc := &config.Account{Name: "work", Platform: tt.platform, Token: &config.TokenConfig{Username: "u", Token: "t"}}
+		client, err := NewClientForAccount(acc)
+		if err != nil {
+			t.Errorf("NewClientForAccount(%+v) failed: %v", tt.platform, err)
+			continue
+		}
+		if client.BaseURL != tt.baseURL || client.Flavor != tt.flavor {
+			t.Errorf("NewClientForAccount(%+v) = %s (%s), expected %s (%s)", tt.platform, client.BaseURL, client.Flavor, tt.baseURL, tt.flavor)
+		}
+	}
+
+	if _, err := NewClientForAccount(&config.Account{Name: "nokey"}); !errors.Is(err, ErrNoToken) {
+		t.Errorf("Expected ErrNoToken, got %v", err)
+	}
+	if _, err := NewClientForAccount(&config.Account{Platform: &config.PlatformConfig{Type: "sourcehut"}, Token: &config.TokenConfig{Token: "t"}}); !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("Expected ErrUnsupportedPlatform, got %v", err)
+	}
+}
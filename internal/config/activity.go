@@ -0,0 +1,360 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dwirx/ghex/internal/platform"
+)
+
+// Activity log defaults, used when ActivitySettings leaves a value unset
+const (
+	DefaultActivityRetentionDays = 90
+	DefaultActivityMaxSizeKB     = 1024
+	DefaultActivityMaxAgeDays    = 30
+)
+
+// activityLogName is the file name of the current activity log
+const activityLogName = "activity.jsonl"
+
+// activityRotatedLayout is the timestamp in rotated log names (activity.<time>.jsonl)
+const activityRotatedLayout = "20060102-150405"
+
+// activityChunkSize is how much of a log is read at a time when reading backwards
+const activityChunkSize = 64 * 1024
+
+// GetActivityLogPath returns the path of the current activity log
+func GetActivityLogPath() string {
+	return filepath.Join(platform.GetConfigDir("ghe"), activityLogName)
+}
+
+// ActivityStore is an append-only JSON Lines activity log. The current file
+// is rotated once it reaches a size or age limit, and rotated files are
+// deleted after the retention period.
+type ActivityStore struct {
+	path      string
+	maxSize   int64
+	maxAge    time.Duration // 0 disables age-based rotation
+	retention time.Duration // 0 keeps rotated files forever
+	now       func() time.Time
+}
+
+// NewActivityStore creates a store at path using settings (nil for defaults)
+func NewActivityStore(path string, settings *ActivitySettings) *ActivityStore {
+	var s ActivitySettings
+	if settings != nil {
+		s = *settings
+	}
+	return &ActivityStore{
+		path:      path,
+		maxSize:   int64(activitySetting(s.MaxSizeKB, DefaultActivityMaxSizeKB)) * 1024,
+		maxAge:    dayDuration(activitySetting(s.MaxAgeDays, DefaultActivityMaxAgeDays)),
+		retention: dayDuration(activitySetting(s.RetentionDays, DefaultActivityRetentionDays)),
+		now:       time.Now,
+	}
+}
+
+// OpenActivityStore returns the activity store configured by cfg
+func OpenActivityStore(cfg *AppConfig) *ActivityStore {
+	return NewActivityStore(GetActivityLogPath(), cfg.Activity)
+}
+
+// activitySetting returns value, def when unset, or 0 when negative (disabled)
+func activitySetting(value, def int) int {
+	switch {
+	case value == 0:
+		return def
+	case value < 0:
+		return 0
+	}
+	return value
+}
+
+// dayDuration converts a number of days to a duration
+func dayDuration(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// Path returns the path of the current log file
+func (s *ActivityStore) Path() string {
+	return s.path
+}
+
+// Append writes entries to the log, rotating it first if needed
+func (s *ActivityStore) Append(entries ...ActivityLogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := platform.EnsureDir(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := s.rotateIfNeeded(); err != nil {
+		return err
+	}
+
+	data, err := marshalActivity(entries)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open activity log: %w", err)
+	}
+	defer f.Close()
+
+	// One write per call so concurrent ghex processes don't interleave lines
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write activity log: %w", err)
+	}
+	return nil
+}
+
+// Import stores entries logged before the store existed as a rotated file,
+// so they sort before the current log and follow the retention period.
+// It returns the path of the new file.
+func (s *ActivityStore) Import(entries []ActivityLogEntry) (string, error) {
+	if len(entries) == 0 {
+		return "", nil
+	}
+	if err := platform.EnsureDir(filepath.Dir(s.path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	last, err := time.Parse(time.RFC3339, entries[len(entries)-1].Timestamp)
+	if err != nil {
+		last = s.now()
+	}
+	data, err := marshalActivity(entries)
+	if err != nil {
+		return "", err
+	}
+	path := s.rotatedPath(last)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write activity log: %w", err)
+	}
+	return path, nil
+}
+
+// Each calls fn for every entry, most recent first, until fn returns false.
+// Files are read backwards in chunks, so recent entries are cheap to read.
+func (s *ActivityStore) Each(fn func(ActivityLogEntry) bool) error {
+	rotated, err := s.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	files := append([]string{s.path}, rotated...)
+
+	for _, path := range files {
+		more, err := eachLineReverse(path, func(line []byte) bool {
+			var entry ActivityLogEntry
+			if json.Unmarshal(line, &entry) != nil {
+				return true // skip damaged lines
+			}
+			return fn(entry)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read activity log: %w", err)
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Recent returns up to limit entries (0 for all), most recent first
+func (s *ActivityStore) Recent(limit int) ([]ActivityLogEntry, error) {
+	var result []ActivityLogEntry
+	err := s.Each(func(e ActivityLogEntry) bool {
+		result = append(result, e)
+		return limit <= 0 || len(result) < limit
+	})
+	return result, err
+}
+
+// Prune deletes rotated files older than the retention period and returns
+// their paths
+func (s *ActivityStore) Prune() ([]string, error) {
+	if s.retention <= 0 {
+		return nil, nil
+	}
+	rotated, err := s.rotatedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := s.now().Add(-s.retention)
+	var removed []string
+	for _, path := range rotated {
+		// A file is rotated after its last entry, so its name bounds every entry in it
+		rotatedAt, ok := rotatedTime(path)
+		if !ok || !rotatedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// Rotate moves the current log aside and prunes old files. It does nothing
+// when the current log is empty.
+func (s *ActivityStore) Rotate() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) || (err == nil && info.Size() == 0) {
+		_, err = s.Prune()
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read activity log: %w", err)
+	}
+
+	if err := os.Rename(s.path, s.rotatedPath(s.now())); err != nil {
+		return fmt.Errorf("failed to rotate activity log: %w", err)
+	}
+	_, err = s.Prune()
+	return err
+}
+
+// rotateIfNeeded rotates the current log when it is too large or too old
+func (s *ActivityStore) rotateIfNeeded() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil
+	}
+	if s.maxSize > 0 && info.Size() >= s.maxSize {
+		return s.Rotate()
+	}
+	if s.maxAge > 0 {
+		if first, ok := firstActivityTime(s.path); ok && s.now().Sub(first) >= s.maxAge {
+			return s.Rotate()
+		}
+	}
+	return nil
+}
+
+// rotatedPath returns an unused rotated file name for t
+func (s *ActivityStore) rotatedPath(t time.Time) string {
+	base := strings.TrimSuffix(s.path, filepath.Ext(s.path))
+	for {
+		path := fmt.Sprintf("%s.%s.jsonl", base, t.UTC().Format(activityRotatedLayout))
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		t = t.Add(time.Second)
+	}
+}
+
+// rotatedFiles returns the rotated files, most recent first
+func (s *ActivityStore) rotatedFiles() ([]string, error) {
+	base := strings.TrimSuffix(s.path, filepath.Ext(s.path))
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(s.path), filepath.Base(base)+".*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, path := range matches {
+		if _, ok := rotatedTime(path); ok {
+			files = append(files, path)
+		}
+	}
+	// The timestamp layout sorts chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, nil
+}
+
+// rotatedTime returns the rotation time encoded in a rotated file name
+func rotatedTime(path string) (time.Time, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(activityRotatedLayout, name[i+1:])
+	return t, err == nil
+}
+
+// firstActivityTime returns the timestamp of the first entry in a log
+func firstActivityTime(path string) (time.Time, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return time.Time{}, false
+	}
+	var entry ActivityLogEntry
+	if json.Unmarshal(line, &entry) != nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, entry.Timestamp)
+	return t, err == nil
+}
+
+// marshalActivity encodes entries as JSON Lines
+func marshalActivity(entries []ActivityLogEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return nil, fmt.Errorf("failed to encode activity: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// eachLineReverse calls fn for each non-empty line of a file, last line
+// first, until fn returns false. It reports whether all lines were read;
+// a missing file has no lines.
+func eachLineReverse(path string, fn func(line []byte) bool) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	offset := info.Size()
+	buf := make([]byte, activityChunkSize)
+	var partial []byte // start of a line continued in the previous chunk
+	for offset > 0 {
+		n := min(int64(len(buf)), offset)
+		offset -= n
+		if _, err := f.ReadAt(buf[:n], offset); err != nil {
+			return false, err
+		}
+
+		lines := bytes.Split(append(buf[:n:n], partial...), []byte{'\n'})
+		// The first line may continue in the next chunk back
+		partial = append([]byte(nil), lines[0]...)
+		for i := len(lines) - 1; i >= 1; i-- {
+			if len(bytes.TrimSpace(lines[i])) > 0 && !fn(lines[i]) {
+				return false, nil
+			}
+		}
+	}
+	if len(bytes.TrimSpace(partial)) > 0 && !fn(partial) {
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStore returns a store in a temporary directory with a fixed clock
+func testStore(t *testing.T, settings *ActivitySettings, now time.Time) *ActivityStore {
+	s := NewActivityStore(filepath.Join(t.TempDir(), activityLogName), settings)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+// activityAt returns an entry logged at t
+func activityAt(t time.Time, account string) ActivityLogEntry {
+	return ActivityLogEntry{Timestamp: t.UTC().Format(time.RFC3339), Action: "switch", AccountName: account, Success: true}
+}
+
+// accountNames returns the account of each entry
+func accountNames(entries []ActivityLogEntry) string {
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.AccountName)
+	}
+	return strings.Join(names, ",")
+}
+
+// TestActivityStoreRecent tests reading across rotated files
+func TestActivityStoreRecent(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := testStore(t, &ActivitySettings{MaxSizeKB: 1, MaxAgeDays: -1}, now)
+
+	// Long entries fill several rotated files
+	for i := 0; i < 100; i++ {
+		entry := activityAt(now, fmt.Sprintf("a%d", i))
+		entry.Error = strings.Repeat("x", 300)
+		if err := s.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+		s.now = func() time.Time { return now.Add(time.Duration(i) * time.Minute) }
+	}
+
+	rotated, _ := s.rotatedFiles()
+	if len(rotated) < 10 {
+		t.Errorf("Expected size-based rotation, got %d rotated files", len(rotated))
+	}
+
+	recent, err := s.Recent(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := accountNames(recent); got != "a99,a98,a97" {
+		t.Errorf("Recent(3) = %s", got)
+	}
+
+	all, _ := s.Recent(0)
+	if len(all) != 100 || all[99].AccountName != "a0" {
+		t.Errorf("Expected all 100 entries newest first, got %d", len(all))
+	}
+}
+
+// TestActivityStoreLargeFile tests lines that span read chunks
+func TestActivityStoreLargeFile(t *testing.T) {
+	now := time.Now()
+	s := testStore(t, &ActivitySettings{MaxSizeKB: -1}, now)
+
+	var entries []ActivityLogEntry
+	for i := 0; i < 500; i++ {
+		entry := activityAt(now, fmt.Sprintf("a%d", i))
+		entry.Error = strings.Repeat("x", i)
+		entries = append(entries, entry)
+	}
+	if err := s.Append(entries...); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := s.Recent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 500 {
+		t.Fatalf("Expected 500 entries, got %d", len(all))
+	}
+	for i, e := range all {
+		if e.AccountName != fmt.Sprintf("a%d", 499-i) || len(e.Error) != 499-i {
+			t.Fatalf("Entry %d is %s", i, e.AccountName)
+		}
+	}
+}
+
+// TestActivityStoreAgeRotationAndRetention tests age rotation and pruning
+func TestActivityStoreAgeRotationAndRetention(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := testStore(t, &ActivitySettings{RetentionDays: 60, MaxAgeDays: 30}, start)
+
+	// One entry every 10 days for 120 days
+	for day := 0; day <= 120; day += 10 {
+		at := start.AddDate(0, 0, day)
+		s.now = func() time.Time { return at }
+		if err := s.Append(activityAt(at, fmt.Sprintf("d%d", day))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := s.Recent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldest := all[len(all)-1].AccountName
+	if oldest == "d0" || len(all) < 6 {
+		t.Errorf("Expected old entries pruned and recent ones kept, got %s", accountNames(all))
+	}
+	if all[0].AccountName != "d120" {
+		t.Errorf("Expected newest entry first, got %s", all[0].AccountName)
+	}
+}
+
+// TestActivityStoreSkipsDamagedLines tests reading a partly written log
+func TestActivityStoreSkipsDamagedLines(t *testing.T) {
+	s := testStore(t, nil, time.Now())
+	data := `{"timestamp":"2024-01-01T00:00:00Z","action":"add","accountName":"a","success":true}` + "\n" +
+		`{"timestamp":"2024-01-02T00:00:00Z","act` + "\n\n" +
+		`{"timestamp":"2024-01-03T00:00:00Z","action":"add","accountName":"b","success":true}`
+	os.WriteFile(s.Path(), []byte(data), 0600)
+
+	all, err := s.Recent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := accountNames(all); got != "b,a" {
+		t.Errorf("Expected b,a, got %s", got)
+	}
+}
+
+// TestMigrateActivityLog tests moving config.json entries to the store
+func TestMigrateActivityLog(t *testing.T) {
+	dir := t.TempDir()
+	m := &Manager{primaryPath: filepath.Join(dir, "config.json"), legacyPath: filepath.Join(dir, "legacy.json")}
+
+	now := time.Now()
+	cfg := NewAppConfig()
+	cfg.ActivityLog = []ActivityLogEntry{activityAt(now.Add(-time.Hour), "old"), activityAt(now, "new")}
+	if err := m.Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := m.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.ActivityLog) != 0 {
+		t.Errorf("Expected the log to leave the config, got %d entries", len(loaded.ActivityLog))
+	}
+	data, _ := os.ReadFile(m.primaryPath)
+	if strings.Contains(string(data), "activityLog") {
+		t.Errorf("Expected config.json without activityLog:\n%s", data)
+	}
+
+	store := NewActivityStore(m.GetActivityLogPath(), nil)
+	if err := store.Append(activityAt(now, "after")); err != nil {
+		t.Fatal(err)
+	}
+	all, _ := store.Recent(0)
+	if got := accountNames(all); got != "after,new,old" {
+		t.Errorf("Expected migrated entries before new ones, got %s", got)
+	}
+
+	// Loading again must not import twice
+	m.Load()
+	if all, _ := store.Recent(0); len(all) != 3 {
+		t.Errorf("Expected 3 entries after reloading, got %d", len(all))
+	}
+}
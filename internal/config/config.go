@@ -42,6 +42,7 @@ func (m *Manager) Load() (*AppConfig, error) {
 			if path == m.legacyPath {
 				_ = m.Save(cfg) // Ignore migration errors
 			}
+			m.migrateActivityLog(cfg)
 			return cfg, nil
 		}
 
@@ -58,6 +59,32 @@ func (m *Manager) Load() (*AppConfig, error) {
 	return NewAppConfig(), nil
 }
 
+// GetActivityLogPath returns the activity log next to the configuration file
+func (m *Manager) GetActivityLogPath() string {
+	return filepath.Join(filepath.Dir(m.primaryPath), activityLogName)
+}
+
+// migrateActivityLog moves entries kept in config.json by older versions
+// to the activity store. On failure the entries stay in config.json and
+// the migration is retried on the next load.
+func (m *Manager) migrateActivityLog(cfg *AppConfig) {
+	if len(cfg.ActivityLog) == 0 {
+		return
+	}
+	imported, err := NewActivityStore(m.GetActivityLogPath(), cfg.Activity).Import(cfg.ActivityLog)
+	if err != nil {
+		return
+	}
+
+	legacy := cfg.ActivityLog
+	cfg.ActivityLog = []ActivityLogEntry{}
+	if err := m.Save(cfg); err != nil {
+		// Don't import the entries twice
+		os.Remove(imported)
+		cfg.ActivityLog = legacy
+	}
+}
+
 // loadFromPath loads configuration from a specific path
 func (m *Manager) loadFromPath(path string) (*AppConfig, error) {
 	data, err := os.ReadFile(path)
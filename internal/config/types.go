@@ -4,6 +4,12 @@ package config
 type SshConfig struct {
 	KeyPath   string `json:"keyPath"`
 	HostAlias string `json:"hostAlias,omitempty"`
+	User      string `json:"user,omitempty"` // SSH user override (AWS CodeCommit: SSH key ID)
+
+	KeyCreatedAt string `json:"keyCreatedAt,omitempty"` // RFC3339, set when ghex generates or rotates the key
+
+	AddToAgent    bool   `json:"addToAgent,omitempty"`    // load the key into ssh-agent on switch
+	AgentLifetime string `json:"agentLifetime,omitempty"` // agent lifetime, e.g. "1h" (empty: until agent exits)
 }
 
 // TokenConfig holds token/PAT authentication configuration
@@ -14,11 +20,27 @@ type TokenConfig struct {
 
 // PlatformConfig holds git platform configuration
 type PlatformConfig struct {
-	Type   string `json:"type"`             // github, gitlab, bitbucket, gitea, codeberg, other
+	Type   string `json:"type"`             // github, gitlab, bitbucket, gitea, codeberg, azure, codecommit, sourcehut, other
 	Domain string `json:"domain,omitempty"` // custom domain (e.g., gitlab.company.com)
 	ApiUrl string `json:"apiUrl,omitempty"` // custom API endpoint
 }
 
+// CustomPlatform describes a user-defined git hosting platform, such as a
+// self-hosted Forgejo or a GitLab instance listening on a non-standard SSH port
+type CustomPlatform struct {
+	Name          string   `json:"name"`                       // identifier used in Account.Platform.Type
+	DisplayName   string   `json:"displayName,omitempty"`      // human-readable name (default: Name)
+	Flavor        string   `json:"flavor,omitempty"`           // github, gitlab, gitea, bitbucket, generic
+	Hosts         []string `json:"hosts"`                      // host patterns, glob syntax (e.g., git.company.com, *.forge.local)
+	SSHHost       string   `json:"sshHost,omitempty"`          // SSH host when it differs from the web host
+	SSHUser       string   `json:"sshUser,omitempty"`          // SSH user (default: git)
+	SSHPort       int      `json:"sshPort,omitempty"`          // SSH port (default: 22)
+	SSHTemplate   string   `json:"sshUrlTemplate,omitempty"`   // e.g., ssh://{user}@{host}:{port}/{path}
+	HTTPSTemplate string   `json:"httpsUrlTemplate,omitempty"` // e.g., https://{host}/scm/{path}
+	ApiUrl        string   `json:"apiUrl,omitempty"`           // API base URL
+	HostKeys      []string `json:"hostKeys,omitempty"`         // pinned SSH host key fingerprints ("[type] SHA256:...")
+}
+
 // Account represents a configured GitHub/Git account
 type Account struct {
 	Name        string          `json:"name"`
@@ -43,19 +65,32 @@ type HealthStatus struct {
 // ActivityLogEntry represents a single activity log entry
 type ActivityLogEntry struct {
 	Timestamp   string `json:"timestamp"`
-	Action      string `json:"action"` // switch, add, remove, edit, test
+	Action      string `json:"action"` // switch, add, remove, edit, test, health, global-ssh, clone, keygen, rotate, update, rollback
 	AccountName string `json:"accountName"`
 	RepoPath    string `json:"repoPath,omitempty"`
 	Method      string `json:"method,omitempty"` // ssh, token
 	Platform    string `json:"platform,omitempty"`
+	Detail      string `json:"detail,omitempty"` // e.g. key path or version change
 	Success     bool   `json:"success"`
 	Error       string `json:"error,omitempty"`
+	DurationMs  int64  `json:"durationMs,omitempty"`
+	Version     string `json:"version,omitempty"` // ghex version that logged the entry
+}
+
+// ActivitySettings configures rotation and retention of the activity log.
+// Zero values use the defaults; negative values disable the limit.
+type ActivitySettings struct {
+	RetentionDays int `json:"retentionDays,omitempty"` // delete rotated logs older than this
+	MaxSizeKB     int `json:"maxSizeKB,omitempty"`     // rotate the current log at this size
+	MaxAgeDays    int `json:"maxAgeDays,omitempty"`    // rotate the current log once its first entry is this old
 }
 
 // AppConfig is the main application configuration
 type AppConfig struct {
 	Accounts        []Account          `json:"accounts"`
-	ActivityLog     []ActivityLogEntry `json:"activityLog,omitempty"`
+	Platforms       []CustomPlatform   `json:"platforms,omitempty"`
+	ActivityLog     []ActivityLogEntry `json:"activityLog,omitempty"` // legacy, moved to the activity store on load
+	Activity        *ActivitySettings  `json:"activity,omitempty"`
 	HealthChecks    []HealthStatus     `json:"healthChecks,omitempty"`
 	LastHealthCheck string             `json:"lastHealthCheck,omitempty"`
 }
@@ -0,0 +1,425 @@
+package doctor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dwirx/ghex/internal/git"
+	"github.com/dwirx/ghex/internal/platform"
+	"github.com/dwirx/ghex/internal/ssh"
+)
+
+// Minimum tool versions
+var (
+	minGitVersion = [2]int{2, 22} // git branch --show-current
+	minSSHVersion = [2]int{7, 3}  // Include, which loads the ghex-managed Host blocks
+)
+
+var (
+	gitVersionPattern = regexp.MustCompile(`git version (\d+)\.(\d+)`)
+	sshVersionPattern = regexp.MustCompile(`OpenSSH_(?:for_Windows_)?(\d+)\.(\d+)`)
+	insteadOfKey      = regexp.MustCompile(`^url\.(.+)\.(insteadof|pushinsteadof)$`)
+)
+
+// parseVersion extracts a major.minor version with pattern
+func parseVersion(pattern *regexp.Regexp, s string) ([2]int, bool) {
+	m := pattern.FindStringSubmatch(s)
+	if m == nil {
+		return [2]int{}, false
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	return [2]int{major, minor}, true
+}
+
+// olderThan compares major.minor versions
+func olderThan(v, min [2]int) bool {
+	return v[0] < min[0] || (v[0] == min[0] && v[1] < min[1])
+}
+
+// gitConfig parses the git config of a scope, nil if it can't be read
+func (env *Env) gitConfig(scope string) map[string][]string {
+	out, err := env.GitConfig(scope)
+	if err != nil {
+		return nil
+	}
+	return git.ParseConfigList(out)
+}
+
+// last returns the effective (last) value of a config key
+func last(cfg map[string][]string, key string) string {
+	values := cfg[key]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+func checkGit(env *Env) []Finding {
+	if !env.CommandExists("git") {
+		return []Finding{{Severity: SeverityError, Message: "git is not installed", Fix: "Install git from https://git-scm.com/downloads"}}
+	}
+	out, _ := env.Output("git", "--version")
+	v, ok := parseVersion(gitVersionPattern, out)
+	if !ok {
+		return []Finding{{Severity: SeverityWarning, Message: fmt.Sprintf("Cannot read git version from %q", strings.TrimSpace(out))}}
+	}
+	if olderThan(v, minGitVersion) {
+		return []Finding{{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("git %d.%d is older than %d.%d, which ghex needs", v[0], v[1], minGitVersion[0], minGitVersion[1]),
+			Fix:      "Upgrade git",
+		}}
+	}
+	return []Finding{{Severity: SeverityOK, Message: fmt.Sprintf("git %d.%d", v[0], v[1])}}
+}
+
+func checkSSH(env *Env) []Finding {
+	if !env.CommandExists("ssh") {
+		return []Finding{{Severity: SeverityError, Message: "ssh is not installed", Fix: "Install OpenSSH"}}
+	}
+
+	var findings []Finding
+	out, _ := env.Output("ssh", "-V")
+	v, ok := parseVersion(sshVersionPattern, out)
+	switch {
+	case !ok:
+		findings = append(findings, Finding{Severity: SeverityInfo, Message: fmt.Sprintf("Not OpenSSH or unknown version: %q", strings.TrimSpace(out))})
+	case olderThan(v, minSSHVersion):
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("OpenSSH %d.%d is older than %d.%d; it rejects the Include of ghex-managed Host blocks", v[0], v[1], minSSHVersion[0], minSSHVersion[1]),
+			Fix:      "Upgrade OpenSSH",
+		})
+	default:
+		findings = append(findings, Finding{Severity: SeverityOK, Message: fmt.Sprintf("OpenSSH %d.%d", v[0], v[1])})
+	}
+
+	for _, tool := range []string{"ssh-keygen", "ssh-add"} {
+		if !env.CommandExists(tool) {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Message:  tool + " not found; key generation and agent commands won't work",
+				Fix:      "Install the OpenSSH client tools",
+			})
+		}
+	}
+	return findings
+}
+
+func checkIdentity(env *Env) []Finding {
+	global := env.gitConfig("global")
+	name, email := last(global, "user.name"), last(global, "user.email")
+
+	var findings []Finding
+	if env.RepoPath != "" {
+		local := env.gitConfig("local")
+		localName, localEmail := last(local, "user.name"), last(local, "user.email")
+		switch {
+		case localEmail == "" && email != "":
+			findings = append(findings, Finding{
+				Severity: SeverityInfo,
+				Message:  fmt.Sprintf("This repository uses the global identity %s <%s>", name, email),
+				Fix:      "Run 'ghex switch' to set a per-repository identity",
+			})
+		case localEmail != "" && email != "" && localEmail != email:
+			findings = append(findings, Finding{
+				Severity: SeverityOK,
+				Message:  fmt.Sprintf("Repository identity %s <%s> overrides the global %s", localName, localEmail, email),
+			})
+		}
+		if localName != "" {
+			name = localName
+		}
+		if localEmail != "" {
+			email = localEmail
+		}
+	}
+
+	if name == "" || email == "" {
+		return append(findings, Finding{
+			Severity: SeverityError,
+			Message:  "No git user.name/user.email set; commits will fail",
+			Fix:      "Run 'ghex switch' or git config --global user.email you@example.com",
+		})
+	}
+
+	if len(env.Emails) > 0 && !containsFold(env.Emails, email) {
+		return append(findings, Finding{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("Commits use %s, which no ghex account uses", email),
+			Fix:      "Run 'ghex switch' to use an account identity",
+		})
+	}
+	if len(findings) == 0 {
+		findings = append(findings, Finding{Severity: SeverityOK, Message: fmt.Sprintf("Identity %s <%s>", name, email)})
+	}
+	return findings
+}
+
+// credentialHelpers returns the helpers git will use; an empty value resets
+// the list, as in git
+func credentialHelpers(cfg map[string][]string) []string {
+	var helpers []string
+	for _, h := range cfg["credential.helper"] {
+		if h == "" {
+			helpers = nil
+			continue
+		}
+		helpers = append(helpers, h)
+	}
+	return helpers
+}
+
+func checkCredentialHelper(env *Env) []Finding {
+	helpers := credentialHelpers(env.gitConfig(""))
+
+	var findings []Finding
+	if len(helpers) > 1 {
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("Several credential helpers are configured (%s); git may return another account's token", strings.Join(helpers, ", ")),
+			Fix:      "Keep one helper: git config --global --unset-all credential.helper, then set it again",
+		})
+	}
+
+	usesStore := false
+	for _, h := range helpers {
+		if h == "store" || strings.HasPrefix(h, "store ") {
+			usesStore = true
+		}
+	}
+
+	switch {
+	case env.TokenAccounts > 0 && !usesStore:
+		f := Finding{
+			Severity: SeverityWarning,
+			Message:  "Token accounts write ~/.git-credentials, but the store credential helper isn't enabled",
+			Fix:      "git config credential.helper store",
+		}
+		if env.RepoPath != "" {
+			f.Apply = git.EnsureCredentialStore
+		}
+		findings = append(findings, f)
+	case usesStore:
+		findings = append(findings, Finding{Severity: SeverityInfo, Message: "Credentials are stored in plain text in ~/.git-credentials"})
+	case len(helpers) == 0:
+		findings = append(findings, Finding{Severity: SeverityOK, Message: "No credential helper configured"})
+	case len(findings) == 0:
+		findings = append(findings, Finding{Severity: SeverityOK, Message: "Credential helper: " + helpers[0]})
+	}
+	return findings
+}
+
+// isSSHURL reports whether a URL or insteadOf prefix uses SSH
+func isSSHURL(s string) bool {
+	return strings.HasPrefix(s, "ssh://") || (!strings.Contains(s, "://") && strings.Contains(s, "@") && strings.Contains(s, ":"))
+}
+
+func checkInsteadOf(env *Env) []Finding {
+	cfg := env.gitConfig("")
+
+	var keys []string
+	for key := range cfg {
+		if insteadOfKey.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var findings []Finding
+	targets := make(map[string][]string) // prefix → bases rewriting it
+	for _, key := range keys {
+		m := insteadOfKey.FindStringSubmatch(key)
+		base, kind := m[1], m[2]
+		for _, prefix := range cfg[key] {
+			targets[kind+" "+prefix] = append(targets[kind+" "+prefix], base)
+
+			if isSSHURL(base) == isSSHURL(prefix) {
+				continue
+			}
+			for _, host := range env.Hosts {
+				if !strings.Contains(prefix, host) && !strings.Contains(base, host) {
+					continue
+				}
+				effect := "HTTPS remotes use SSH, so token accounts are bypassed"
+				if isSSHURL(prefix) {
+					effect = "SSH remotes use HTTPS, so ghex's SSH keys are bypassed"
+				}
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("%s rewrites %s to %s: %s", key, prefix, base, effect),
+					Fix:      fmt.Sprintf("git config --global --unset %s %q", key, prefix),
+				})
+				break
+			}
+		}
+	}
+
+	var prefixes []string
+	for p := range targets {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	for _, p := range prefixes {
+		if bases := targets[p]; len(bases) > 1 {
+			_, prefix, _ := strings.Cut(p, " ")
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("%s is rewritten by several rules (%s); only one applies", prefix, strings.Join(bases, ", ")),
+				Fix:      "Remove the duplicate url.<base>.insteadOf entries",
+			})
+		}
+	}
+
+	if len(findings) == 0 {
+		findings = append(findings, Finding{Severity: SeverityOK, Message: fmt.Sprintf("%d URL rewrite rules, no conflicts", len(prefixes))})
+	}
+	return findings
+}
+
+func checkSSHCommand(env *Env) []Finding {
+	var findings []Finding
+	for _, name := range []string{"GIT_SSH_COMMAND", "GIT_SSH"} {
+		if value := env.Getenv(name); value != "" {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("%s=%s overrides the ssh command for every git operation", name, value),
+				Fix:      fmt.Sprintf("unset %s (and remove it from your shell profile)", name),
+			})
+		}
+	}
+
+	scopes := []string{"global"}
+	if env.RepoPath != "" {
+		scopes = append(scopes, "local")
+	}
+	for _, scope := range scopes {
+		if value := last(env.gitConfig(scope), "core.sshcommand"); value != "" {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("core.sshCommand=%s (%s) can override the keys ghex configures", value, scope),
+				Fix:      fmt.Sprintf("git config --%s --unset core.sshCommand", scope),
+			})
+		}
+	}
+
+	if len(findings) == 0 {
+		findings = append(findings, Finding{Severity: SeverityOK, Message: "No ssh command overrides"})
+	}
+	return findings
+}
+
+func checkSSHConfig(env *Env) []Finding {
+	user, err := ssh.LoadConfig(ssh.GetSSHConfigPath())
+	if err != nil {
+		return []Finding{{Severity: SeverityError, Message: err.Error()}}
+	}
+	managed, err := ssh.LoadConfig(ssh.GetManagedConfigPath())
+	if err != nil {
+		return []Finding{{Severity: SeverityError, Message: err.Error()}}
+	}
+	if len(managed.Hosts()) == 0 {
+		return []Finding{{Severity: SeverityOK, Message: "No ghex-managed Host blocks"}}
+	}
+
+	if !user.Includes(managed.Path) {
+		return []Finding{{
+			Severity: SeverityError,
+			Message:  "~/.ssh/config doesn't include " + ssh.ManagedInclude + ", so ghex's Host blocks are ignored",
+			Fix:      "Add 'Include " + ssh.ManagedInclude + "' at the top of ~/.ssh/config",
+			Apply:    ssh.EnsureManagedInclude,
+		}}
+	}
+
+	// Blocks older versions may have written are only removed when asked
+	var findings []Finding
+	candidates, _ := ssh.LegacyHostCandidates()
+	for _, alias := range candidates {
+		alias := alias
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("Host %s in ~/.ssh/config looks like a block older ghex versions wrote and duplicates the managed one", alias),
+			Fix:      fmt.Sprintf("Remove the Host %s block from ~/.ssh/config", alias),
+			Apply:    func() error { return ssh.RemoveUserHostBlocks([]string{alias}) },
+		})
+	}
+
+	// ssh uses the first value it finds, so blocks above the Include win
+	for _, block := range managed.Hosts() {
+		patterns := block.Patterns()
+		if len(patterns) != 1 || strings.ContainsAny(patterns[0], "*?!") {
+			continue
+		}
+		resolved, err := user.Resolve(patterns[0])
+		if err != nil {
+			findings = append(findings, Finding{Severity: SeverityWarning, Message: err.Error()})
+			continue
+		}
+		for _, keyword := range []string{"HostName", "User", "Port"} {
+			want := block.Get(keyword)
+			if want == "" {
+				continue
+			}
+			if got := resolved.Get(keyword); !strings.EqualFold(got, want) {
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("Host %s: %s %s from ~/.ssh/config overrides ghex's %s", patterns[0], keyword, got, want),
+					Fix:      fmt.Sprintf("Move the Include line above the %s setting in ~/.ssh/config, or remove that setting", keyword),
+				})
+			}
+		}
+	}
+
+	if len(findings) == 0 {
+		findings = append(findings, Finding{Severity: SeverityOK, Message: fmt.Sprintf("%d ghex Host blocks in effect", len(managed.Hosts()))})
+	}
+	return findings
+}
+
+func checkSSHFiles(env *Env) []Finding {
+	var findings []Finding
+
+	for _, ref := range env.Keys {
+		if !platform.FileExists(platform.ExpandPath(ref.KeyPath)) {
+			findings = append(findings, Finding{
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Account %s points to missing key %s", ref.Account, ref.KeyPath),
+				Fix:      "Run 'ghex ssh generate' or 'ghex ssh import' for the account",
+			})
+		}
+	}
+
+	issues, err := env.Permissions()
+	if err != nil {
+		findings = append(findings, Finding{Severity: SeverityWarning, Message: err.Error()})
+	} else if len(issues) > 0 {
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("%d permission problem(s) in ~/.ssh (details: ghex ssh doctor)", len(issues)),
+			Fix:      "Restrict the modes of the reported files",
+			Apply: func() error {
+				_, err := ssh.FixPermissions(issues)
+				return err
+			},
+		})
+	}
+
+	if len(findings) == 0 {
+		findings = append(findings, Finding{Severity: SeverityOK, Message: fmt.Sprintf("%d account keys present, permissions fine", len(env.Keys))})
+	}
+	return findings
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+// Package doctor diagnoses the git and SSH environment ghex depends on
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dwirx/ghex/internal/git"
+	"github.com/dwirx/ghex/internal/platform"
+	"github.com/dwirx/ghex/internal/shell"
+	"github.com/dwirx/ghex/internal/ssh"
+)
+
+// Severity ranks findings
+type Severity int
+
+const (
+	SeverityOK Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+)
+
+// String returns the severity name
+func (s Severity) String() string {
+	switch s {
+	case SeverityOK:
+		return "ok"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// Finding is the result of one check
+type Finding struct {
+	Check    string
+	Severity Severity
+	Message  string
+	Fix      string       // suggested fix, empty if nothing to do
+	Apply    func() error // applies Fix; set only for fixes that are safe to automate
+}
+
+// Fixable reports whether ghex can apply the fix itself
+func (f Finding) Fixable() bool {
+	return f.Apply != nil
+}
+
+// Env is what the checks inspect. Tests replace the functions.
+type Env struct {
+	RepoPath      string   // git repository being checked, empty outside a repo
+	Hosts         []string // SSH hosts used by accounts
+	Keys          []ssh.KeyReference
+	Emails        []string // git emails of accounts
+	TokenAccounts int      // accounts using HTTPS tokens
+	Getenv        func(string) string
+	CommandExists func(string) bool
+	Output        func(name string, args ...string) (string, error) // combined output
+	GitConfig     func(scope string) (string, error)                // "" for the merged config
+	Permissions   func() ([]ssh.PermissionIssue, error)
+}
+
+// NewEnv returns an Env backed by the real system
+func NewEnv(repoPath string) *Env {
+	return &Env{
+		RepoPath:      repoPath,
+		Getenv:        os.Getenv,
+		CommandExists: shell.CommandExists,
+		Output:        shell.Exec,
+		GitConfig: func(scope string) (string, error) {
+			if scope == "" {
+				if repoPath != "" {
+					return shell.RunInDir(repoPath, "git", "config", "--list")
+				}
+				return git.GetConfigList()
+			}
+			return git.GetScopedConfigList(scope, repoPath)
+		},
+		Permissions: ssh.CheckPermissions,
+	}
+}
+
+// Check is a named diagnostic
+type Check struct {
+	Name string
+	Run  func(env *Env) []Finding
+}
+
+// Checks returns all checks in report order
+func Checks() []Check {
+	return []Check{
+		{"git", checkGit},
+		{"ssh", checkSSH},
+		{"identity", checkIdentity},
+		{"credentials", checkCredentialHelper},
+		{"insteadOf", checkInsteadOf},
+		{"ssh command", checkSSHCommand},
+		{"ssh config", checkSSHConfig},
+		{"ssh files", checkSSHFiles},
+	}
+}
+
+// Report is the outcome of a doctor run
+type Report struct {
+	Time     time.Time
+	Findings []Finding
+	Env      map[string]string // tool versions and system details
+}
+
+// Run runs all checks
+func Run(env *Env, version string) *Report {
+	report := &Report{
+		Time: time.Now(),
+		Env: map[string]string{
+			"os":   runtime.GOOS + "/" + runtime.GOARCH,
+			"ghex": version,
+		},
+	}
+	if out, err := env.Output("git", "--version"); err == nil {
+		report.Env["git"] = strings.TrimSpace(out)
+	}
+	if out, err := env.Output("ssh", "-V"); err == nil {
+		report.Env["ssh"] = strings.TrimSpace(out)
+	}
+
+	for _, check := range Checks() {
+		for _, f := range check.Run(env) {
+			f.Check = check.Name
+			report.Findings = append(report.Findings, f)
+		}
+	}
+	return report
+}
+
+// Worst returns the highest severity in the report
+func (r *Report) Worst() Severity {
+	worst := SeverityOK
+	for _, f := range r.Findings {
+		if f.Severity > worst {
+			worst = f.Severity
+		}
+	}
+	return worst
+}
+
+// Fixable returns the findings ghex can fix itself
+func (r *Report) Fixable() []Finding {
+	var fixable []Finding
+	for _, f := range r.Findings {
+		if f.Fixable() && f.Severity > SeverityOK {
+			fixable = append(fixable, f)
+		}
+	}
+	return fixable
+}
+
+// Markdown renders the report for a bug report. Secrets and the home
+// directory are redacted.
+func (r *Report) Markdown(gitConfig string, secrets []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# ghex doctor report\n\n")
+	fmt.Fprintf(&b, "Generated %s\n\n", r.Time.UTC().Format(time.RFC3339))
+
+	b.WriteString("## Environment\n\n")
+	keys := make([]string, 0, len(r.Env))
+	for k := range r.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "- %s: %s\n", k, r.Env[k])
+	}
+
+	b.WriteString("\n## Findings\n\n")
+	for _, f := range r.Findings {
+		fmt.Fprintf(&b, "- [%s] %s: %s\n", f.Severity, f.Check, f.Message)
+		if f.Fix != "" {
+			fmt.Fprintf(&b, "  - fix: %s\n", f.Fix)
+		}
+	}
+
+	if gitConfig != "" {
+		b.WriteString("\n## git config --list\n\n```\n")
+		b.WriteString(strings.TrimSpace(gitConfig))
+		b.WriteString("\n```\n")
+	}
+	return Redact(b.String(), secrets)
+}
+
+// urlCredentials matches user:password@ in URLs
+var urlCredentials = regexp.MustCompile(`://[^/@\s:]+:[^/@\s]+@`)
+
+// Redact removes secrets, URL credentials and the home directory from text
+func Redact(text string, secrets []string) string {
+	for _, s := range secrets {
+		if s != "" {
+			text = strings.ReplaceAll(text, s, "[REDACTED]")
+		}
+	}
+	text = urlCredentials.ReplaceAllString(text, "://[REDACTED]@")
+	if home := platform.GetHomeDir(); home != "" && home != "/" {
+		text = strings.ReplaceAll(text, home, "~")
+	}
+	return text
+}
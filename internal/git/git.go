@@ -152,14 +152,22 @@ func WriteCredentials(username, token, host string) error {
 
 // TestTokenAuth tests token authentication against GitHub API
 func TestTokenAuth(username, token string) (bool, string, error) {
+	return TestTokenAuthForPlatform("github", "", username, token)
+}
+
+// TestTokenAuthForPlatform tests token authentication against the platform's API
+func TestTokenAuthForPlatform(platformType, domain, username, token string) (bool, string, error) {
+	args, err := tokenCheckArgs(platformType, domain, username, token)
+	if err != nil {
+		return false, err.Error(), err
+	}
+
 	// Use curl to test authentication
-	output, err := shell.Exec("curl",
+	output, err := shell.Exec("curl", append([]string{
 		"-s",
 		"-o", "/dev/null",
 		"-w", "%{http_code}",
-		"-u", fmt.Sprintf("%s:%s", username, token),
-		"https://api.github.com/user",
-	)
+	}, args...)...)
 
 	code := strings.TrimSpace(output)
 	if code == "200" {
@@ -169,7 +177,91 @@ func TestTokenAuth(username, token string) (bool, string, error) {
 	return false, fmt.Sprintf("HTTP %s", code), err
 }
 
+// tokenCheckArgs returns the curl arguments (auth and URL) used to validate a token
+func tokenCheckArgs(platformType, domain, username, token string) ([]string, error) {
+	basicAuth := []string{"-u", fmt.Sprintf("%s:%s", username, token)}
+	apiURL := strings.TrimSuffix(GetPlatformAPIURL(platformType), "/")
+	host := GetPlatformHTTPSHost(platformType, domain)
+
+	switch GetPlatformFlavor(platformType) {
+	case FlavorGitHub:
+		if apiURL == "" {
+			apiURL = "https://api.github.com"
+			if domain != "" && !strings.EqualFold(domain, "github.com") {
+				apiURL = fmt.Sprintf("https://%s/api/v3", domain)
+			}
+		}
+		return append(basicAuth, apiURL+"/user"), nil
+	case FlavorGitLab:
+		if apiURL == "" {
+			apiURL = fmt.Sprintf("https://%s/api/v4", host)
+		}
+		return []string{"-H", "PRIVATE-TOKEN: " + token, apiURL + "/user"}, nil
+	case FlavorGitea:
+		if domain == "" && platformType == "gitea" {
+			return nil, fmt.Errorf("gitea requires a custom domain")
+		}
+		if apiURL == "" {
+			apiURL = fmt.Sprintf("https://%s/api/v1", host)
+		}
+		return []string{"-H", "Authorization: token " + token, apiURL + "/user"}, nil
+	case FlavorBitbucket:
+		if apiURL == "" {
+			apiURL = "https://api.bitbucket.org/2.0"
+		}
+		return append(basicAuth, apiURL+"/user"), nil
+	}
+
+	switch strings.ToLower(platformType) {
+	case "azure":
+		// Personal access tokens are accepted with any username
+		return append(basicAuth, "https://app.vssps.visualstudio.com/_apis/profile/profiles/me?api-version=7.1"), nil
+	case "sourcehut":
+		return []string{
+			"-X", "POST",
+			"-H", "Authorization: Bearer " + token,
+			"-H", "Content-Type: application/json",
+			"-d", `{"query":"{ me { canonicalName } }"}`,
+			"https://meta.sr.ht/query",
+		}, nil
+	case "codecommit":
+		return nil, fmt.Errorf("AWS CodeCommit git credentials can only be checked against a repository (try git ls-remote)")
+	}
+
+	if apiURL != "" {
+		return append(basicAuth, apiURL+"/user"), nil
+	}
+	return nil, fmt.Errorf("token validation is not supported for platform '%s'", platformType)
+}
+
 // GetConfigList returns all git configuration
 func GetConfigList() (string, error) {
 	return shell.Run("git", "config", "--list")
 }
+
+// GetScopedConfigList returns the git configuration of one scope ("system",
+// "global" or "local"); local reads the repository at path
+func GetScopedConfigList(scope, path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	return shell.RunInDir(path, "git", "config", "--"+scope, "--list")
+}
+
+// ParseConfigList parses `git config --list` output into each key's values,
+// in the order git reports them
+func ParseConfigList(output string) map[string][]string {
+	values := make(map[string][]string)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		key, value, found := strings.Cut(line, "=")
+		if key == "" {
+			continue
+		}
+		if !found {
+			value = "true" // git prints bare boolean keys without a value
+		}
+		values[key] = append(values[key], value)
+	}
+	return values
+}
@@ -0,0 +1,198 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Platform flavors describe which API and URL conventions a custom platform follows
+const (
+	FlavorGitHub    = "github"
+	FlavorGitLab    = "gitlab"
+	FlavorGitea     = "gitea"
+	FlavorBitbucket = "bitbucket"
+	FlavorGeneric   = "generic"
+)
+
+// customPlatforms holds user-defined platforms keyed by lowercase name
+var customPlatforms = map[string]PlatformURLConfig{}
+
+// customPlatformOrder preserves registration order so host matching is deterministic
+var customPlatformOrder []string
+
+// RegisterPlatform registers a user-defined platform under the given name
+func RegisterPlatform(name string, cfg PlatformURLConfig) error {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		return fmt.Errorf("platform name is required")
+	}
+	if _, ok := platformURLConfigs[key]; ok {
+		return fmt.Errorf("platform '%s' is built in and cannot be redefined", name)
+	}
+	if len(cfg.HostPatterns) == 0 {
+		return fmt.Errorf("platform '%s' has no host patterns", name)
+	}
+	for _, pattern := range cfg.HostPatterns {
+		if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+			return fmt.Errorf("platform '%s' has invalid host pattern '%s': %w", name, pattern, err)
+		}
+	}
+
+	if cfg.Flavor == "" {
+		cfg.Flavor = FlavorGeneric
+	}
+	if cfg.DefaultHost == "" {
+		cfg.DefaultHost = firstLiteralHost(cfg.HostPatterns)
+	}
+
+	if _, exists := customPlatforms[key]; !exists {
+		customPlatformOrder = append(customPlatformOrder, key)
+	}
+	customPlatforms[key] = cfg
+	return nil
+}
+
+// ResetCustomPlatforms removes all user-defined platforms
+func ResetCustomPlatforms() {
+	customPlatforms = map[string]PlatformURLConfig{}
+	customPlatformOrder = nil
+}
+
+// IsCustomPlatform reports whether name refers to a registered user-defined platform
+func IsCustomPlatform(name string) bool {
+	_, ok := customPlatforms[strings.ToLower(name)]
+	return ok
+}
+
+// MatchCustomPlatform finds the user-defined platform whose host patterns match host
+func MatchCustomPlatform(host string) (string, bool) {
+	host = strings.ToLower(stripPort(host))
+	if host == "" {
+		return "", false
+	}
+
+	for _, name := range customPlatformOrder {
+		cfg := customPlatforms[name]
+		if matchesAnyHost(cfg.HostPatterns, host) {
+			return name, true
+		}
+		if cfg.SSHHost != "" && strings.EqualFold(cfg.SSHHost, host) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// MatchHostPattern reports whether host matches a glob-style host pattern
+func MatchHostPattern(pattern, host string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	host = strings.ToLower(host)
+	if pattern == host {
+		return true
+	}
+	matched, err := path.Match(pattern, host)
+	return err == nil && matched
+}
+
+// matchesAnyHost reports whether host matches any of the given host patterns
+func matchesAnyHost(patterns []string, host string) bool {
+	host = stripPort(host)
+	for _, pattern := range patterns {
+		if MatchHostPattern(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPlatformFlavor returns the API/URL flavor for a platform
+// Built-in platforms are their own flavor; custom platforms use their configured one
+func GetPlatformFlavor(platform string) string {
+	platform = strings.ToLower(platform)
+	if cfg, ok := customPlatforms[platform]; ok {
+		return cfg.Flavor
+	}
+	switch platform {
+	case "codeberg":
+		return FlavorGitea
+	case "github", "gitlab", "gitea", "bitbucket":
+		return platform
+	default:
+		return FlavorGeneric
+	}
+}
+
+// GetPlatformSSHUser returns the SSH user for a platform (default: git)
+func GetPlatformSSHUser(platform string) string {
+	if user := GetPlatformURLConfig(platform).SSHUser; user != "" {
+		return user
+	}
+	return "git"
+}
+
+// GetPlatformSSHPort returns the SSH port for a platform, or 0 for the default port
+func GetPlatformSSHPort(platform string) int {
+	port := GetPlatformURLConfig(platform).SSHPort
+	if port == 22 {
+		return 0
+	}
+	return port
+}
+
+// GetPlatformAPIURL returns the configured API base URL for a platform, if any
+func GetPlatformAPIURL(platform string) string {
+	return GetPlatformURLConfig(platform).APIURL
+}
+
+// ExtractHost returns the host part of a git URL, without user or port
+func ExtractHost(rawURL string) string {
+	return stripPort(detectHost(strings.TrimSpace(rawURL)))
+}
+
+// expandURLTemplate fills {user}, {host}, {port}, {path}, {owner} and {repo} placeholders
+func expandURLTemplate(template, user, host string, port int, repoPath string) string {
+	owner, repo := "", repoPath
+	if idx := strings.LastIndex(repoPath, "/"); idx >= 0 {
+		owner, repo = repoPath[:idx], repoPath[idx+1:]
+	}
+
+	// Drop ":{port}" entirely when no port is set so the URL stays valid
+	portStr := ""
+	if port > 0 {
+		portStr = strconv.Itoa(port)
+	} else {
+		template = strings.ReplaceAll(template, ":{port}", "")
+	}
+
+	replacer := strings.NewReplacer(
+		"{user}", user,
+		"{host}", host,
+		"{port}", portStr,
+		"{path}", repoPath,
+		"{owner}", owner,
+		"{repo}", repo,
+	)
+	return replacer.Replace(template)
+}
+
+// firstLiteralHost returns the first host pattern without wildcards
+func firstLiteralHost(patterns []string) string {
+	for _, p := range patterns {
+		if !strings.ContainsAny(p, "*?[") {
+			return strings.ToLower(strings.TrimSpace(p))
+		}
+	}
+	return ""
+}
+
+// stripPort removes a trailing :port from a host
+func stripPort(host string) string {
+	if idx := strings.LastIndex(host, ":"); idx >= 0 {
+		if _, err := strconv.Atoi(host[idx+1:]); err == nil {
+			return host[:idx]
+		}
+	}
+	return host
+}
@@ -2,7 +2,6 @@ package git
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
 )
 
@@ -10,50 +9,82 @@ import (
 type URLInfo struct {
 	URL      string
 	IsSSH    bool
+	Scheme   string // ssh, scp, git, http or https
+	User     string
 	Host     string
-	Owner    string
+	Port     int
+	Owner    string // full namespace, e.g. "group/subgroup"
 	Repo     string
-	Platform string // github, gitlab, bitbucket, gitea, other
+	Platform string // github, gitlab, bitbucket, gitea, azure, codecommit, sourcehut, other
 }
 
-// ParseRepoFromURL extracts owner/repo from a git URL
+// ParseRepoFromURL extracts owner/repo from a git URL.
+// The owner keeps the full namespace, so nested GitLab groups stay intact.
 func ParseRepoFromURL(rawURL string) (owner, repo string, err error) {
-	if rawURL == "" {
-		return "", "", fmt.Errorf("empty URL")
+	u, err := ParseGitURL(rawURL)
+	if err != nil {
+		return "", "", err
 	}
 
-	rawURL = strings.TrimSpace(rawURL)
-
-	// SSH format: git@host:owner/repo.git
-	sshPattern := regexp.MustCompile(`^git@([^:]+):(.+?)(?:\.git)?$`)
-	if matches := sshPattern.FindStringSubmatch(rawURL); len(matches) == 3 {
-		parts := strings.Split(matches[2], "/")
-		if len(parts) >= 2 {
-			return parts[0], strings.TrimSuffix(parts[len(parts)-1], ".git"), nil
-		}
+	owner, repo, ok := splitRepoPath(u.Host, u.RepoPath())
+	if !ok {
+		return "", "", fmt.Errorf("unable to parse URL: %s", strings.TrimSpace(rawURL))
 	}
+	return owner, repo, nil
+}
 
-	// SSH format: ssh://git@host/owner/repo.git
-	sshURLPattern := regexp.MustCompile(`^ssh://git@([^/]+)/(.+?)(?:\.git)?$`)
-	if matches := sshURLPattern.FindStringSubmatch(rawURL); len(matches) == 3 {
-		parts := strings.Split(matches[2], "/")
-		if len(parts) >= 2 {
-			return parts[0], strings.TrimSuffix(parts[len(parts)-1], ".git"), nil
+// splitRepoPath splits a URL path into owner and repo using the host's path layout.
+// Azure DevOps returns "org/project" as owner and AWS CodeCommit returns the region.
+func splitRepoPath(host, repoPath string) (owner, repo string, ok bool) {
+	host = strings.ToLower(host)
+	parts := strings.Split(strings.Trim(repoPath, "/"), "/")
+
+	switch detectPlatform(host) {
+	case "azure":
+		// SSH: v3/org/project/repo
+		if len(parts) == 4 && parts[0] == "v3" {
+			return parts[1] + "/" + parts[2], parts[3], true
+		}
+		// HTTPS: org/project/_git/repo or, on visualstudio.com, [collection/]project/_git/repo
+		for i, part := range parts {
+			if part != "_git" || i == 0 || i != len(parts)-2 {
+				continue
+			}
+			project := parts[i-1]
+			org := parts[0]
+			if strings.HasSuffix(host, ".visualstudio.com") {
+				org = strings.TrimSuffix(host, ".visualstudio.com")
+			}
+			return org + "/" + project, parts[i+1], true
 		}
+		return "", "", false
+	case "codecommit":
+		// v1/repos/repo, owner is the AWS region
+		if len(parts) == 3 && parts[0] == "v1" && parts[1] == "repos" {
+			return codeCommitRegion(host), parts[2], true
+		}
+		return "", "", false
 	}
 
-	// HTTPS format: https://host/owner/repo.git
-	httpsPattern := regexp.MustCompile(`^https?://([^/]+)/(.+?)(?:\.git)?$`)
-	if matches := httpsPattern.FindStringSubmatch(rawURL); len(matches) == 3 {
-		parts := strings.Split(matches[2], "/")
-		if len(parts) >= 2 {
-			return parts[0], strings.TrimSuffix(parts[len(parts)-1], ".git"), nil
-		}
+	if len(parts) >= 2 {
+		return strings.Join(parts[:len(parts)-1], "/"), parts[len(parts)-1], true
 	}
+	return "", "", false
+}
 
-	return "", "", fmt.Errorf("unable to parse URL: %s", rawURL)
+// codeCommitRegion extracts the AWS region from a CodeCommit host
+// such as git-codecommit.eu-west-1.amazonaws.com
+func codeCommitRegion(host string) string {
+	host = strings.TrimPrefix(strings.ToLower(host), "git-codecommit.")
+	if idx := strings.Index(host, "."); idx > 0 {
+		return host[:idx]
+	}
+	return defaultCodeCommitRegion
 }
 
+// defaultCodeCommitRegion is used when no AWS region is known
+const defaultCodeCommitRegion = "us-east-1"
+
 // NormalizeURL normalizes a git URL and adds .git suffix if missing
 func NormalizeURL(rawURL string) (normalized string, isSSH bool, err error) {
 	if rawURL == "" {
@@ -63,23 +94,19 @@ func NormalizeURL(rawURL string) (normalized string, isSSH bool, err error) {
 	rawURL = strings.TrimSpace(rawURL)
 	rawURL = strings.TrimSuffix(rawURL, "#") // Remove trailing #
 
-	// SSH format: git@host:path or ssh://git@host/path
-	if strings.HasPrefix(rawURL, "git@") || strings.HasPrefix(rawURL, "ssh://") {
-		if !strings.HasSuffix(rawURL, ".git") {
-			rawURL += ".git"
-		}
-		return rawURL, true, nil
+	u, err := ParseGitURL(rawURL)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid git URL format: %s", rawURL)
 	}
 
-	// HTTPS format
-	if strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://") {
-		if !strings.HasSuffix(rawURL, ".git") {
-			rawURL += ".git"
-		}
-		return rawURL, false, nil
+	// Azure DevOps, CodeCommit and SourceHut paths don't take a .git suffix
+	switch detectPlatform(u.Host) {
+	case "azure", "codecommit", "sourcehut":
+	default:
+		u.GitSuffix = true
 	}
 
-	return "", false, fmt.Errorf("invalid git URL format: %s", rawURL)
+	return u.String(), u.IsSSH(), nil
 }
 
 // ParseURL parses a git URL and returns detailed information
@@ -94,41 +121,34 @@ func ParseURL(rawURL string) (*URLInfo, error) {
 		return nil, err
 	}
 
-	host := detectHost(normalized)
-	platform := detectPlatform(host)
+	u, err := ParseGitURL(normalized)
+	if err != nil {
+		return nil, err
+	}
 
 	return &URLInfo{
 		URL:      normalized,
 		IsSSH:    isSSH,
-		Host:     host,
+		Scheme:   u.Scheme,
+		User:     u.User,
+		Host:     u.Host,
+		Port:     u.Port,
 		Owner:    owner,
 		Repo:     repo,
-		Platform: platform,
+		Platform: detectPlatform(u.Host),
 	}, nil
 }
 
+// IsSSHURL reports whether a git URL uses SSH (ssh:// or scp-style user@host:path)
+func IsSSHURL(rawURL string) bool {
+	u, err := ParseGitURL(rawURL)
+	return err == nil && u.IsSSH()
+}
+
 // detectHost extracts the host from a URL
 func detectHost(rawURL string) string {
-	// SSH format: git@host:path
-	if strings.HasPrefix(rawURL, "git@") {
-		parts := strings.SplitN(rawURL[4:], ":", 2)
-		if len(parts) > 0 {
-			return parts[0]
-		}
-	}
-
-	// SSH URL format: ssh://git@host/path
-	if strings.HasPrefix(rawURL, "ssh://git@") {
-		parts := strings.SplitN(rawURL[10:], "/", 2)
-		if len(parts) > 0 {
-			return parts[0]
-		}
-	}
-
-	// HTTPS format
-	httpsPattern := regexp.MustCompile(`^https?://([^/]+)`)
-	if matches := httpsPattern.FindStringSubmatch(rawURL); len(matches) == 2 {
-		return matches[1]
+	if u, err := ParseGitURL(rawURL); err == nil {
+		return u.Host
 	}
 
 	return ""
@@ -136,6 +156,10 @@ func detectHost(rawURL string) string {
 
 // detectPlatform detects the git platform from the host
 func detectPlatform(host string) string {
+	if name, ok := MatchCustomPlatform(host); ok {
+		return name
+	}
+
 	host = strings.ToLower(host)
 
 	if strings.Contains(host, "github") {
@@ -153,6 +177,15 @@ func detectPlatform(host string) string {
 	if strings.Contains(host, "gitea") {
 		return "gitea"
 	}
+	if host == "dev.azure.com" || host == "ssh.dev.azure.com" || strings.HasSuffix(host, ".visualstudio.com") {
+		return "azure"
+	}
+	if strings.HasPrefix(host, "git-codecommit.") && strings.HasSuffix(host, ".amazonaws.com") {
+		return "codecommit"
+	}
+	if host == "sr.ht" || strings.HasSuffix(host, ".sr.ht") {
+		return "sourcehut"
+	}
 
 	return "other"
 }
@@ -162,6 +195,16 @@ type PlatformURLConfig struct {
 	SSHFormat   string // Format string for SSH URL (e.g., "git@%s:%s")
 	HTTPSFormat string // Format string for HTTPS URL (e.g., "https://%s/%s")
 	DefaultHost string // Default host for the platform
+
+	// Fields below are only used by custom platforms
+	Flavor        string   // github, gitlab, gitea, bitbucket, generic
+	HostPatterns  []string // Glob patterns matched against URL hosts
+	SSHHost       string   // SSH host when it differs from the web host
+	SSHUser       string   // SSH user (default: git)
+	SSHPort       int      // SSH port (0 or 22 for default)
+	SSHTemplate   string   // Template for SSH URLs, e.g. "ssh://{user}@{host}:{port}/{path}"
+	HTTPSTemplate string   // Template for HTTPS URLs, e.g. "https://{host}/{path}"
+	APIURL        string   // API base URL
 }
 
 // platformURLConfigs holds URL configurations for each platform
@@ -191,6 +234,21 @@ var platformURLConfigs = map[string]PlatformURLConfig{
 		HTTPSFormat: "https://%s/%s",
 		DefaultHost: "codeberg.org",
 	},
+	"azure": {
+		SSHFormat:   "git@%s:v3/%s",
+		HTTPSFormat: "https://%s/%s",
+		DefaultHost: "dev.azure.com",
+	},
+	"codecommit": {
+		SSHFormat:   "ssh://%s/v1/repos/%s",
+		HTTPSFormat: "https://%s/v1/repos/%s",
+		DefaultHost: "git-codecommit." + defaultCodeCommitRegion + ".amazonaws.com",
+	},
+	"sourcehut": {
+		SSHFormat:   "git@%s:%s",
+		HTTPSFormat: "https://%s/%s",
+		DefaultHost: "git.sr.ht",
+	},
 	"other": {
 		SSHFormat:   "git@%s:%s",
 		HTTPSFormat: "https://%s/%s",
@@ -204,6 +262,9 @@ func GetPlatformURLConfig(platform string) PlatformURLConfig {
 	if config, ok := platformURLConfigs[platform]; ok {
 		return config
 	}
+	if config, ok := customPlatforms[platform]; ok {
+		return config
+	}
 	return platformURLConfigs["other"]
 }
 
@@ -218,18 +279,93 @@ func BuildRemoteURL(platform, domain, repoPath string, useSSH bool) string {
 		}
 	}
 
+	switch strings.ToLower(platform) {
+	case "azure", "codecommit", "sourcehut":
+		// These platforms use their own path layout and no .git suffix
+		return buildMultiSegmentRemoteURL(strings.ToLower(platform), config, domain, strings.TrimSuffix(repoPath, ".git"), useSSH)
+	}
+
 	// Ensure repo path has .git suffix
 	if !strings.HasSuffix(repoPath, ".git") {
 		repoPath += ".git"
 	}
 
+	if IsCustomPlatform(platform) {
+		return buildCustomRemoteURL(platform, config, domain, repoPath, useSSH)
+	}
+
 	if useSSH {
+		// scp-style URLs can't carry a port, so fall back to ssh://
+		if stripPort(domain) != domain {
+			return fmt.Sprintf("ssh://git@%s/%s", domain, repoPath)
+		}
 		return fmt.Sprintf(config.SSHFormat, domain, repoPath)
 	}
 
 	return fmt.Sprintf(config.HTTPSFormat, domain, repoPath)
 }
 
+// buildCustomRemoteURL builds a remote URL for a user-defined platform
+func buildCustomRemoteURL(platform string, config PlatformURLConfig, domain, repoPath string, useSSH bool) string {
+	if !useSSH {
+		template := config.HTTPSTemplate
+		if template == "" {
+			template = "https://{host}/{path}"
+		}
+		return expandURLTemplate(template, "", domain, 0, repoPath)
+	}
+
+	host := GetPlatformSSHHost(platform, domain)
+	user := GetPlatformSSHUser(platform)
+	port := GetPlatformSSHPort(platform)
+
+	template := config.SSHTemplate
+	if template == "" {
+		template = "{user}@{host}:{path}"
+		if port > 0 {
+			template = "ssh://{user}@{host}:{port}/{path}"
+		}
+	}
+	return expandURLTemplate(template, user, host, port, repoPath)
+}
+
+// buildMultiSegmentRemoteURL builds remote URLs for Azure DevOps, AWS CodeCommit and SourceHut.
+// Azure DevOps expects "org/project/repo", CodeCommit "region/repo" (or just "repo")
+// and SourceHut "~user/repo".
+func buildMultiSegmentRemoteURL(platform string, config PlatformURLConfig, domain, repoPath string, useSSH bool) string {
+	switch platform {
+	case "azure":
+		parts := strings.Split(repoPath, "/")
+		if len(parts) != 3 {
+			break
+		}
+		org, project, repo := parts[0], parts[1], parts[2]
+		if useSSH {
+			return fmt.Sprintf(config.SSHFormat, GetPlatformSSHHost(platform, domain), repoPath)
+		}
+		if strings.HasSuffix(strings.ToLower(domain), ".visualstudio.com") {
+			return fmt.Sprintf("https://%s/%s/_git/%s", domain, project, repo)
+		}
+		return fmt.Sprintf("https://%s/%s/%s/_git/%s", domain, org, project, repo)
+
+	case "codecommit":
+		// A leading region selects the regional endpoint
+		if idx := strings.Index(repoPath, "/"); idx >= 0 {
+			domain = "git-codecommit." + repoPath[:idx] + ".amazonaws.com"
+			repoPath = repoPath[idx+1:]
+		}
+		if useSSH {
+			return fmt.Sprintf(config.SSHFormat, domain, repoPath)
+		}
+		return fmt.Sprintf(config.HTTPSFormat, domain, repoPath)
+	}
+
+	if useSSH {
+		return fmt.Sprintf("git@%s:%s", GetPlatformSSHHost(platform, domain), repoPath)
+	}
+	return fmt.Sprintf("https://%s/%s", domain, repoPath)
+}
+
 // BuildSSHRemoteURL builds an SSH remote URL for a platform
 func BuildSSHRemoteURL(platform, domain, repoPath string) string {
 	return BuildRemoteURL(platform, domain, repoPath, true)
@@ -256,6 +392,24 @@ func WithGitSuffix(repoPath string) string {
 
 // GetPlatformSSHHost returns the SSH host for a platform
 func GetPlatformSSHHost(platform, domain string) string {
+	if config, ok := customPlatforms[strings.ToLower(platform)]; ok {
+		// A separate SSH host only applies to the platform's own web hosts
+		if config.SSHHost != "" && (domain == "" || matchesAnyHost(config.HostPatterns, domain)) {
+			return config.SSHHost
+		}
+		if domain == "" {
+			domain = config.DefaultHost
+		}
+	}
+
+	if strings.EqualFold(platform, "azure") {
+		// Azure DevOps serves SSH from a dedicated host
+		if strings.HasSuffix(strings.ToLower(domain), ".visualstudio.com") {
+			return "vs-ssh.visualstudio.com"
+		}
+		return "ssh.dev.azure.com"
+	}
+
 	if domain != "" {
 		return domain
 	}
@@ -267,7 +421,22 @@ func GetPlatformSSHHost(platform, domain string) string {
 		return "gitlab.com"
 	case "bitbucket":
 		return "bitbucket.org"
+	case "codeberg":
+		return "codeberg.org"
+	case "codecommit", "sourcehut":
+		return GetDefaultDomain(platform)
 	default:
 		return "github.com"
 	}
 }
+
+// GetPlatformHTTPSHost returns the web/HTTPS host for a platform
+func GetPlatformHTTPSHost(platform, domain string) string {
+	if domain != "" {
+		return domain
+	}
+	if host := GetDefaultDomain(platform); host != "" {
+		return host
+	}
+	return "github.com"
+}
@@ -0,0 +1,180 @@
+package git
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Git URL schemes
+const (
+	SchemeSSH   = "ssh"
+	SchemeSCP   = "scp" // user@host:path
+	SchemeGit   = "git"
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
+)
+
+// GitURL is a git remote URL split into its parts
+type GitURL struct {
+	Scheme    string // ssh, scp, git, http or https
+	User      string // user name, if any
+	Host      string // host name without port or brackets
+	Port      int    // port, or 0 when not set
+	Path      string // repository path as written, without .git suffix
+	GitSuffix bool   // whether the path ended in .git
+
+	password string // kept only so String() doesn't drop embedded credentials
+}
+
+// scpURLPattern matches scp-style URLs: user@host:path
+var scpURLPattern = regexp.MustCompile(`^([^@/:\s]+)@(\[[^\]]+\]|[^:/\s]+):(.*)$`)
+
+// ParseGitURL parses ssh://, git://, http(s):// and scp-style git URLs
+func ParseGitURL(rawURL string) (*GitURL, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, fmt.Errorf("empty URL")
+	}
+
+	if !strings.Contains(rawURL, "://") {
+		matches := scpURLPattern.FindStringSubmatch(rawURL)
+		if matches == nil {
+			return nil, fmt.Errorf("unable to parse URL: %s", rawURL)
+		}
+		u := &GitURL{
+			Scheme: SchemeSCP,
+			User:   matches[1],
+			Host:   strings.Trim(matches[2], "[]"),
+		}
+		u.setPath(matches[3])
+		if u.RepoPath() == "" {
+			return nil, fmt.Errorf("missing repository path: %s", rawURL)
+		}
+		return u, nil
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse URL: %w", err)
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	switch scheme {
+	case "git+ssh", "ssh+git":
+		scheme = SchemeSSH
+	case SchemeSSH, SchemeGit, SchemeHTTP, SchemeHTTPS:
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme: %s", parsed.Scheme)
+	}
+
+	u := &GitURL{
+		Scheme: scheme,
+		Host:   parsed.Hostname(),
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host: %s", rawURL)
+	}
+	if parsed.User != nil {
+		u.User = parsed.User.Username()
+		u.password, _ = parsed.User.Password()
+	}
+	if p := parsed.Port(); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port: %s", p)
+		}
+		u.Port = port
+	}
+
+	u.setPath(strings.TrimPrefix(parsed.Path, "/"))
+	if u.RepoPath() == "" {
+		return nil, fmt.Errorf("missing repository path: %s", rawURL)
+	}
+	return u, nil
+}
+
+// setPath stores the path and records a trailing .git suffix
+func (u *GitURL) setPath(p string) {
+	u.GitSuffix = strings.HasSuffix(p, ".git")
+	u.Path = strings.TrimSuffix(p, ".git")
+}
+
+// IsSSH reports whether the URL uses SSH transport
+func (u *GitURL) IsSSH() bool {
+	return u.Scheme == SchemeSSH || u.Scheme == SchemeSCP
+}
+
+// RepoPath returns the full repository path, e.g. "group/subgroup/repo"
+func (u *GitURL) RepoPath() string {
+	return strings.Trim(u.Path, "/")
+}
+
+// Namespace returns everything before the repository name, e.g. "group/subgroup"
+func (u *GitURL) Namespace() string {
+	p := u.RepoPath()
+	if idx := strings.LastIndex(p, "/"); idx >= 0 {
+		return p[:idx]
+	}
+	return ""
+}
+
+// Name returns the repository name without .git suffix
+func (u *GitURL) Name() string {
+	p := u.RepoPath()
+	return p[strings.LastIndex(p, "/")+1:]
+}
+
+// HostPort returns the host with port, bracketing IPv6 addresses
+func (u *GitURL) HostPort() string {
+	if u.Port > 0 {
+		return net.JoinHostPort(u.Host, strconv.Itoa(u.Port))
+	}
+	if strings.Contains(u.Host, ":") {
+		return "[" + u.Host + "]"
+	}
+	return u.Host
+}
+
+// String formats the URL back into its original form
+func (u *GitURL) String() string {
+	p := u.Path
+	if u.GitSuffix {
+		p += ".git"
+	}
+
+	if u.Scheme == SchemeSCP {
+		return fmt.Sprintf("%s@%s:%s", u.User, u.HostPort(), p)
+	}
+
+	userInfo := ""
+	if u.password != "" {
+		userInfo = url.UserPassword(u.User, u.password).String() + "@"
+	} else if u.User != "" {
+		userInfo = url.User(u.User).String() + "@"
+	}
+	return fmt.Sprintf("%s://%s%s/%s", u.Scheme, userInfo, u.HostPort(), p)
+}
+
+// CarrySSHPort keeps a non-default SSH port from oldURL when newURL points
+// at the same host over SSH without a port
+func CarrySSHPort(newURL, oldURL string) string {
+	oldParsed, err := ParseGitURL(oldURL)
+	if err != nil || !oldParsed.IsSSH() || oldParsed.Port == 0 {
+		return newURL
+	}
+	newParsed, err := ParseGitURL(newURL)
+	if err != nil || !newParsed.IsSSH() || newParsed.Port != 0 || !strings.EqualFold(newParsed.Host, oldParsed.Host) {
+		return newURL
+	}
+
+	newParsed.Port = oldParsed.Port
+	if newParsed.Scheme == SchemeSCP {
+		newParsed.Scheme = SchemeSSH
+		newParsed.Path = strings.TrimPrefix(newParsed.Path, "/")
+	}
+	return newParsed.String()
+}
@@ -0,0 +1,218 @@
+package git
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+// TestParseGitURL tests parsing of the supported URL forms
+func TestParseGitURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		scheme string
+		user   string
+		host   string
+		port   int
+		path   string
+	}{
+		{"git@github.com:user/repo.git", SchemeSCP, "git", "github.com", 0, "user/repo"},
+		{"deploy@git.company.com:team/app", SchemeSCP, "deploy", "git.company.com", 0, "team/app"},
+		{"ssh://git@gitlab.example.com:2222/group/sub/repo.git", SchemeSSH, "git", "gitlab.example.com", 2222, "group/sub/repo"},
+		{"ssh://gitlab.example.com/group/repo", SchemeSSH, "", "gitlab.example.com", 0, "group/repo"},
+		{"git+ssh://git@host/owner/repo.git", SchemeSSH, "git", "host", 0, "owner/repo"},
+		{"git://git.kernel.org/pub/scm/git/git.git", SchemeGit, "", "git.kernel.org", 0, "pub/scm/git/git"},
+		{"https://gitlab.com/group/sub/repo.git", SchemeHTTPS, "", "gitlab.com", 0, "group/sub/repo"},
+		{"https://user@git.company.com:8443/team/app.git", SchemeHTTPS, "user", "git.company.com", 8443, "team/app"},
+		{"http://[::1]:3000/owner/repo.git", SchemeHTTP, "", "::1", 3000, "owner/repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := ParseGitURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseGitURL(%s) failed: %v", tt.url, err)
+			}
+			if u.Scheme != tt.scheme || u.User != tt.user || u.Host != tt.host || u.Port != tt.port || u.RepoPath() != tt.path {
+				t.Errorf("ParseGitURL(%s) = %+v", tt.url, u)
+			}
+			if u.String() != tt.url && !strings.HasPrefix(tt.url, "git+ssh://") {
+				t.Errorf("String() = %s, expected %s", u.String(), tt.url)
+			}
+		})
+	}
+}
+
+// TestParseGitURLInvalid tests that malformed URLs are rejected
+func TestParseGitURLInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"not a url",
+		"github.com/user/repo",
+		"ftp://host/owner/repo.git",
+		"https://github.com",
+		"git@github.com:",
+	}
+
+	for _, raw := range invalid {
+		if _, err := ParseGitURL(raw); err == nil {
+			t.Errorf("ParseGitURL(%q) expected error", raw)
+		}
+	}
+}
+
+// TestParseRepoFromURLNamespaces tests that nested namespaces are kept
+func TestParseRepoFromURLNamespaces(t *testing.T) {
+	tests := []struct {
+		url   string
+		owner string
+		repo  string
+	}{
+		{"https://gitlab.com/group/sub/repo.git", "group/sub", "repo"},
+		{"git@gitlab.com:group/sub/deeper/repo.git", "group/sub/deeper", "repo"},
+		{"ssh://git@host:2222/group/sub/repo.git", "group/sub", "repo"},
+		{"git://git.kernel.org/pub/scm/git/git.git", "pub/scm/git", "git"},
+		{"https://github.com/user/repo", "user", "repo"},
+	}
+
+	for _, tt := range tests {
+		owner, repo, err := ParseRepoFromURL(tt.url)
+		if err != nil {
+			t.Errorf("ParseRepoFromURL(%s) failed: %v", tt.url, err)
+			continue
+		}
+		if owner != tt.owner || repo != tt.repo {
+			t.Errorf("ParseRepoFromURL(%s) = %s, %s, expected %s, %s", tt.url, owner, repo, tt.owner, tt.repo)
+		}
+	}
+}
+
+// TestBuildRemoteURLWithPort tests that a port in the domain switches SSH URLs to ssh://
+func TestBuildRemoteURLWithPort(t *testing.T) {
+	if got := BuildRemoteURL("gitlab", "git.company.com:2222", "group/sub/repo", true); got != "ssh://git@git.company.com:2222/group/sub/repo.git" {
+		t.Errorf("Unexpected SSH URL: %s", got)
+	}
+	if got := BuildRemoteURL("gitlab", "git.company.com:8443", "group/sub/repo", false); got != "https://git.company.com:8443/group/sub/repo.git" {
+		t.Errorf("Unexpected HTTPS URL: %s", got)
+	}
+}
+
+// TestCarrySSHPort tests keeping the SSH port of the current remote
+func TestCarrySSHPort(t *testing.T) {
+	old := "ssh://git@git.company.com:2222/group/repo.git"
+
+	if got := CarrySSHPort("git@git.company.com:group/repo.git", old); got != old {
+		t.Errorf("CarrySSHPort kept no port: %s", got)
+	}
+	if got := CarrySSHPort("git@github.com:group/repo.git", old); got != "git@github.com:group/repo.git" {
+		t.Errorf("CarrySSHPort changed a different host: %s", got)
+	}
+	if got := CarrySSHPort("https://git.company.com/group/repo.git", old); got != "https://git.company.com/group/repo.git" {
+		t.Errorf("CarrySSHPort changed an HTTPS URL: %s", got)
+	}
+}
+
+// genSegment generates a path segment or host label
+func genSegment() gopter.Gen {
+	return gen.RegexMatch(`^[a-z][a-z0-9_-]{0,11}$`)
+}
+
+// genRepoPath generates a repository path with 2 to 5 segments
+func genRepoPath() gopter.Gen {
+	return gen.SliceOfN(4, genSegment()).
+		FlatMap(func(v interface{}) gopter.Gen {
+			segments := v.([]string)
+			return gen.IntRange(2, len(segments)).Map(func(n int) string {
+				return strings.Join(segments[:n], "/")
+			})
+		}, nil)
+}
+
+// genGitURL generates GitURL values in any supported scheme
+func genGitURL() gopter.Gen {
+	return gopter.CombineGens(
+		gen.OneConstOf(SchemeSSH, SchemeSCP, SchemeGit, SchemeHTTP, SchemeHTTPS),
+		gen.OneGenOf(gen.Const(""), genSegment()),
+		gen.SliceOfN(2, genSegment()),
+		gen.OneGenOf(gen.Const(0), gen.IntRange(1, 65535)),
+		genRepoPath(),
+		gen.Bool(),
+	).Map(func(values []interface{}) *GitURL {
+		u := &GitURL{
+			Scheme:    values[0].(string),
+			User:      values[1].(string),
+			Host:      strings.Join(values[2].([]string), ".") + ".example",
+			Port:      values[3].(int),
+			Path:      values[4].(string),
+			GitSuffix: values[5].(bool),
+		}
+		if u.Scheme == SchemeSCP {
+			// scp-style URLs always have a user and never a port
+			if u.User == "" {
+				u.User = "git"
+			}
+			u.Port = 0
+		}
+		return u
+	})
+}
+
+// TestGitURLRoundTripProperty tests that formatting and parsing a URL is lossless
+func TestGitURLRoundTripProperty(t *testing.T) {
+	properties := gopter.NewProperties(nil)
+
+	properties.Property("ParseGitURL(u.String()) == u", prop.ForAll(
+		func(u *GitURL) bool {
+			parsed, err := ParseGitURL(u.String())
+			if err != nil {
+				return false
+			}
+			return *parsed == *u
+		},
+		genGitURL(),
+	))
+
+	properties.Property("ParseRepoFromURL keeps the full namespace", prop.ForAll(
+		func(u *GitURL) bool {
+			owner, repo, err := ParseRepoFromURL(u.String())
+			return err == nil && owner == u.Namespace() && repo == u.Name()
+		},
+		genGitURL(),
+	))
+
+	properties.TestingRun(t)
+}
+
+// TestBuildRemoteURLRoundTripProperty tests that built URLs parse back to the same repository
+func TestBuildRemoteURLRoundTripProperty(t *testing.T) {
+	properties := gopter.NewProperties(nil)
+
+	properties.Property("BuildRemoteURL round-trips through ParseGitURL", prop.ForAll(
+		func(repoPath string, port int, useSSH bool) bool {
+			domain := "git.example.com"
+			if port > 0 {
+				domain += ":" + strconv.Itoa(port)
+			}
+
+			built := BuildRemoteURL("gitlab", domain, repoPath, useSSH)
+			u, err := ParseGitURL(built)
+			if err != nil {
+				return false
+			}
+			return u.RepoPath() == repoPath &&
+				u.Host == "git.example.com" &&
+				u.Port == port &&
+				u.IsSSH() == useSSH &&
+				u.GitSuffix
+		},
+		genRepoPath(),
+		gen.OneGenOf(gen.Const(0), gen.IntRange(1, 65535)),
+		gen.Bool(),
+	))
+
+	properties.TestingRun(t)
+}
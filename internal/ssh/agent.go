@@ -0,0 +1,133 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dwirx/ghex/internal/platform"
+	"github.com/dwirx/ghex/internal/shell"
+)
+
+// AgentKey is an identity loaded in ssh-agent
+type AgentKey struct {
+	Bits        int
+	Fingerprint string
+	Comment     string
+	Type        string // e.g. ED25519, RSA
+}
+
+// IsAgentRunning reports whether an ssh-agent is reachable
+func IsAgentRunning() bool {
+	if os.Getenv("SSH_AUTH_SOCK") == "" && !platform.IsWindows() {
+		return false
+	}
+	_, err := shell.Exec("ssh-add", "-l")
+	// Exit code 1 means the agent is running but has no identities
+	return err == nil || shell.GetExitCode(err) == 1
+}
+
+// AddToAgent loads a key into ssh-agent, prompting for its passphrase if needed.
+// A zero lifetime keeps the key loaded until the agent exits.
+func AddToAgent(keyPath string, lifetime time.Duration) error {
+	if !IsAgentRunning() {
+		return fmt.Errorf("ssh-agent is not running (SSH_AUTH_SOCK is not set)")
+	}
+
+	args := []string{}
+	if lifetime > 0 {
+		args = append(args, "-t", strconv.Itoa(int(lifetime.Seconds())))
+	}
+	args = append(args, platform.ExpandPath(keyPath))
+
+	if err := shell.RunInteractive("ssh-add", args...); err != nil {
+		return fmt.Errorf("failed to add key to agent: %w", err)
+	}
+	return nil
+}
+
+// RemoveFromAgent unloads a key from ssh-agent
+func RemoveFromAgent(keyPath string) error {
+	keyPath = platform.ExpandPath(keyPath)
+
+	// ssh-add -d needs the public key next to the private key
+	if _, err := EnsurePublicKey(keyPath); err != nil {
+		return err
+	}
+
+	if output, err := shell.Exec("ssh-add", "-d", keyPath); err != nil {
+		return fmt.Errorf("failed to remove key from agent: %s", strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// ListAgentKeys returns the identities loaded in ssh-agent
+func ListAgentKeys() ([]AgentKey, error) {
+	output, err := shell.Exec("ssh-add", "-l")
+	if err != nil {
+		if shell.GetExitCode(err) == 1 {
+			// Agent running without identities
+			return []AgentKey{}, nil
+		}
+		return nil, fmt.Errorf("cannot reach ssh-agent: %s", strings.TrimSpace(output))
+	}
+	return parseAgentList(output), nil
+}
+
+// parseAgentList parses `ssh-add -l` output lines such as
+// "256 SHA256:abc... user@host (ED25519)"
+func parseAgentList(output string) []AgentKey {
+	var keys []AgentKey
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[1], "SHA256:") {
+			continue
+		}
+
+		bits, _ := strconv.Atoi(fields[0])
+		key := AgentKey{Bits: bits, Fingerprint: fields[1]}
+
+		rest := fields[2:]
+		if last := rest[len(rest)-1]; strings.HasPrefix(last, "(") && strings.HasSuffix(last, ")") {
+			key.Type = strings.Trim(last, "()")
+			rest = rest[:len(rest)-1]
+		}
+		key.Comment = strings.Join(rest, " ")
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// KeyFingerprint returns the SHA256 fingerprint of a private key's public half
+func KeyFingerprint(keyPath string) (string, error) {
+	pubPath := platform.ExpandPath(keyPath) + ".pub"
+	if !platform.FileExists(pubPath) {
+		// Deriving the public key from an encrypted private key would prompt
+		return "", fmt.Errorf("public key not found: %s", pubPath)
+	}
+	key, err := ReadPublicKey(pubPath)
+	if err != nil {
+		return "", err
+	}
+	return key.Fingerprint(), nil
+}
+
+// IsKeyInAgent reports whether the key is currently loaded in ssh-agent
+func IsKeyInAgent(keyPath string) bool {
+	fingerprint, err := KeyFingerprint(keyPath)
+	if err != nil {
+		return false
+	}
+	keys, err := ListAgentKeys()
+	if err != nil {
+		return false
+	}
+	for _, k := range keys {
+		if k.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
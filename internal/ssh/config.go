@@ -2,10 +2,8 @@ package ssh
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
-	"regexp"
-	"strings"
+	"strconv"
 
 	"github.com/dwirx/ghex/internal/platform"
 )
@@ -15,190 +13,80 @@ func GetSSHConfigPath() string {
 	return filepath.Join(platform.GetSSHDir(), "config")
 }
 
-// EnsureConfigBlock ensures an SSH Host block exists in the config file
-// If the block already exists, it updates it; otherwise, it appends a new block
+// HostOptions holds optional connection settings for a Host block
+type HostOptions struct {
+	User string // SSH user (default: git)
+	Port int    // SSH port (0 for default)
+}
+
+// EnsureConfigBlock ensures an SSH Host block exists in the ghex-managed config
+// file, which ~/.ssh/config pulls in via Include. If the block already exists,
+// it updates it; otherwise, it adds a new block
 func EnsureConfigBlock(alias, keyPath, hostname string) error {
+	return EnsureConfigBlockWithOptions(alias, keyPath, hostname, HostOptions{})
+}
+
+// EnsureConfigBlockWithOptions is like EnsureConfigBlock but allows a custom
+// SSH user and port, e.g. for self-hosted platforms on non-standard ports
+func EnsureConfigBlockWithOptions(alias, keyPath, hostname string, opts HostOptions) error {
 	if hostname == "" {
 		hostname = "github.com"
 	}
 
-	sshDir := platform.GetSSHDir()
-	configPath := GetSSHConfigPath()
-
 	// Ensure SSH directory exists with proper permissions
-	if err := platform.EnsureDir(sshDir, 0700); err != nil {
+	if err := platform.EnsureDir(platform.GetSSHDir(), 0700); err != nil {
 		return fmt.Errorf("failed to create SSH directory: %w", err)
 	}
 
-	// Read existing config
-	var content string
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("failed to read SSH config: %w", err)
-		}
-		content = ""
-	} else {
-		content = string(data)
-	}
-
-	// Build the new Host block
-	block := buildHostBlock(alias, keyPath, hostname)
+	return updateConfigs(func(user, managed *Config) {
+		managed.SetHost(alias, hostDirectives(keyPath, hostname, opts))
 
-	// Check if Host block already exists
-	if containsHostBlock(content, alias) {
-		// Update existing block
-		content = updateHostBlock(content, alias, block)
-	} else {
-		// Append new block
-		if content != "" && !strings.HasSuffix(content, "\n") {
-			content += "\n"
-		}
-		if content != "" {
-			content += "\n"
+		// Move blocks written by older versions out of ~/.ssh/config; others
+		// are left to LegacyHostCandidates, which asks first
+		if isLegacyGhexBlock(user.FindHost(alias), hostname, keyPath) {
+			user.RemoveHost(alias)
 		}
-		content += block + "\n"
-	}
-
-	// Write config file
-	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
-		return fmt.Errorf("failed to write SSH config: %w", err)
-	}
-
-	return nil
-}
-
-// buildHostBlock creates an SSH Host block string
-func buildHostBlock(alias, keyPath, hostname string) string {
-	return fmt.Sprintf(`Host %s
-  HostName %s
-  User git
-  IdentityFile %s
-  IdentitiesOnly yes`, alias, hostname, keyPath)
+	})
 }
 
-// containsHostBlock checks if a Host block exists in the config
-func containsHostBlock(content, alias string) bool {
-	pattern := fmt.Sprintf(`(?m)^Host\s+%s\s*$`, regexp.QuoteMeta(alias))
-	matched, _ := regexp.MatchString(pattern, content)
-	return matched
-}
-
-// updateHostBlock replaces an existing Host block with a new one
-func updateHostBlock(content, alias, newBlock string) string {
-	lines := strings.Split(content, "\n")
-	var result []string
-	inBlock := false
-	hostPattern := regexp.MustCompile(`^Host\s+`)
-	targetPattern := regexp.MustCompile(fmt.Sprintf(`^Host\s+%s\s*$`, regexp.QuoteMeta(alias)))
-
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-
-		if targetPattern.MatchString(line) {
-			// Found the target block, replace it
-			result = append(result, newBlock)
-			inBlock = true
-			continue
-		}
-
-		if inBlock {
-			// Check if we've reached the next Host block or end of block
-			if hostPattern.MatchString(line) {
-				inBlock = false
-				result = append(result, line)
-			}
-			// Skip lines in the old block
-			continue
-		}
-
-		result = append(result, line)
+// hostDirectives returns the directives of a ghex-managed Host block
+func hostDirectives(keyPath, hostname string, opts HostOptions) []Directive {
+	user := opts.User
+	if user == "" {
+		user = "git"
 	}
 
-	return strings.Join(result, "\n")
-}
-
-// RemoveHostBlock removes a Host block from the SSH config
-func RemoveHostBlock(alias string) error {
-	configPath := GetSSHConfigPath()
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // Nothing to remove
-		}
-		return fmt.Errorf("failed to read SSH config: %w", err)
-	}
-
-	content := string(data)
-	if !containsHostBlock(content, alias) {
-		return nil // Block doesn't exist
+	directives := []Directive{
+		{"HostName", hostname},
+		{"User", user},
 	}
-
-	// Remove the block
-	lines := strings.Split(content, "\n")
-	var result []string
-	inBlock := false
-	hostPattern := regexp.MustCompile(`^Host\s+`)
-	targetPattern := regexp.MustCompile(fmt.Sprintf(`^Host\s+%s\s*$`, regexp.QuoteMeta(alias)))
-
-	for _, line := range lines {
-		if targetPattern.MatchString(line) {
-			inBlock = true
-			continue
-		}
-
-		if inBlock {
-			if hostPattern.MatchString(line) {
-				inBlock = false
-				result = append(result, line)
-			}
-			continue
-		}
-
-		result = append(result, line)
+	if opts.Port > 0 && opts.Port != 22 {
+		directives = append(directives, Directive{"Port", strconv.Itoa(opts.Port)})
 	}
+	return append(directives,
+		Directive{"IdentityFile", keyPath},
+		Directive{"IdentitiesOnly", "yes"},
+	)
+}
 
-	// Clean up extra newlines
-	newContent := strings.TrimSpace(strings.Join(result, "\n")) + "\n"
-
-	return os.WriteFile(configPath, []byte(newContent), 0600)
+// RemoveHostBlock removes a Host block from the managed SSH config;
+// ~/.ssh/config is left to the user
+func RemoveHostBlock(alias string) error {
+	return updateConfigs(func(user, managed *Config) {
+		managed.RemoveHost(alias)
+	})
 }
 
-// GetHostBlock retrieves a Host block from the SSH config
+// GetHostBlock retrieves a Host block from the managed or user SSH config
 func GetHostBlock(alias string) (string, error) {
-	configPath := GetSSHConfigPath()
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return "", err
-	}
-
-	content := string(data)
-	lines := strings.Split(content, "\n")
-	var blockLines []string
-	inBlock := false
-	hostPattern := regexp.MustCompile(`^Host\s+`)
-	targetPattern := regexp.MustCompile(fmt.Sprintf(`^Host\s+%s\s*$`, regexp.QuoteMeta(alias)))
-
-	for _, line := range lines {
-		if targetPattern.MatchString(line) {
-			inBlock = true
-			blockLines = append(blockLines, line)
-			continue
+	for _, path := range []string{GetManagedConfigPath(), GetSSHConfigPath()} {
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			return "", err
 		}
-
-		if inBlock {
-			if hostPattern.MatchString(line) {
-				break
-			}
-			blockLines = append(blockLines, line)
+		if block := cfg.FindHost(alias); block != nil {
+			return block.String(), nil
 		}
 	}
-
-	if len(blockLines) == 0 {
-		return "", fmt.Errorf("host block not found: %s", alias)
-	}
-
-	return strings.Join(blockLines, "\n"), nil
+	return "", fmt.Errorf("host block not found: %s", alias)
 }
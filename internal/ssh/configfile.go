@@ -0,0 +1,720 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dwirx/ghex/internal/platform"
+)
+
+// Block kinds
+const (
+	BlockGlobal = "" // directives before the first Host/Match line
+	BlockHost   = "host"
+	BlockMatch  = "match"
+)
+
+// maxIncludeDepth mirrors OpenSSH's READCONF_MAX_DEPTH
+const maxIncludeDepth = 16
+
+// ConfigLine is a single line of an SSH config file. Unmodified lines are
+// written back exactly as they were read.
+type ConfigLine struct {
+	Raw     string
+	Keyword string   // lowercased keyword, empty for blank lines and comments
+	Args    []string // unquoted arguments
+}
+
+// IsDirective reports whether the line carries a keyword
+func (l *ConfigLine) IsDirective() bool {
+	return l.Keyword != ""
+}
+
+// ConfigBlock is a Host or Match section, or the global section at the top
+type ConfigBlock struct {
+	Kind    string
+	Leading []*ConfigLine // comments directly above the header
+	Header  *ConfigLine   // nil for the global section
+	Lines   []*ConfigLine // directives, comments and blank lines in the body
+}
+
+// Patterns returns the Host patterns or Match criteria of the block
+func (b *ConfigBlock) Patterns() []string {
+	if b.Header == nil {
+		return nil
+	}
+	return b.Header.Args
+}
+
+// Directives returns the body lines that carry a keyword
+func (b *ConfigBlock) Directives() []*ConfigLine {
+	var lines []*ConfigLine
+	for _, l := range b.Lines {
+		if l.IsDirective() {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// Get returns the first value of a keyword in the block body
+func (b *ConfigBlock) Get(keyword string) string {
+	keyword = strings.ToLower(keyword)
+	for _, l := range b.Lines {
+		if l.Keyword == keyword && len(l.Args) > 0 {
+			return l.Args[0]
+		}
+	}
+	return ""
+}
+
+// String renders the block as it appears in the file
+func (b *ConfigBlock) String() string {
+	var lines []string
+	for _, l := range b.allLines() {
+		lines = append(lines, l.Raw)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// allLines returns leading comments, header and body in file order
+func (b *ConfigBlock) allLines() []*ConfigLine {
+	lines := append([]*ConfigLine{}, b.Leading...)
+	if b.Header != nil {
+		lines = append(lines, b.Header)
+	}
+	return append(lines, b.Lines...)
+}
+
+// hasPattern reports whether the Host header lists the exact pattern
+func (b *ConfigBlock) hasPattern(pattern string) bool {
+	if b.Kind != BlockHost {
+		return false
+	}
+	for _, p := range b.Header.Args {
+		if p == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// isCatchAll reports whether the block applies to every host
+func (b *ConfigBlock) isCatchAll() bool {
+	switch b.Kind {
+	case BlockHost:
+		return len(b.Header.Args) == 1 && b.Header.Args[0] == "*"
+	case BlockMatch:
+		return len(b.Header.Args) == 1 && strings.EqualFold(b.Header.Args[0], "all")
+	}
+	return false
+}
+
+// Config is a parsed SSH config file
+type Config struct {
+	Path    string
+	Blocks  []*ConfigBlock // Blocks[0] is always the global section
+	newline string
+	trailer bool // file ends with a newline
+}
+
+// LoadConfig parses an SSH config file; a missing file yields an empty config
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			cfg := ParseConfig("")
+			cfg.Path = path
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read SSH config: %w", err)
+	}
+	cfg := ParseConfig(string(data))
+	cfg.Path = path
+	return cfg, nil
+}
+
+// ParseConfig parses SSH config content
+func ParseConfig(content string) *Config {
+	cfg := &Config{newline: "\n", trailer: true}
+	if strings.Contains(content, "\r\n") {
+		cfg.newline = "\r\n"
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+
+	current := &ConfigBlock{Kind: BlockGlobal}
+	cfg.Blocks = []*ConfigBlock{current}
+
+	if content == "" {
+		return cfg
+	}
+	cfg.trailer = strings.HasSuffix(content, "\n")
+	content = strings.TrimSuffix(content, "\n")
+
+	for _, raw := range strings.Split(content, "\n") {
+		line := parseLine(raw)
+		if line.Keyword != BlockHost && line.Keyword != BlockMatch {
+			current.Lines = append(current.Lines, line)
+			continue
+		}
+
+		next := &ConfigBlock{Kind: line.Keyword, Header: line}
+		next.Leading, current.Lines = splitLeadingComments(current.Lines)
+		cfg.Blocks = append(cfg.Blocks, next)
+		current = next
+	}
+	return cfg
+}
+
+// splitLeadingComments moves the comment lines directly above a header out of
+// the previous block, so they stay with the block they describe
+func splitLeadingComments(lines []*ConfigLine) (leading, rest []*ConfigLine) {
+	i := len(lines)
+	for i > 0 && isComment(lines[i-1].Raw) {
+		i--
+	}
+	return lines[i:], lines[:i]
+}
+
+// isComment reports whether a raw line is a comment
+func isComment(raw string) bool {
+	return strings.HasPrefix(strings.TrimSpace(raw), "#")
+}
+
+// parseLine splits a raw line into keyword and arguments
+func parseLine(raw string) *ConfigLine {
+	line := &ConfigLine{Raw: raw}
+	text := strings.TrimSpace(raw)
+	if text == "" || strings.HasPrefix(text, "#") {
+		return line
+	}
+
+	// Keyword and arguments are separated by whitespace and/or a single '='
+	end := strings.IndexAny(text, " \t=")
+	if end < 0 {
+		line.Keyword = strings.ToLower(text)
+		return line
+	}
+	line.Keyword = strings.ToLower(text[:end])
+	rest := strings.TrimLeft(text[end:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+	line.Args = splitArgs(rest)
+	return line
+}
+
+// splitArgs splits arguments on whitespace, honouring double quotes
+func splitArgs(s string) []string {
+	var args []string
+	var cur strings.Builder
+	inQuote, inArg := false, false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			inArg = true
+		case (r == ' ' || r == '\t') && !inQuote:
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// quoteArg quotes an argument containing whitespace
+func quoteArg(arg string) string {
+	if strings.ContainsAny(arg, " \t") {
+		return `"` + arg + `"`
+	}
+	return arg
+}
+
+// String renders the config, preserving untouched lines byte for byte
+func (c *Config) String() string {
+	var lines []string
+	for _, b := range c.Blocks {
+		for _, l := range b.allLines() {
+			lines = append(lines, l.Raw)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	out := strings.Join(lines, c.newline)
+	if c.trailer {
+		out += c.newline
+	}
+	return out
+}
+
+// Save writes the config back to its path
+func (c *Config) Save() error {
+	if err := os.WriteFile(c.Path, []byte(c.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write SSH config: %w", err)
+	}
+	return nil
+}
+
+// FindHost returns the Host block that lists alias as one of its patterns
+func (c *Config) FindHost(alias string) *ConfigBlock {
+	for _, b := range c.Blocks {
+		if b.hasPattern(alias) {
+			return b
+		}
+	}
+	return nil
+}
+
+// Hosts returns all Host blocks
+func (c *Config) Hosts() []*ConfigBlock {
+	var hosts []*ConfigBlock
+	for _, b := range c.Blocks {
+		if b.Kind == BlockHost {
+			hosts = append(hosts, b)
+		}
+	}
+	return hosts
+}
+
+// Includes reports whether a top-level Include directive pulls in path
+func (c *Config) Includes(path string) bool {
+	for _, l := range c.Blocks[0].Directives() {
+		if l.Keyword != "include" {
+			continue
+		}
+		for _, pattern := range l.Args {
+			pattern = platform.ExpandPath(pattern)
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(platform.GetSSHDir(), pattern)
+			}
+			if ok, _ := filepath.Match(pattern, path); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddInclude inserts an Include directive at the top of the file, where it
+// applies to all hosts
+func (c *Config) AddInclude(pattern string) {
+	lines := []*ConfigLine{parseLine("Include " + quoteArg(pattern))}
+	if len(c.String()) > 0 {
+		lines = append(lines, &ConfigLine{Raw: ""})
+	}
+	global := c.Blocks[0]
+	global.Lines = append(lines, global.Lines...)
+}
+
+// Directive is a keyword/value pair written into a Host block
+type Directive struct {
+	Keyword string
+	Value   string
+}
+
+// SetHost creates or updates the Host block for alias. Comments inside an
+// existing block are kept; if alias shares a block with other patterns it is
+// split out into its own block so the other hosts are left untouched.
+func (c *Config) SetHost(alias string, directives []Directive) {
+	if b := c.FindHost(alias); b != nil && len(b.Header.Args) == 1 {
+		indent := bodyIndent(b)
+		var kept []*ConfigLine
+		for _, l := range b.Lines {
+			if !l.IsDirective() {
+				kept = append(kept, l)
+			}
+		}
+		// Keep trailing blank lines after the new directives
+		split := len(kept)
+		for split > 0 && strings.TrimSpace(kept[split-1].Raw) == "" {
+			split--
+		}
+		lines := append([]*ConfigLine{}, kept[:split]...)
+		lines = append(lines, directiveLines(directives, indent)...)
+		b.Lines = append(lines, kept[split:]...)
+		return
+	}
+
+	block := &ConfigBlock{
+		Kind:   BlockHost,
+		Header: parseLine("Host " + quoteArg(alias)),
+		Lines:  directiveLines(directives, "  "),
+	}
+
+	pos := len(c.Blocks)
+	if b := c.FindHost(alias); b != nil {
+		c.removePattern(b, alias)
+		pos = c.indexOf(b)
+	} else {
+		// Specific hosts must come before catch-all sections, since the
+		// first obtained value of each option wins
+		for i, b := range c.Blocks {
+			if b.isCatchAll() {
+				pos = i
+				break
+			}
+		}
+	}
+
+	// Separate the new block from its neighbours with blank lines
+	if prev := c.Blocks[pos-1]; len(prev.allLines()) > 0 {
+		if last := prev.allLines()[len(prev.allLines())-1]; strings.TrimSpace(last.Raw) != "" {
+			prev.Lines = append(prev.Lines, &ConfigLine{Raw: ""})
+		}
+	}
+	if pos < len(c.Blocks) {
+		block.Lines = append(block.Lines, &ConfigLine{Raw: ""})
+	}
+
+	c.Blocks = append(c.Blocks[:pos], append([]*ConfigBlock{block}, c.Blocks[pos:]...)...)
+}
+
+// RemoveHost removes alias from the config. A block listing only alias is
+// deleted, keeping the comments above it; otherwise alias is dropped from the
+// block's patterns. Returns false if alias was not found.
+func (c *Config) RemoveHost(alias string) bool {
+	b := c.FindHost(alias)
+	if b == nil {
+		return false
+	}
+	if len(b.Header.Args) > 1 {
+		c.removePattern(b, alias)
+		return true
+	}
+
+	i := c.indexOf(b)
+	c.Blocks = append(c.Blocks[:i], c.Blocks[i+1:]...)
+
+	// Collapse the blank lines left around the removed block
+	prev := c.Blocks[i-1]
+	prev.Lines = append(prev.Lines, b.Leading...)
+	for len(prev.Lines) > 0 && strings.TrimSpace(prev.Lines[len(prev.Lines)-1].Raw) == "" {
+		prev.Lines = prev.Lines[:len(prev.Lines)-1]
+	}
+	if i < len(c.Blocks) && len(prev.allLines()) > 0 {
+		prev.Lines = append(prev.Lines, &ConfigLine{Raw: ""})
+	}
+	return true
+}
+
+// removePattern drops one pattern from a multi-pattern Host header
+func (c *Config) removePattern(b *ConfigBlock, pattern string) {
+	var args []string
+	for _, p := range b.Header.Args {
+		if p != pattern {
+			args = append(args, quoteArg(p))
+		}
+	}
+	indent := b.Header.Raw[:len(b.Header.Raw)-len(strings.TrimLeft(b.Header.Raw, " \t"))]
+	keyword := strings.Fields(strings.TrimSpace(b.Header.Raw))[0]
+	b.Header = parseLine(indent + keyword + " " + strings.Join(args, " "))
+}
+
+// indexOf returns the position of a block
+func (c *Config) indexOf(block *ConfigBlock) int {
+	for i, b := range c.Blocks {
+		if b == block {
+			return i
+		}
+	}
+	return -1
+}
+
+// bodyIndent returns the indentation used by a block's directives
+func bodyIndent(b *ConfigBlock) string {
+	for _, l := range b.Lines {
+		if l.IsDirective() {
+			return l.Raw[:len(l.Raw)-len(strings.TrimLeft(l.Raw, " \t"))]
+		}
+	}
+	return "  "
+}
+
+// directiveLines renders directives as config lines
+func directiveLines(directives []Directive, indent string) []*ConfigLine {
+	lines := make([]*ConfigLine, len(directives))
+	for i, d := range directives {
+		lines[i] = parseLine(indent + d.Keyword + " " + quoteArg(d.Value))
+	}
+	return lines
+}
+
+// multiValueKeywords may be given several times, each adding a value
+var multiValueKeywords = map[string]bool{
+	"identityfile":    true,
+	"certificatefile": true,
+	"localforward":    true,
+	"remoteforward":   true,
+	"dynamicforward":  true,
+	"sendenv":         true,
+	"setenv":          true,
+}
+
+// ResolvedConfig holds the effective options for a host, like `ssh -G`
+type ResolvedConfig struct {
+	Host    string
+	Files   []string // config files that were read
+	options map[string][]string
+}
+
+// Get returns the effective value of a keyword
+func (r *ResolvedConfig) Get(keyword string) string {
+	if values := r.options[strings.ToLower(keyword)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// GetAll returns all values of a multi-value keyword such as IdentityFile
+func (r *ResolvedConfig) GetAll(keyword string) []string {
+	return r.options[strings.ToLower(keyword)]
+}
+
+// HostName returns the real host name, defaulting to the host itself
+func (r *ResolvedConfig) HostName() string {
+	if h := r.Get("hostname"); h != "" {
+		return strings.ReplaceAll(h, "%h", r.Host)
+	}
+	return r.Host
+}
+
+// User returns the login user, or empty if not configured
+func (r *ResolvedConfig) User() string {
+	return r.Get("user")
+}
+
+// Port returns the port, defaulting to 22
+func (r *ResolvedConfig) Port() string {
+	if p := r.Get("port"); p != "" {
+		return p
+	}
+	return "22"
+}
+
+// IdentityFiles returns the identity files with ~ and tokens expanded
+func (r *ResolvedConfig) IdentityFiles() []string {
+	var files []string
+	for _, f := range r.GetAll("identityfile") {
+		if strings.EqualFold(f, "none") {
+			continue
+		}
+		files = append(files, r.expandTokens(f))
+	}
+	return files
+}
+
+// expandTokens expands the % tokens OpenSSH supports in IdentityFile
+func (r *ResolvedConfig) expandTokens(s string) string {
+	replacer := strings.NewReplacer(
+		"%%", "%",
+		"%d", platform.GetHomeDir(),
+		"%h", r.HostName(),
+		"%n", r.Host,
+		"%p", r.Port(),
+		"%r", r.User(),
+		"%u", localUser(),
+	)
+	return platform.ExpandPath(replacer.Replace(s))
+}
+
+// Resolve computes the effective options for host from the user's SSH config
+func Resolve(host string) (*ResolvedConfig, error) {
+	cfg, err := LoadConfig(GetSSHConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Resolve(host)
+}
+
+// Resolve computes the effective options for host, following Include
+// directives. As in OpenSSH, the first value obtained for an option wins.
+func (c *Config) Resolve(host string) (*ResolvedConfig, error) {
+	r := &ResolvedConfig{Host: host, options: make(map[string][]string)}
+	if err := c.apply(r, 0); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// apply evaluates the config's blocks against r. Files are only included
+// from matching blocks, so their global section always applies.
+func (c *Config) apply(r *ResolvedConfig, depth int) error {
+	if c.Path != "" {
+		r.Files = append(r.Files, c.Path)
+	}
+
+	for _, b := range c.Blocks {
+		active := true
+		switch b.Kind {
+		case BlockHost:
+			active = matchHostPatterns(r.Host, b.Header.Args)
+		case BlockMatch:
+			active = matchCriteria(r, b.Header.Args)
+		}
+		if !active {
+			continue
+		}
+
+		for _, l := range b.Directives() {
+			if l.Keyword == "include" {
+				if err := c.include(r, l.Args, depth); err != nil {
+					return err
+				}
+				continue
+			}
+			if len(l.Args) == 0 {
+				continue
+			}
+			if multiValueKeywords[l.Keyword] {
+				r.options[l.Keyword] = append(r.options[l.Keyword], strings.Join(l.Args, " "))
+			} else if _, ok := r.options[l.Keyword]; !ok {
+				r.options[l.Keyword] = []string{strings.Join(l.Args, " ")}
+			}
+		}
+	}
+	return nil
+}
+
+// include reads and applies the files named by an Include directive
+func (c *Config) include(r *ResolvedConfig, patterns []string, depth int) error {
+	if depth >= maxIncludeDepth {
+		return fmt.Errorf("SSH config Include nested too deeply")
+	}
+
+	for _, pattern := range patterns {
+		pattern = platform.ExpandPath(pattern)
+		if !filepath.IsAbs(pattern) {
+			// Relative paths are relative to ~/.ssh for the user config
+			pattern = filepath.Join(platform.GetSSHDir(), pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid Include pattern %q: %w", pattern, err)
+		}
+		sort.Strings(matches)
+
+		for _, path := range matches {
+			included, err := LoadConfig(path)
+			if err != nil {
+				return err
+			}
+			if err := included.apply(r, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchHostPatterns applies Host pattern semantics: any positive match and
+// no negated match
+func matchHostPatterns(host string, patterns []string) bool {
+	matched := false
+	for _, p := range patterns {
+		if neg := strings.HasPrefix(p, "!"); neg {
+			if matchPattern(strings.ToLower(p[1:]), strings.ToLower(host)) {
+				return false
+			}
+			continue
+		}
+		if matchPattern(strings.ToLower(p), strings.ToLower(host)) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchPatternList matches a comma-separated pattern list, as used by Match
+func matchPatternList(value, list string) bool {
+	return matchHostPatterns(value, strings.Split(list, ","))
+}
+
+// matchCriteria evaluates the criteria of a Match line. A criterion ghex
+// cannot evaluate (exec, canonical or an unknown one) makes the block never
+// match, even when negated, so no directive ssh might skip is applied.
+func matchCriteria(r *ResolvedConfig, args []string) bool {
+	for i := 0; i < len(args); i++ {
+		criterion := strings.ToLower(args[i])
+		negate := strings.HasPrefix(criterion, "!")
+		criterion = strings.TrimPrefix(criterion, "!")
+
+		var result bool
+		switch criterion {
+		case "all", "final":
+			result = true
+		case "host", "originalhost", "user", "localuser":
+			if i+1 >= len(args) {
+				return false
+			}
+			i++
+			value := args[i]
+			switch criterion {
+			case "host":
+				result = matchPatternList(r.HostName(), value)
+			case "originalhost":
+				result = matchPatternList(r.Host, value)
+			case "user":
+				result = matchPatternList(r.User(), value)
+			case "localuser":
+				result = matchPatternList(localUser(), value)
+			}
+		default:
+			return false
+		}
+
+		if result == negate {
+			return false
+		}
+	}
+	return len(args) > 0
+}
+
+// matchPattern matches s against a pattern with '*' and '?' wildcards
+func matchPattern(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = pattern[1:]
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchPattern(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return s == ""
+}
+
+// localUser returns the current OS user name
+func localUser() string {
+	if u := os.Getenv("USER"); u != "" {
+		return u
+	}
+	return os.Getenv("USERNAME")
+}
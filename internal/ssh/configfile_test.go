@@ -0,0 +1,245 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleConfig = `# Global settings
+Include ~/.ssh/extra/*
+AddKeysToAgent yes
+
+Host github.com
+  HostName github.com
+  User git
+  # ghex-managed key
+  IdentityFile ~/.ssh/id_github
+
+# Work hosts
+Host work gitlab.work
+	HostName gitlab.work.example
+	IdentityFile "~/.ssh/id work"
+
+Match host *.internal exec "test -f /tmp/vpn"
+  ProxyJump bastion
+
+Host *
+  ServerAliveInterval=60
+`
+
+// TestParseConfigRoundTrip tests that unmodified configs are written back unchanged
+func TestParseConfigRoundTrip(t *testing.T) {
+	inputs := []string{
+		sampleConfig,
+		"",
+		"Host a\n  User x",                    // no trailing newline
+		"Host a\r\n  User x\r\n\r\n# end\r\n", // CRLF
+	}
+	for _, in := range inputs {
+		if out := ParseConfig(in).String(); out != in {
+			t.Errorf("round trip changed config:\n%q\n%q", in, out)
+		}
+	}
+}
+
+// TestParseConfigStructure tests blocks, leading comments and arguments
+func TestParseConfigStructure(t *testing.T) {
+	cfg := ParseConfig(sampleConfig)
+
+	if len(cfg.Blocks) != 5 {
+		t.Fatalf("Expected 5 blocks, got %d", len(cfg.Blocks))
+	}
+	work := cfg.FindHost("gitlab.work")
+	if work == nil || work != cfg.FindHost("work") {
+		t.Fatal("Expected multi-pattern Host block to be found by each pattern")
+	}
+	if len(work.Leading) != 1 || work.Leading[0].Raw != "# Work hosts" {
+		t.Errorf("Expected leading comment on work block, got %v", work.Leading)
+	}
+	if got := work.Get("IdentityFile"); got != "~/.ssh/id work" {
+		t.Errorf("Expected quoted IdentityFile, got %q", got)
+	}
+	if got := cfg.Blocks[4].Get("serveraliveinterval"); got != "60" {
+		t.Errorf("Expected Keyword=value syntax to parse, got %q", got)
+	}
+	if cfg.Blocks[3].Kind != BlockMatch {
+		t.Errorf("Expected Match block, got %q", cfg.Blocks[3].Kind)
+	}
+}
+
+// TestSetHostUpdate tests that updating a block keeps neighbouring sections
+func TestSetHostUpdate(t *testing.T) {
+	cfg := ParseConfig(sampleConfig)
+	cfg.SetHost("github.com", hostDirectives("~/.ssh/id_new", "github.com", HostOptions{}))
+	out := cfg.String()
+
+	expected := `Host github.com
+  # ghex-managed key
+  HostName github.com
+  User git
+  IdentityFile ~/.ssh/id_new
+  IdentitiesOnly yes
+
+# Work hosts`
+	if !strings.Contains(out, expected) {
+		t.Errorf("Unexpected updated block:\n%s", out)
+	}
+	for _, keep := range []string{"Match host *.internal", "  ProxyJump bastion", "Include ~/.ssh/extra/*", "Host *\n"} {
+		if !strings.Contains(out, keep) {
+			t.Errorf("Expected %q to be preserved:\n%s", keep, out)
+		}
+	}
+}
+
+// TestSetHostMultiPattern tests that a shared Host block is split, not replaced
+func TestSetHostMultiPattern(t *testing.T) {
+	cfg := ParseConfig(sampleConfig)
+	cfg.SetHost("work", []Directive{{"HostName", "gitlab.work.example"}, {"IdentityFile", "~/.ssh/id_ghex"}})
+	out := cfg.String()
+
+	if !strings.Contains(out, "Host work\n  HostName gitlab.work.example\n  IdentityFile ~/.ssh/id_ghex\n\n# Work hosts\nHost gitlab.work\n") {
+		t.Errorf("Expected alias split into its own block:\n%s", out)
+	}
+	if !strings.Contains(out, "\tIdentityFile \"~/.ssh/id work\"") {
+		t.Errorf("Expected original block body to be kept:\n%s", out)
+	}
+}
+
+// TestSetHostNew tests that new hosts are placed before catch-all sections
+func TestSetHostNew(t *testing.T) {
+	cfg := ParseConfig(sampleConfig)
+	cfg.SetHost("gitlab.com", []Directive{{"User", "git"}})
+	out := cfg.String()
+
+	if !strings.Contains(out, "  ProxyJump bastion\n\nHost gitlab.com\n  User git\n\nHost *\n") {
+		t.Errorf("Expected new block before Host *:\n%s", out)
+	}
+
+	empty := ParseConfig("")
+	empty.SetHost("a", []Directive{{"User", "git"}})
+	empty.SetHost("b", []Directive{{"User", "git"}})
+	if got := empty.String(); got != "Host a\n  User git\n\nHost b\n  User git\n" {
+		t.Errorf("Unexpected new config: %q", got)
+	}
+}
+
+// TestRemoveHost tests removing blocks without touching the following sections
+func TestRemoveHost(t *testing.T) {
+	cfg := ParseConfig(sampleConfig)
+	if !cfg.RemoveHost("github.com") {
+		t.Fatal("Expected github.com to be removed")
+	}
+	out := cfg.String()
+	if strings.Contains(out, "id_github") {
+		t.Errorf("Expected block to be removed:\n%s", out)
+	}
+	if !strings.Contains(out, "AddKeysToAgent yes\n\n# Work hosts\nHost work gitlab.work\n") {
+		t.Errorf("Expected following block and its comment to be kept:\n%s", out)
+	}
+
+	if !cfg.RemoveHost("work") || !strings.Contains(cfg.String(), "Host gitlab.work\n") {
+		t.Errorf("Expected pattern to be dropped from shared block:\n%s", cfg.String())
+	}
+	if cfg.RemoveHost("missing") {
+		t.Error("Expected RemoveHost to report missing host")
+	}
+}
+
+// TestResolve tests option precedence, wildcards, Match and Include
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	included := filepath.Join(dir, "included.conf")
+	if err := os.WriteFile(included, []byte("Host *.example\n  User included\n  IdentityFile ~/.ssh/id_included\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := ParseConfig(`Include ` + included + `
+
+Host gh
+  HostName github.com
+  IdentityFile ~/.ssh/id_%h
+
+Host !skip.example *.example
+  Port 2222
+
+Match originalhost gh
+  User matched
+
+Host *
+  User default
+  Port 22
+  IdentityFile ~/.ssh/id_default
+`)
+
+	r, err := cfg.Resolve("gh")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if r.HostName() != "github.com" || r.User() != "matched" || r.Port() != "22" {
+		t.Errorf("Unexpected resolution for gh: %s@%s:%s", r.User(), r.HostName(), r.Port())
+	}
+	home, _ := os.UserHomeDir()
+	expected := []string{filepath.Join(home, ".ssh", "id_github.com"), filepath.Join(home, ".ssh", "id_default")}
+	if files := r.IdentityFiles(); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected identity files %v, got %v", expected, files)
+	}
+
+	r, _ = cfg.Resolve("git.example")
+	if r.Port() != "2222" || r.User() != "included" || len(r.Files) != 1 {
+		t.Errorf("Unexpected resolution for git.example: %s:%s %v", r.User(), r.Port(), r.Files)
+	}
+
+	r, _ = cfg.Resolve("skip.example")
+	if r.Port() != "22" || r.User() != "included" {
+		t.Errorf("Expected negated pattern to skip block, got port %s", r.Port())
+	}
+}
+
+// TestMatchPattern tests ssh wildcard matching
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		expected   bool
+	}{
+		{"*", "anything", true},
+		{"*.example", "git.example", true},
+		{"*.example", "example", false},
+		{"git?.com", "git1.com", true},
+		{"git?.com", "git.com", false},
+		{"a*b*c", "axxbyyc", true},
+		{"exact", "exact", true},
+	}
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.s); got != tt.expected {
+			t.Errorf("matchPattern(%q, %q) = %v, expected %v", tt.pattern, tt.s, got, tt.expected)
+		}
+	}
+}
+
+// TestMatchCriteria tests that criteria ghex can't evaluate never match
+func TestMatchCriteria(t *testing.T) {
+	r := &ResolvedConfig{Host: "gh", options: map[string][]string{"hostname": {"github.com"}}}
+	tests := []struct {
+		line     string
+		expected bool
+	}{
+		{"all", true},
+		{"host github.com", true},
+		{"!host gitlab.com", true},
+		{"originalhost gh host *.com", true},
+		{"host gitlab.com", false},
+		{`exec "true"`, false},
+		{`!exec "false"`, false},
+		{"!canonical", false},
+		{"host github.com !exec x", false},
+		{"tagged work", false},
+	}
+	for _, tt := range tests {
+		if got := matchCriteria(r, splitArgs(tt.line)); got != tt.expected {
+			t.Errorf("Match %s = %v, expected %v", tt.line, got, tt.expected)
+		}
+	}
+}
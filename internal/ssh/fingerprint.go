@@ -0,0 +1,66 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dwirx/ghex/internal/platform"
+)
+
+// PublicKey is a parsed authorized_keys style public key line
+type PublicKey struct {
+	Type    string // e.g. ssh-ed25519, ssh-rsa
+	Blob    []byte // decoded key data
+	Comment string
+}
+
+// ParsePublicKey parses a public key line such as "ssh-ed25519 AAAA... user@host"
+func ParsePublicKey(line string) (*PublicKey, error) {
+	fields := strings.Fields(strings.TrimSpace(line))
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid public key format")
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key data: %w", err)
+	}
+
+	return &PublicKey{
+		Type:    fields[0],
+		Blob:    blob,
+		Comment: strings.Join(fields[2:], " "),
+	}, nil
+}
+
+// Fingerprint returns the SHA256 fingerprint in the format used by ssh-keygen -l
+func (k *PublicKey) Fingerprint() string {
+	sum := sha256.Sum256(k.Blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// Authorized returns the key as "type base64" without the comment
+func (k *PublicKey) Authorized() string {
+	return k.Type + " " + base64.StdEncoding.EncodeToString(k.Blob)
+}
+
+// Fingerprint returns the SHA256 fingerprint of a public key line
+func Fingerprint(publicKey string) (string, error) {
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return key.Fingerprint(), nil
+}
+
+// ReadPublicKey reads and parses a public key file
+func ReadPublicKey(pubPath string) (*PublicKey, error) {
+	data, err := os.ReadFile(platform.ExpandPath(pubPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	return ParsePublicKey(string(data))
+}
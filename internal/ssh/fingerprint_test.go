@@ -0,0 +1,43 @@
+package ssh
+
+import "testing"
+
+const testPublicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMrDP6Fc254OR/75BAFP7osbj3sNdNByR3mucXRJZZLL test@example"
+
+// TestFingerprint tests that fingerprints match ssh-keygen -l output
+func TestFingerprint(t *testing.T) {
+	fp, err := Fingerprint(testPublicKey)
+	if err != nil {
+		t.Fatalf("Fingerprint failed: %v", err)
+	}
+	if fp != "SHA256:AMgHu1JDqctT6O6ATdrT0WGW69TfSVJjjZ5jbM/TKnI" {
+		t.Errorf("Unexpected fingerprint: %s", fp)
+	}
+
+	// The comment does not affect the fingerprint
+	other, _ := Fingerprint("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMrDP6Fc254OR/75BAFP7osbj3sNdNByR3mucXRJZZLL")
+	if other != fp {
+		t.Errorf("Expected same fingerprint without comment, got %s", other)
+	}
+}
+
+// TestParsePublicKey tests parsing public key lines
+func TestParsePublicKey(t *testing.T) {
+	key, err := ParsePublicKey(testPublicKey + "\n")
+	if err != nil {
+		t.Fatalf("ParsePublicKey failed: %v", err)
+	}
+	if key.Type != "ssh-ed25519" || key.Comment != "test@example" {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+	if key.Authorized()+" test@example" != testPublicKey {
+		t.Errorf("Unexpected authorized form: %s", key.Authorized())
+	}
+
+	invalid := []string{"", "ssh-ed25519", "ssh-ed25519 not-base64!"}
+	for _, line := range invalid {
+		if _, err := ParsePublicKey(line); err == nil {
+			t.Errorf("ParsePublicKey(%q) expected error", line)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dwirx/ghex/internal/platform"
+)
+
+// Public key file states
+const (
+	PubKeyMatch    = "match"    // .pub holds the private key's public half
+	PubKeyMismatch = "mismatch" // .pub belongs to another key
+	PubKeyMissing  = "missing"  // no .pub file
+	PubKeyUnknown  = "unknown"  // the private key is encrypted PEM and can't be compared
+)
+
+// Inventory sort orders
+const (
+	SortByName        = "name"
+	SortByType        = "type"
+	SortByBits        = "bits"
+	SortByModified    = "modified"
+	SortByFingerprint = "fingerprint"
+)
+
+// KeyInfo describes a private key file
+type KeyInfo struct {
+	Path            string    `json:"path"`
+	Type            string    `json:"type"`
+	Bits            int       `json:"bits"`
+	Fingerprint     string    `json:"fingerprint"`
+	Comment         string    `json:"comment,omitempty"`
+	Encrypted       bool      `json:"encrypted"`
+	PublicKeyStatus string    `json:"publicKey"`
+	Modified        time.Time `json:"modified"`
+	Accounts        []string  `json:"accounts"`
+	Orphaned        bool      `json:"orphaned"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// KeyReference is an account's use of a key path
+type KeyReference struct {
+	Account string `json:"account"`
+	KeyPath string `json:"keyPath"`
+}
+
+// Inventory is the set of keys on disk and how accounts use them
+type Inventory struct {
+	Keys    []KeyInfo      `json:"keys"`
+	Missing []KeyReference `json:"missing"` // accounts pointing to keys that don't exist
+}
+
+// SortOrders returns the supported inventory sort orders
+func SortOrders() []string {
+	return []string{SortByName, SortByType, SortByBits, SortByModified, SortByFingerprint}
+}
+
+// BuildInventory inspects the keys in ~/.ssh plus any key referenced by an
+// account, and links them to the accounts
+func BuildInventory(refs []KeyReference) (*Inventory, error) {
+	paths, err := ListPrivateKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &Inventory{Keys: []KeyInfo{}, Missing: []KeyReference{}}
+	index := make(map[string]int)
+	add := func(path string) {
+		if _, ok := index[cleanKeyPath(path)]; ok {
+			return
+		}
+		index[cleanKeyPath(path)] = len(inv.Keys)
+		inv.Keys = append(inv.Keys, InspectKey(path))
+	}
+
+	for _, path := range paths {
+		add(path)
+	}
+
+	for _, ref := range refs {
+		path := platform.ExpandPath(ref.KeyPath)
+		if !platform.FileExists(path) {
+			inv.Missing = append(inv.Missing, ref)
+			continue
+		}
+		add(path) // Keys outside ~/.ssh
+		i := index[cleanKeyPath(path)]
+		inv.Keys[i].Accounts = append(inv.Keys[i].Accounts, ref.Account)
+	}
+
+	for i := range inv.Keys {
+		if inv.Keys[i].Accounts == nil {
+			inv.Keys[i].Accounts = []string{}
+		}
+		inv.Keys[i].Orphaned = len(inv.Keys[i].Accounts) == 0
+	}
+
+	SortKeys(inv.Keys, SortByName)
+	return inv, nil
+}
+
+// cleanKeyPath normalises a key path for comparison
+func cleanKeyPath(path string) string {
+	path = filepath.Clean(platform.ExpandPath(path))
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// SortKeys sorts keys in place
+func SortKeys(keys []KeyInfo, by string) error {
+	var less func(a, b KeyInfo) bool
+	switch by {
+	case "", SortByName:
+		less = func(a, b KeyInfo) bool { return a.Path < b.Path }
+	case SortByType:
+		less = func(a, b KeyInfo) bool { return a.Type < b.Type }
+	case SortByBits:
+		less = func(a, b KeyInfo) bool { return a.Bits > b.Bits }
+	case SortByModified:
+		less = func(a, b KeyInfo) bool { return a.Modified.After(b.Modified) }
+	case SortByFingerprint:
+		less = func(a, b KeyInfo) bool { return a.Fingerprint < b.Fingerprint }
+	default:
+		return fmt.Errorf("unknown sort order %q (use %s)", by, strings.Join(SortOrders(), ", "))
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return nil
+}
+
+// InspectKey reads a private key and its .pub file. Problems are reported in
+// KeyInfo.Error rather than failing, so one bad file doesn't hide the rest.
+func InspectKey(path string) KeyInfo {
+	info := KeyInfo{Path: path}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Modified = stat.ModTime()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Encrypted = isKeyDataEncrypted(data)
+
+	blob, err := privateKeyPublicBlob(data)
+	if err != nil {
+		info.Error = err.Error()
+	}
+
+	pub, pubErr := ReadPublicKey(path + ".pub")
+	switch {
+	case pubErr != nil && !platform.FileExists(path+".pub"):
+		info.PublicKeyStatus = PubKeyMissing
+	case pubErr != nil:
+		info.PublicKeyStatus = PubKeyMismatch
+	case blob == nil:
+		info.PublicKeyStatus = PubKeyUnknown
+	case bytes.Equal(blob, pub.Blob):
+		info.PublicKeyStatus = PubKeyMatch
+	default:
+		info.PublicKeyStatus = PubKeyMismatch
+	}
+
+	if blob == nil && pub != nil {
+		blob = pub.Blob // Encrypted PEM keys: trust the .pub file
+	}
+	if pub != nil {
+		info.Comment = pub.Comment
+	}
+	if blob != nil {
+		info.Type, info.Bits = publicKeyTypeAndBits(blob)
+		info.Fingerprint = (&PublicKey{Blob: blob}).Fingerprint()
+		info.Error = ""
+	}
+	return info
+}
+
+// privateKeyPublicBlob returns the SSH wire-format public key of a private key
+// file. It returns nil without error for encrypted PEM keys.
+func privateKeyPublicBlob(data []byte) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("not a PEM encoded private key")
+	}
+
+	if block.Type == "OPENSSH PRIVATE KEY" {
+		// The public keys are stored unencrypted in the header
+		raw := block.Bytes
+		if !bytes.HasPrefix(raw, []byte(openSSHMagic)) {
+			return nil, fmt.Errorf("invalid OpenSSH private key")
+		}
+		rest := raw[len(openSSHMagic):]
+		var ok bool
+		for i := 0; i < 3; i++ { // cipher, kdf, kdf options
+			if _, rest, ok = readSSHString(rest); !ok {
+				return nil, fmt.Errorf("invalid OpenSSH private key")
+			}
+		}
+		if len(rest) < 4 || binary.BigEndian.Uint32(rest) < 1 {
+			return nil, fmt.Errorf("OpenSSH private key holds no keys")
+		}
+		blob, _, ok := readSSHString(rest[4:])
+		if !ok {
+			return nil, fmt.Errorf("invalid OpenSSH private key")
+		}
+		return blob, nil
+	}
+
+	if strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") || block.Type == "ENCRYPTED PRIVATE KEY" {
+		return nil, nil
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported key format %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return marshalPublicKey(key)
+}
+
+// openSSHMagic starts every OpenSSH private key
+const openSSHMagic = "openssh-key-v1\x00"
+
+// marshalPublicKey encodes a private key's public half in SSH wire format
+func marshalPublicKey(key interface{}) ([]byte, error) {
+	var b []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		b = appendSSHString(b, []byte("ssh-rsa"))
+		b = appendSSHMPInt(b, big.NewInt(int64(k.E)))
+		b = appendSSHMPInt(b, k.N)
+	case *ecdsa.PrivateKey:
+		curve := "nistp" + fmt.Sprint(k.Curve.Params().BitSize)
+		point, err := k.PublicKey.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		b = appendSSHString(b, []byte("ecdsa-sha2-"+curve))
+		b = appendSSHString(b, []byte(curve))
+		b = appendSSHString(b, point.Bytes())
+	case ed25519.PrivateKey:
+		b = appendSSHString(b, []byte("ssh-ed25519"))
+		b = appendSSHString(b, k.Public().(ed25519.PublicKey))
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return b, nil
+}
+
+// publicKeyTypeAndBits reads the key type and size from a wire-format blob
+func publicKeyTypeAndBits(blob []byte) (string, int) {
+	keyType, rest, ok := readSSHString(blob)
+	if !ok {
+		return "", 0
+	}
+
+	switch t := string(keyType); {
+	case t == "ssh-rsa":
+		_, rest, _ = readSSHString(rest) // e
+		n, _, _ := readSSHString(rest)
+		return t, new(big.Int).SetBytes(n).BitLen()
+	case t == "ssh-dss":
+		p, _, _ := readSSHString(rest)
+		return t, new(big.Int).SetBytes(p).BitLen()
+	case strings.Contains(t, "ed25519"):
+		return t, 256
+	case strings.Contains(t, "nistp521"):
+		return t, 521
+	case strings.Contains(t, "nistp384"):
+		return t, 384
+	case strings.Contains(t, "nistp256"):
+		return t, 256
+	default:
+		return t, 0
+	}
+}
+
+// readSSHString reads a uint32 length-prefixed string
+func readSSHString(b []byte) ([]byte, []byte, bool) {
+	if len(b) < 4 {
+		return nil, nil, false
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return nil, nil, false
+	}
+	return b[4 : 4+n], b[4+n:], true
+}
+
+// appendSSHString appends a uint32 length-prefixed string
+func appendSSHString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// appendSSHMPInt appends a positive big integer in SSH mpint format
+func appendSSHMPInt(b []byte, n *big.Int) []byte {
+	raw := n.Bytes()
+	if len(raw) > 0 && raw[0]&0x80 != 0 {
+		raw = append([]byte{0}, raw...)
+	}
+	return appendSSHString(b, raw)
+}
@@ -0,0 +1,440 @@
+package ssh
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dwirx/ghex/internal/platform"
+	"github.com/dwirx/ghex/internal/shell"
+)
+
+// ErrHostKeyMismatch means a host presented a key that contradicts its pinned fingerprints
+var ErrHostKeyMismatch = errors.New("host key does not match pinned fingerprint")
+
+// HostKeyPin is a trusted host key fingerprint. An empty Type pins the
+// fingerprint without saying which key type it belongs to.
+type HostKeyPin struct {
+	Type        string // e.g. ssh-ed25519
+	Fingerprint string // SHA256:...
+}
+
+// publishedHostKeys are the host key fingerprints published by the platforms
+var publishedHostKeys = map[string][]HostKeyPin{
+	// https://docs.github.com/en/authentication/keeping-your-account-and-data-secure/githubs-ssh-key-fingerprints
+	"github.com": {
+		{"ssh-ed25519", "SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"},
+		{"ecdsa-sha2-nistp256", "SHA256:p2QAMXNIC1TJYWeIOttrVc98/R1BUFWu3/LiyKgUfQM"},
+		{"ssh-rsa", "SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s"},
+	},
+	// https://docs.gitlab.com/ee/user/gitlab_com/#ssh-host-keys-fingerprints
+	"gitlab.com": {
+		{"ssh-ed25519", "SHA256:eUXGGm1YGsMAS7vkcx6JOJdOGHPem5gQp4taiCfCLB8"},
+		{"ecdsa-sha2-nistp256", "SHA256:HbW3g8zUjNSksFbqTiUWPWg2Bq1x8xdGUrliXFzSnUw"},
+		{"ssh-rsa", "SHA256:ROQFvPThGrW4RuWLoL9tq9I9zJ42fK4XywyRtbOz/EQ"},
+	},
+	// https://support.atlassian.com/bitbucket-cloud/docs/configure-ssh-and-two-step-verification/
+	"bitbucket.org": {
+		{"ssh-ed25519", "SHA256:ybgmFkzwOSotHTHLJgHO0QN8L0xErw6vd0VhFA9m3SM"},
+		{"ecdsa-sha2-nistp256", "SHA256:FC73VB6C4OQLSCrjEayhMp9UMxS97caD/Yyi2bhW/J0"},
+		{"ssh-rsa", "SHA256:46OSHA1Rmj8E8ERTC6xkNcmGOw9oFxYr0WF6zWW8l1E"},
+	},
+	// https://docs.codeberg.org/security/ssh-fingerprint/
+	"codeberg.org": {
+		{"ssh-ed25519", "SHA256:mIlxA9k46MmM6qdJOdMnAQpzGxF4WIVVL+fj+wZbw0g"},
+		{"ecdsa-sha2-nistp256", "SHA256:T9FYDEHELhVkulEKKwge5aVhVTbqCW0MIRwAfpARs/E"},
+		{"ssh-rsa", "SHA256:6QQmYi4ppFS4/+zSZ5S4IU+4sa6rwvQ4PbhCtPEBekQ"},
+	},
+	// https://learn.microsoft.com/en-us/azure/devops/repos/git/use-ssh-keys-to-authenticate
+	"ssh.dev.azure.com": {
+		{"ssh-rsa", "SHA256:ohD8VZEXGWo6Ez8GSEJQ9WpafgLFsOfLOtGGQCQo6Og"},
+	},
+	"vs-ssh.visualstudio.com": {
+		{"ssh-rsa", "SHA256:ohD8VZEXGWo6Ez8GSEJQ9WpafgLFsOfLOtGGQCQo6Og"},
+	},
+}
+
+// publishedAliases share the keys of another host
+var publishedAliases = map[string]string{
+	"ssh.github.com":       "github.com", // SSH over port 443
+	"altssh.gitlab.com":    "gitlab.com",
+	"altssh.bitbucket.org": "bitbucket.org",
+}
+
+var (
+	customHostKeys   = map[string][]HostKeyPin{}
+	customHostKeysMu sync.RWMutex
+)
+
+// RegisterHostKeys pins fingerprints for a host pattern, e.g. from a custom
+// platform. Entries are "SHA256:..." or "<key type> SHA256:...".
+func RegisterHostKeys(hostPattern string, fingerprints []string) error {
+	var pins []HostKeyPin
+	for _, fp := range fingerprints {
+		pin, err := ParseHostKeyPin(fp)
+		if err != nil {
+			return fmt.Errorf("invalid host key for %s: %w", hostPattern, err)
+		}
+		pins = append(pins, pin)
+	}
+
+	customHostKeysMu.Lock()
+	defer customHostKeysMu.Unlock()
+	customHostKeys[strings.ToLower(hostPattern)] = pins
+	return nil
+}
+
+// ResetHostKeys removes all pins registered with RegisterHostKeys
+func ResetHostKeys() {
+	customHostKeysMu.Lock()
+	defer customHostKeysMu.Unlock()
+	customHostKeys = map[string][]HostKeyPin{}
+}
+
+// ParseHostKeyPin parses "SHA256:..." or "<key type> SHA256:..."
+func ParseHostKeyPin(s string) (HostKeyPin, error) {
+	fields := strings.Fields(s)
+	var pin HostKeyPin
+	switch len(fields) {
+	case 1:
+		pin.Fingerprint = fields[0]
+	case 2:
+		pin.Type, pin.Fingerprint = fields[0], fields[1]
+	default:
+		return pin, fmt.Errorf("expected \"[type] SHA256:...\", got %q", s)
+	}
+	if !strings.HasPrefix(pin.Fingerprint, "SHA256:") {
+		return pin, fmt.Errorf("only SHA256 fingerprints are supported: %q", s)
+	}
+	return pin, nil
+}
+
+// PinnedHostKeys returns the trusted fingerprints for a host, custom pins first
+func PinnedHostKeys(host string) []HostKeyPin {
+	host = strings.ToLower(host)
+
+	customHostKeysMu.RLock()
+	for pattern, pins := range customHostKeys {
+		if matchPattern(pattern, host) {
+			customHostKeysMu.RUnlock()
+			return pins
+		}
+	}
+	customHostKeysMu.RUnlock()
+
+	if alias, ok := publishedAliases[host]; ok {
+		host = alias
+	}
+	return publishedHostKeys[host]
+}
+
+// HostKeyStatus is the result of checking one host key against the pins
+type HostKeyStatus int
+
+const (
+	HostKeyUnpinned HostKeyStatus = iota // no pin covers this key
+	HostKeyVerified                      // fingerprint is pinned
+	HostKeyMismatch                      // a pin for this key type has another fingerprint
+)
+
+// HostKey is a host public key with its verification status
+type HostKey struct {
+	Key    *PublicKey
+	Status HostKeyStatus
+}
+
+// VerifyHostKeys checks keys against pins. It fails with ErrHostKeyMismatch
+// if any key contradicts a pin of its type, or if the host has pins and
+// none of the keys matches.
+func VerifyHostKeys(keys []*PublicKey, pins []HostKeyPin) ([]HostKey, error) {
+	result := make([]HostKey, len(keys))
+	verified := false
+
+	for i, key := range keys {
+		result[i] = HostKey{Key: key, Status: HostKeyUnpinned}
+		fp := key.Fingerprint()
+		for _, pin := range pins {
+			if pin.Fingerprint == fp && (pin.Type == "" || pin.Type == key.Type) {
+				result[i].Status = HostKeyVerified
+				verified = true
+				break
+			}
+			if pin.Type == key.Type {
+				result[i].Status = HostKeyMismatch
+			}
+		}
+	}
+
+	for _, k := range result {
+		if k.Status == HostKeyMismatch {
+			return result, fmt.Errorf("%w: %s key %s", ErrHostKeyMismatch, k.Key.Type, k.Key.Fingerprint())
+		}
+	}
+	if len(pins) > 0 && len(keys) > 0 && !verified {
+		return result, fmt.Errorf("%w: none of the %d keys is pinned", ErrHostKeyMismatch, len(keys))
+	}
+	return result, nil
+}
+
+// keyscan runs ssh-keyscan; tests replace it
+var keyscan = func(host string, port int) (string, error) {
+	args := []string{"-T", "10"}
+	if port > 0 && port != 22 {
+		args = append(args, "-p", strconv.Itoa(port))
+	}
+	args = append(args, host)
+	return shell.Exec("ssh-keyscan", args...)
+}
+
+// ScanHostKeys fetches the public keys a host presents
+func ScanHostKeys(host string, port int) ([]*PublicKey, error) {
+	output, err := keyscan(host, port)
+	if err != nil && strings.TrimSpace(output) == "" {
+		return nil, fmt.Errorf("ssh-keyscan %s failed: %w", host, err)
+	}
+
+	var keys []*PublicKey
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// "host keytype base64"; errors such as "getaddrinfo ..." are skipped
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !isHostKeyType(fields[1]) {
+			continue
+		}
+		key, err := ParsePublicKey(fields[1] + " " + fields[2])
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no host keys received from %s", host)
+	}
+	return keys, nil
+}
+
+// isHostKeyType reports whether s names an SSH public key algorithm
+func isHostKeyType(s string) bool {
+	return strings.HasPrefix(s, "ssh-") || strings.HasPrefix(s, "ecdsa-") || strings.HasPrefix(s, "sk-")
+}
+
+// GetKnownHostsPath returns the path of the user's known_hosts file
+func GetKnownHostsPath() string {
+	return filepath.Join(platform.GetSSHDir(), "known_hosts")
+}
+
+// knownHostsName returns the host name as written in known_hosts
+func knownHostsName(host string, port int) string {
+	if port > 0 && port != 22 {
+		return fmt.Sprintf("[%s]:%d", host, port)
+	}
+	return host
+}
+
+// KnownHostKeys returns the keys recorded for host in a known_hosts file,
+// including hashed entries. @revoked and @cert-authority lines are skipped.
+func KnownHostKeys(path, host string, port int) ([]*PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	name := knownHostsName(host, port)
+	var keys []*PublicKey
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "@") {
+			continue
+		}
+		if !knownHostsMatch(fields[0], name) {
+			continue
+		}
+		if key, err := ParsePublicKey(fields[1] + " " + fields[2]); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// knownHostsMatch matches a known_hosts host field against name
+func knownHostsMatch(field, name string) bool {
+	if strings.HasPrefix(field, "|1|") {
+		parts := strings.Split(field[3:], "|")
+		if len(parts) != 2 {
+			return false
+		}
+		salt, err1 := base64.StdEncoding.DecodeString(parts[0])
+		hash, err2 := base64.StdEncoding.DecodeString(parts[1])
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(name))
+		return hmac.Equal(mac.Sum(nil), hash)
+	}
+	return matchHostPatterns(name, strings.Split(field, ","))
+}
+
+// SyncResult describes what SyncKnownHosts found and changed
+type SyncResult struct {
+	Host    string
+	Keys    []HostKey // keys presented by the host
+	Pinned  bool      // host has pinned fingerprints
+	Added   int       // entries written to known_hosts
+	Removed int       // stale entries removed (with replace)
+}
+
+// SyncKnownHosts scans host, verifies its keys against the pins and records
+// them in known_hosts. Without pins, keys are only written when trustUnpinned
+// is set. Recorded keys that contradict the pins cause ErrHostKeyMismatch
+// unless replace is set, in which case they are removed.
+func SyncKnownHosts(host string, port int, trustUnpinned, replace bool) (*SyncResult, error) {
+	pins := PinnedHostKeys(host)
+	result := &SyncResult{Host: host, Pinned: len(pins) > 0}
+
+	scanned, err := ScanHostKeys(host, port)
+	if err != nil {
+		return result, err
+	}
+	result.Keys, err = VerifyHostKeys(scanned, pins)
+	if err != nil {
+		return result, err
+	}
+
+	path := GetKnownHostsPath()
+	known, err := KnownHostKeys(path, host, port)
+	if err != nil {
+		return result, err
+	}
+
+	// Recorded keys must agree with what the host presents now
+	if _, err := VerifyHostKeys(known, pinsFromKeys(result.Keys, trustUnpinned || result.Pinned)); err != nil && len(known) > 0 {
+		if !replace {
+			return result, fmt.Errorf("known_hosts has a different key for %s (%w); re-run with --replace to fix", host, ErrHostKeyMismatch)
+		}
+		if result.Removed, err = removeKnownHost(path, knownHostsName(host, port)); err != nil {
+			return result, err
+		}
+		known = nil
+	}
+
+	var lines []string
+	for _, k := range result.Keys {
+		if k.Status != HostKeyVerified && !trustUnpinned {
+			continue
+		}
+		if containsKey(known, k.Key) {
+			continue
+		}
+		lines = append(lines, knownHostsName(host, port)+" "+k.Key.Authorized())
+	}
+	if len(lines) == 0 {
+		return result, nil
+	}
+
+	if err := appendKnownHosts(path, lines); err != nil {
+		return result, err
+	}
+	result.Added = len(lines)
+	return result, nil
+}
+
+// CheckKnownHost verifies the known_hosts entries of a host with pinned
+// fingerprints against the pins, without writing anything
+func CheckKnownHost(host string, port int) error {
+	pins := PinnedHostKeys(host)
+	if len(pins) == 0 {
+		return nil
+	}
+
+	known, err := KnownHostKeys(GetKnownHostsPath(), host, port)
+	if err != nil {
+		return err
+	}
+	if len(known) > 0 {
+		if _, err := VerifyHostKeys(known, pins); err != nil {
+			return fmt.Errorf("known_hosts entry for %s: %w (run: ghex ssh known-hosts sync --replace)", host, err)
+		}
+	}
+	return nil
+}
+
+// pinsFromKeys turns keys into pins so recorded entries can be compared
+func pinsFromKeys(keys []HostKey, includeUnpinned bool) []HostKeyPin {
+	var pins []HostKeyPin
+	for _, k := range keys {
+		if k.Status == HostKeyVerified || includeUnpinned {
+			pins = append(pins, HostKeyPin{Type: k.Key.Type, Fingerprint: k.Key.Fingerprint()})
+		}
+	}
+	return pins
+}
+
+// containsKey reports whether keys holds key
+func containsKey(keys []*PublicKey, key *PublicKey) bool {
+	for _, k := range keys {
+		if k.Fingerprint() == key.Fingerprint() {
+			return true
+		}
+	}
+	return false
+}
+
+// appendKnownHosts adds lines to known_hosts
+func appendKnownHosts(path string, lines []string) error {
+	if err := platform.EnsureDir(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create SSH directory: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+	content := string(data)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += strings.Join(lines, "\n") + "\n"
+
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// removeKnownHost removes all plain and hashed entries for name
+func removeKnownHost(path, name string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	var kept []string
+	removed := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && !strings.HasPrefix(fields[0], "#") && !strings.HasPrefix(fields[0], "@") && knownHostsMatch(fields[0], name) {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Join(kept, "\n")), 0600); err != nil {
+		return 0, fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,174 @@
+package ssh
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const (
+	hostKeyA = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMrDP6Fc254OR/75BAFP7osbj3sNdNByR3mucXRJZZLL"
+	hostKeyB = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIKAfLSQUXk1NhY65peFDvigjySDsA3z0fjNXfVInce1R"
+)
+
+// mustFingerprint returns the fingerprint of a public key line
+func mustFingerprint(t *testing.T, key string) string {
+	fp, err := Fingerprint(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fp
+}
+
+// stubKeyscan makes ssh-keyscan return the given key for any host
+func stubKeyscan(t *testing.T, key string) {
+	orig := keyscan
+	keyscan = func(host string, port int) (string, error) {
+		return "# " + host + ":22 SSH-2.0-OpenSSH\n" + knownHostsName(host, port) + " " + key + "\n", nil
+	}
+	t.Cleanup(func() { keyscan = orig })
+}
+
+// TestVerifyHostKeys tests pin verification outcomes
+func TestVerifyHostKeys(t *testing.T) {
+	a, _ := ParsePublicKey(hostKeyA)
+	fpA := a.Fingerprint()
+	fpB := mustFingerprint(t, hostKeyB)
+
+	if res, err := VerifyHostKeys([]*PublicKey{a}, []HostKeyPin{{"ssh-ed25519", fpA}}); err != nil || res[0].Status != HostKeyVerified {
+		t.Errorf("Expected verified key, got %v %v", res, err)
+	}
+	if _, err := VerifyHostKeys([]*PublicKey{a}, []HostKeyPin{{"ssh-ed25519", fpB}}); !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("Expected mismatch for pinned type, got %v", err)
+	}
+	if _, err := VerifyHostKeys([]*PublicKey{a}, []HostKeyPin{{"", fpB}}); !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("Expected mismatch when no key matches untyped pins, got %v", err)
+	}
+	if res, err := VerifyHostKeys([]*PublicKey{a}, []HostKeyPin{{"ssh-rsa", fpB}, {"", fpA}}); err != nil || res[0].Status != HostKeyVerified {
+		t.Errorf("Expected verified key with mixed pins, got %v %v", res, err)
+	}
+	if res, err := VerifyHostKeys([]*PublicKey{a}, nil); err != nil || res[0].Status != HostKeyUnpinned {
+		t.Errorf("Expected unpinned key, got %v %v", res, err)
+	}
+}
+
+// TestPinnedHostKeys tests published and custom pins
+func TestPinnedHostKeys(t *testing.T) {
+	t.Cleanup(ResetHostKeys)
+
+	if len(PinnedHostKeys("github.com")) == 0 || len(PinnedHostKeys("ssh.github.com")) == 0 {
+		t.Error("Expected published pins for github.com and ssh.github.com")
+	}
+	if len(PinnedHostKeys("git.sr.ht")) != 0 {
+		t.Error("Expected no pins for git.sr.ht")
+	}
+
+	if err := RegisterHostKeys("*.corp.example", []string{"ssh-ed25519 SHA256:abc", "SHA256:def"}); err != nil {
+		t.Fatalf("RegisterHostKeys failed: %v", err)
+	}
+	pins := PinnedHostKeys("git.corp.example")
+	if len(pins) != 2 || pins[0].Type != "ssh-ed25519" || pins[1].Fingerprint != "SHA256:def" {
+		t.Errorf("Unexpected custom pins: %v", pins)
+	}
+	if err := RegisterHostKeys("bad.example", []string{"MD5:aa:bb"}); err == nil {
+		t.Error("Expected error for non-SHA256 fingerprint")
+	}
+}
+
+// TestSyncKnownHosts tests writing, re-syncing and replacing entries
+func TestSyncKnownHosts(t *testing.T) {
+	setupSSHHome(t)
+	t.Cleanup(ResetHostKeys)
+	RegisterHostKeys("git.corp.example", []string{mustFingerprint(t, hostKeyA)})
+
+	stubKeyscan(t, hostKeyA)
+	result, err := SyncKnownHosts("git.corp.example", 2222, false, false)
+	if err != nil || result.Added != 1 {
+		t.Fatalf("Expected one key added, got %+v %v", result, err)
+	}
+	data, _ := os.ReadFile(GetKnownHostsPath())
+	if string(data) != "[git.corp.example]:2222 "+hostKeyA+"\n" {
+		t.Errorf("Unexpected known_hosts:\n%s", data)
+	}
+
+	if result, err = SyncKnownHosts("git.corp.example", 2222, false, false); err != nil || result.Added != 0 {
+		t.Errorf("Expected no changes on re-sync, got %+v %v", result, err)
+	}
+
+	// A stale entry for the same key type must be reported, then replaced
+	os.WriteFile(GetKnownHostsPath(), []byte("other.example "+hostKeyB+"\n[git.corp.example]:2222 "+hostKeyB+"\n"), 0600)
+	if _, err := SyncKnownHosts("git.corp.example", 2222, false, false); !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("Expected mismatch for stale entry, got %v", err)
+	}
+	result, err = SyncKnownHosts("git.corp.example", 2222, false, true)
+	if err != nil || result.Removed != 1 || result.Added != 1 {
+		t.Errorf("Expected stale entry replaced, got %+v %v", result, err)
+	}
+	data, _ = os.ReadFile(GetKnownHostsPath())
+	if !strings.HasPrefix(string(data), "other.example "+hostKeyB+"\n") {
+		t.Errorf("Expected other hosts to be kept:\n%s", data)
+	}
+
+	// The host presenting an unpinned key must fail loudly and write nothing
+	stubKeyscan(t, hostKeyB)
+	os.Remove(GetKnownHostsPath())
+	if _, err := SyncKnownHosts("git.corp.example", 2222, true, true); !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("Expected mismatch for unpinned host key, got %v", err)
+	}
+	if _, err := os.Stat(GetKnownHostsPath()); !os.IsNotExist(err) {
+		t.Error("Expected known_hosts not to be written")
+	}
+	if _, err := SyncKnownHosts("github.com", 0, true, false); !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("Expected mismatch against published GitHub keys, got %v", err)
+	}
+}
+
+// TestCheckKnownHost tests the read-only check used before connection tests
+func TestCheckKnownHost(t *testing.T) {
+	setupSSHHome(t)
+	t.Cleanup(ResetHostKeys)
+
+	if err := CheckKnownHost("git.sr.ht", 0); err != nil {
+		t.Errorf("Expected unpinned host to be left alone, got %v", err)
+	}
+	if err := CheckKnownHost("github.com", 0); err != nil {
+		t.Errorf("Expected an unrecorded pinned host to pass the check, got %v", err)
+	}
+	if _, err := os.Stat(GetKnownHostsPath()); !os.IsNotExist(err) {
+		t.Error("Expected known_hosts not to be written")
+	}
+
+	os.WriteFile(GetKnownHostsPath(), []byte("github.com "+hostKeyA+"\n"), 0600)
+	if err := CheckKnownHost("github.com", 0); !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("Expected mismatch for wrong recorded GitHub key, got %v", err)
+	}
+}
+
+// TestKnownHostsMatch tests plain, port and hashed host fields
+func TestKnownHostsMatch(t *testing.T) {
+	salt := []byte("0123456789abcdefghij")
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte("github.com"))
+	hashed := "|1|" + base64.StdEncoding.EncodeToString(salt) + "|" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		field, name string
+		expected    bool
+	}{
+		{"github.com,140.82.112.3", "github.com", true},
+		{"[git.example]:2222", "[git.example]:2222", true},
+		{"git.example", "[git.example]:2222", false},
+		{"*.example,!bad.example", "bad.example", false},
+		{hashed, "github.com", true},
+		{hashed, "gitlab.com", false},
+	}
+	for _, tt := range tests {
+		if got := knownHostsMatch(tt.field, tt.name); got != tt.expected {
+			t.Errorf("knownHostsMatch(%q, %q) = %v, expected %v", tt.field, tt.name, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dwirx/ghex/internal/platform"
+)
+
+// ManagedInclude is the Include pattern added to ~/.ssh/config
+const ManagedInclude = "config.d/ghex"
+
+// managedHeader is written at the top of a new managed file
+const managedHeader = "# Managed by ghex. Edit with ghex; inspect or revert with `ghex ssh config`."
+
+// maxSnapshots is the number of snapshots kept before the oldest are pruned
+const maxSnapshots = 20
+
+// snapshotTimeFormat names snapshot directories; it sorts chronologically
+const snapshotTimeFormat = "20060102-150405.000000"
+
+// GetManagedConfigPath returns the path of the ghex-managed SSH config file
+func GetManagedConfigPath() string {
+	return filepath.Join(platform.GetSSHDir(), "config.d", "ghex")
+}
+
+// GetSnapshotDir returns the directory holding SSH config snapshots
+func GetSnapshotDir() string {
+	return filepath.Join(platform.GetConfigDir("ghe"), "ssh-backups")
+}
+
+// ManagedFile is an SSH config file ghex writes to
+type ManagedFile struct {
+	Name string // file name inside a snapshot
+	Path string
+}
+
+// ManagedFiles returns the user SSH config and the ghex-managed file
+func ManagedFiles() []ManagedFile {
+	return []ManagedFile{
+		{Name: "config", Path: GetSSHConfigPath()},
+		{Name: "ghex", Path: GetManagedConfigPath()},
+	}
+}
+
+// updateConfigs loads both config files, applies fn to them and writes back
+// the files that changed, snapshotting the previous state first. It also
+// makes sure ~/.ssh/config includes the managed file.
+func updateConfigs(fn func(user, managed *Config)) error {
+	if err := platform.EnsureDir(filepath.Dir(GetManagedConfigPath()), 0700); err != nil {
+		return fmt.Errorf("failed to create SSH directory: %w", err)
+	}
+
+	user, err := LoadConfig(GetSSHConfigPath())
+	if err != nil {
+		return err
+	}
+	managed, err := LoadConfig(GetManagedConfigPath())
+	if err != nil {
+		return err
+	}
+	before := []string{user.String(), managed.String()}
+
+	fn(user, managed)
+
+	if len(managed.Hosts()) > 0 && !user.Includes(managed.Path) {
+		user.AddInclude(ManagedInclude)
+	}
+	if managed.String() != "" && before[1] == "" {
+		managed.Blocks[0].Lines = append([]*ConfigLine{{Raw: managedHeader}, {Raw: ""}}, managed.Blocks[0].Lines...)
+	}
+
+	if user.String() == before[0] && managed.String() == before[1] {
+		return nil
+	}
+
+	if _, err := TakeSnapshot(); err != nil {
+		return err
+	}
+	for i, cfg := range []*Config{user, managed} {
+		if cfg.String() == before[i] {
+			continue
+		}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureManagedInclude adds the Include of the managed file to ~/.ssh/config
+// if the managed file has Host blocks but isn't included
+func EnsureManagedInclude() error {
+	return updateConfigs(func(user, managed *Config) {})
+}
+
+// isLegacyGhexBlock reports whether a Host block in ~/.ssh/config is exactly
+// what older ghex versions wrote for this host name and key. Only such blocks
+// are moved to the managed file without asking, as they can't hold anything
+// the user maintains.
+func isLegacyGhexBlock(b *ConfigBlock, hostname, keyPath string) bool {
+	if !looksLikeGhexBlock(b) {
+		return false
+	}
+	want := []Directive{{"hostname", hostname}, {"user", "git"}, {"identityfile", keyPath}, {"identitiesonly", "yes"}}
+	got := b.Directives()
+	if len(got) != len(want) {
+		return false
+	}
+	for i, d := range got {
+		if d.Keyword != want[i].Keyword || len(d.Args) != 1 || !strings.EqualFold(d.Args[0], want[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// looksLikeGhexBlock reports whether a Host block in ~/.ssh/config has the
+// shape of one written by older ghex versions. It may be hand-written, so
+// such blocks are only removed when the user agrees.
+func looksLikeGhexBlock(b *ConfigBlock) bool {
+	if b == nil || len(b.Patterns()) != 1 || !strings.EqualFold(b.Get("IdentitiesOnly"), "yes") {
+		return false
+	}
+	for _, l := range b.Lines {
+		if isComment(l.Raw) {
+			return false
+		}
+		switch l.Keyword {
+		case "", "hostname", "user", "port", "identityfile", "identitiesonly":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// LegacyHostCandidates returns the aliases of Host blocks in ~/.ssh/config
+// that look like ones older ghex versions wrote and are duplicated by a
+// managed block
+func LegacyHostCandidates() ([]string, error) {
+	user, err := LoadConfig(GetSSHConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	managed, err := LoadConfig(GetManagedConfigPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var aliases []string
+	for _, block := range user.Hosts() {
+		if !looksLikeGhexBlock(block) {
+			continue
+		}
+		if alias := block.Patterns()[0]; managed.FindHost(alias) != nil {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases, nil
+}
+
+// RemoveUserHostBlocks removes Host blocks from ~/.ssh/config, such as the
+// legacy blocks the user agreed to drop
+func RemoveUserHostBlocks(aliases []string) error {
+	return updateConfigs(func(user, managed *Config) {
+		for _, alias := range aliases {
+			user.RemoveHost(alias)
+		}
+	})
+}
+
+// Snapshot is a saved copy of the SSH config files
+type Snapshot struct {
+	ID   string
+	Time time.Time
+	Dir  string
+}
+
+// TakeSnapshot saves the current SSH config files
+func TakeSnapshot() (*Snapshot, error) {
+	now := time.Now()
+	snap := &Snapshot{ID: now.Format(snapshotTimeFormat), Time: now}
+	snap.Dir = filepath.Join(GetSnapshotDir(), snap.ID)
+
+	if err := platform.EnsureDir(snap.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	for _, f := range ManagedFiles() {
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // Restoring will remove the file
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", f.Path, err)
+		}
+		if err := os.WriteFile(filepath.Join(snap.Dir, f.Name), data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+
+	pruneSnapshots()
+	return snap, nil
+}
+
+// ListSnapshots returns the saved snapshots, newest first
+func ListSnapshots() ([]Snapshot, error) {
+	entries, err := os.ReadDir(GetSnapshotDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+
+	var snapshots []Snapshot
+	for _, e := range entries {
+		t, err := time.ParseInLocation(snapshotTimeFormat, e.Name(), time.Local)
+		if !e.IsDir() || err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{ID: e.Name(), Time: t, Dir: filepath.Join(GetSnapshotDir(), e.Name())})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].ID > snapshots[j].ID })
+	return snapshots, nil
+}
+
+// GetSnapshot returns a snapshot by ID, or the latest one if id is empty
+func GetSnapshot(id string) (*Snapshot, error) {
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no SSH config snapshots found")
+	}
+	if id == "" {
+		return &snapshots[0], nil
+	}
+	for i := range snapshots {
+		if snapshots[i].ID == id {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, fmt.Errorf("snapshot not found: %s", id)
+}
+
+// ReadFile returns the snapshot's copy of a managed file, or "" if the file
+// did not exist when the snapshot was taken
+func (s *Snapshot) ReadFile(name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Diff returns a unified diff from the snapshot to the current files
+func (s *Snapshot) Diff() (string, error) {
+	var out strings.Builder
+	for _, f := range ManagedFiles() {
+		old, err := s.ReadFile(f.Name)
+		if err != nil {
+			return "", err
+		}
+		current, err := os.ReadFile(f.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		out.WriteString(UnifiedDiff(f.Path+" ("+s.ID+")", f.Path, old, string(current)))
+	}
+	return out.String(), nil
+}
+
+// Restore writes the snapshot's files back, snapshotting the current state
+// first so the restore can itself be undone
+func (s *Snapshot) Restore() error {
+	// Read the snapshot before taking a new one, which may prune it
+	if _, err := os.Stat(s.Dir); err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	files := ManagedFiles()
+	contents := make([][]byte, len(files))
+	for i, f := range files {
+		data, err := os.ReadFile(filepath.Join(s.Dir, f.Name))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		contents[i] = data
+	}
+
+	if _, err := TakeSnapshot(); err != nil {
+		return err
+	}
+
+	for i, f := range files {
+		data := contents[i]
+		if data == nil {
+			if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", f.Path, err)
+			}
+			continue
+		}
+		if err := platform.EnsureDir(filepath.Dir(f.Path), 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(f.Path, data, 0600); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// pruneSnapshots removes the oldest snapshots beyond maxSnapshots
+func pruneSnapshots() {
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		return
+	}
+	for i := maxSnapshots; i < len(snapshots); i++ {
+		os.RemoveAll(snapshots[i].Dir)
+	}
+}
@@ -0,0 +1,206 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupSSHHome points the home and config directories at a temp dir
+func setupSSHHome(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("APPDATA", filepath.Join(home, ".config"))
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+// TestEnsureConfigBlockManaged tests that ghex writes only to its own file
+func TestEnsureConfigBlockManaged(t *testing.T) {
+	setupSSHHome(t)
+
+	original := `# My config
+Host github.com
+  HostName github.com
+  User git
+  IdentityFile ~/.ssh/id_new
+  IdentitiesOnly yes
+
+Host gitlab.com
+  HostName gitlab.com
+  User git
+  IdentityFile ~/.ssh/id_old
+  IdentitiesOnly yes
+
+Host server
+  HostName 10.0.0.1
+  IdentityFile ~/.ssh/id_server
+  IdentitiesOnly yes
+  ForwardAgent yes
+`
+	if err := os.WriteFile(GetSSHConfigPath(), []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EnsureConfigBlock("github.com", "~/.ssh/id_new", "github.com"); err != nil {
+		t.Fatalf("EnsureConfigBlock failed: %v", err)
+	}
+
+	user, _ := os.ReadFile(GetSSHConfigPath())
+	expected := "Include config.d/ghex\n\n# My config\n\nHost gitlab.com\n"
+	if !strings.HasPrefix(string(user), expected) {
+		t.Errorf("Expected Include and migrated legacy block, got:\n%s", user)
+	}
+	if !strings.Contains(string(user), "ForwardAgent yes") {
+		t.Errorf("Expected hand-written block to be kept:\n%s", user)
+	}
+
+	managed, _ := os.ReadFile(GetManagedConfigPath())
+	if !strings.HasPrefix(string(managed), managedHeader) || !strings.Contains(string(managed), "IdentityFile ~/.ssh/id_new") {
+		t.Errorf("Unexpected managed file:\n%s", managed)
+	}
+
+	// A second write must not add another Include, nor remove a block that
+	// merely looks like ghex's
+	if err := EnsureConfigBlock("gitlab.com", "~/.ssh/id_gl", "gitlab.com"); err != nil {
+		t.Fatal(err)
+	}
+	user, _ = os.ReadFile(GetSSHConfigPath())
+	if strings.Count(string(user), "Include") != 1 || !strings.Contains(string(user), "id_old") {
+		t.Errorf("Expected a single Include and the gitlab.com block, got:\n%s", user)
+	}
+
+	// Such blocks are offered for removal instead
+	candidates, err := LegacyHostCandidates()
+	if err != nil || len(candidates) != 1 || candidates[0] != "gitlab.com" {
+		t.Errorf("Expected gitlab.com as legacy candidate, got %v %v", candidates, err)
+	}
+	if err := RemoveUserHostBlocks(candidates); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ = os.ReadFile(GetSSHConfigPath()); strings.Contains(string(user), "id_old") || !strings.Contains(string(user), "ForwardAgent yes") {
+		t.Errorf("Expected only the gitlab.com block removed, got:\n%s", user)
+	}
+
+	resolved, err := Resolve("github.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := resolved.IdentityFiles(); len(files) != 1 || !strings.HasSuffix(files[0], "id_new") {
+		t.Errorf("Expected github.com to resolve to the managed key, got %v", files)
+	}
+}
+
+// TestSnapshotRestore tests that writes are snapshotted and can be reverted
+func TestSnapshotRestore(t *testing.T) {
+	setupSSHHome(t)
+
+	original := "Host server\n  HostName 10.0.0.1\n"
+	if err := os.WriteFile(GetSSHConfigPath(), []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureConfigBlock("github.com", "~/.ssh/id_new", "github.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := GetSnapshot("")
+	if err != nil {
+		t.Fatalf("Expected a snapshot: %v", err)
+	}
+	diff, err := snap.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "+Include config.d/ghex") || !strings.Contains(diff, "+Host github.com") {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+
+	if err := snap.Restore(); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	user, _ := os.ReadFile(GetSSHConfigPath())
+	if string(user) != original {
+		t.Errorf("Expected original config, got:\n%s", user)
+	}
+	if _, err := os.Stat(GetManagedConfigPath()); !os.IsNotExist(err) {
+		t.Error("Expected managed file to be removed")
+	}
+
+	snapshots, _ := ListSnapshots()
+	if len(snapshots) != 2 {
+		t.Errorf("Expected restore to snapshot the current state, got %d snapshots", len(snapshots))
+	}
+}
+
+// TestSnapshotRestoreOldest tests restoring a snapshot that the safety
+// snapshot taken by Restore prunes
+func TestSnapshotRestoreOldest(t *testing.T) {
+	setupSSHHome(t)
+
+	if err := os.WriteFile(GetSSHConfigPath(), []byte("Host current\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < maxSnapshots; i++ {
+		dir := filepath.Join(GetSnapshotDir(), start.Add(time.Duration(i)*time.Second).Format(snapshotTimeFormat))
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		config := fmt.Sprintf("Host snapshot%d\n", i)
+		if err := os.WriteFile(filepath.Join(dir, "config"), []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots, _ := ListSnapshots()
+	oldest := snapshots[len(snapshots)-1]
+	if err := oldest.Restore(); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	user, _ := os.ReadFile(GetSSHConfigPath())
+	if string(user) != "Host snapshot0\n" {
+		t.Errorf("Expected oldest snapshot to be restored, got %q", user)
+	}
+
+	snapshots, _ = ListSnapshots()
+	if len(snapshots) != maxSnapshots {
+		t.Errorf("Expected %d snapshots, got %d", maxSnapshots, len(snapshots))
+	}
+}
+
+// TestUnifiedDiff tests diff hunks
+func TestUnifiedDiff(t *testing.T) {
+	if diff := UnifiedDiff("a", "b", "x\n", "x\n"); diff != "" {
+		t.Errorf("Expected no diff, got %q", diff)
+	}
+
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	updated := "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+	expected := `--- a
++++ b
+@@ -1,7 +1,7 @@
+ 1
+ 2
+ 3
+-4
++four
+ 5
+ 6
+ 7
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+	if diff := UnifiedDiff("a", "b", old, updated); diff != expected {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+}
@@ -0,0 +1,101 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dwirx/ghex/internal/platform"
+)
+
+// rotationSuffix matches the date suffix added by RotatedKeyPath
+var rotationSuffix = regexp.MustCompile(`_\d{8}$`)
+
+// RotatedKeyPath returns the path for a key replacing keyPath, e.g.
+// ~/.ssh/id_ed25519_work → ~/.ssh/id_ed25519_work_20261018
+func RotatedKeyPath(keyPath string, now time.Time) string {
+	base := rotationSuffix.ReplaceAllString(keyPath, "")
+	return base + "_" + now.Format("20060102")
+}
+
+// GetKeyArchiveDir returns the directory holding rotated-out keys
+func GetKeyArchiveDir() string {
+	return filepath.Join(platform.GetSSHDir(), "archive")
+}
+
+// ArchiveKey moves a private key and its .pub file to the archive directory,
+// stamped with the time, and returns the archived private key path
+func ArchiveKey(keyPath string, now time.Time) (string, error) {
+	keyPath = platform.ExpandPath(keyPath)
+	if !platform.FileExists(keyPath) {
+		return "", fmt.Errorf("key not found: %s", keyPath)
+	}
+
+	dir := GetKeyArchiveDir()
+	if err := platform.EnsureDir(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	dest := filepath.Join(dir, filepath.Base(keyPath)+"."+now.Format("20060102-150405"))
+	if platform.FileExists(dest) {
+		return "", fmt.Errorf("archive already exists: %s", dest)
+	}
+	if err := os.Rename(keyPath, dest); err != nil {
+		return "", fmt.Errorf("failed to archive key: %w", err)
+	}
+	if platform.FileExists(keyPath + ".pub") {
+		if err := os.Rename(keyPath+".pub", dest+".pub"); err != nil {
+			return dest, fmt.Errorf("failed to archive public key: %w", err)
+		}
+	}
+	return dest, nil
+}
+
+// KeySharedWith returns the accounts other than account that use keyPath
+func KeySharedWith(refs []KeyReference, account, keyPath string) []string {
+	var shared []string
+	for _, ref := range refs {
+		if ref.Account != account && cleanKeyPath(ref.KeyPath) == cleanKeyPath(keyPath) {
+			shared = append(shared, ref.Account)
+		}
+	}
+	return shared
+}
+
+// ReplaceIdentityFile points the managed Host blocks for hosts that use
+// oldPath at newPath
+// and returns the patterns of the blocks it changed
+func ReplaceIdentityFile(oldPath, newPath string, hosts []string) ([]string, error) {
+	var changed []string
+	err := updateConfigs(func(user, managed *Config) {
+		for _, b := range managed.Hosts() {
+			if !hasAnyPattern(b, hosts) {
+				continue
+			}
+			for i, l := range b.Lines {
+				if l.Keyword != "identityfile" || len(l.Args) != 1 || cleanKeyPath(l.Args[0]) != cleanKeyPath(oldPath) {
+					continue
+				}
+				indent := l.Raw[:len(l.Raw)-len(strings.TrimLeft(l.Raw, " \t"))]
+				b.Lines[i] = directiveLines([]Directive{{"IdentityFile", newPath}}, indent)[0]
+				changed = append(changed, strings.Join(b.Patterns(), " "))
+			}
+		}
+	})
+	return changed, err
+}
+
+// hasAnyPattern reports whether one of the block's patterns is in hosts
+func hasAnyPattern(b *ConfigBlock, hosts []string) bool {
+	for _, pattern := range b.Patterns() {
+		for _, host := range hosts {
+			if strings.EqualFold(pattern, host) {
+				return true
+			}
+		}
+	}
+	return false
+}
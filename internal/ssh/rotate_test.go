@@ -0,0 +1,101 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRotatedKeyPath tests that repeated rotations don't stack suffixes
+func TestRotatedKeyPath(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	tests := map[string]string{
+		"~/.ssh/id_ed25519_work":          "~/.ssh/id_ed25519_work_20261018",
+		"~/.ssh/id_ed25519_work_20251001": "~/.ssh/id_ed25519_work_20261018",
+		"~/.ssh/id_rsa":                   "~/.ssh/id_rsa_20261018",
+	}
+	for in, expected := range tests {
+		if got := RotatedKeyPath(in, now); got != expected {
+			t.Errorf("RotatedKeyPath(%s) = %s, expected %s", in, got, expected)
+		}
+	}
+}
+
+// TestArchiveKey tests that the key pair moves to the archive directory
+func TestArchiveKey(t *testing.T) {
+	home := setupSSHHome(t)
+	key := filepath.Join(home, ".ssh", "id_work")
+	os.WriteFile(key, []byte("private"), 0600)
+	os.WriteFile(key+".pub", []byte("public"), 0644)
+
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	dest, err := ArchiveKey("~/.ssh/id_work", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dest != filepath.Join(GetKeyArchiveDir(), "id_work.20261018-093000") {
+		t.Errorf("Unexpected archive path: %s", dest)
+	}
+	if _, err := os.Stat(key); !os.IsNotExist(err) {
+		t.Error("Expected the key to be moved")
+	}
+	if data, _ := os.ReadFile(dest + ".pub"); string(data) != "public" {
+		t.Errorf("Expected the public key to be archived, got %q", data)
+	}
+
+	if _, err := ArchiveKey("~/.ssh/id_work", now); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+}
+
+// TestReplaceIdentityFile tests that only blocks using the old key change
+func TestReplaceIdentityFile(t *testing.T) {
+	setupSSHHome(t)
+	if err := EnsureConfigBlock("github.com", "~/.ssh/id_work", "github.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureConfigBlock("gitlab.com", "~/.ssh/id_other", "gitlab.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EnsureConfigBlock("github-personal", "~/.ssh/id_work", "github.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := ReplaceIdentityFile("~/.ssh/id_work", "~/.ssh/id_work_20261018", []string{"github.com", "gitlab.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 1 || hosts[0] != "github.com" {
+		t.Errorf("Expected github.com to change, got %v", hosts)
+	}
+
+	data, _ := os.ReadFile(GetManagedConfigPath())
+	content := string(data)
+	if !strings.Contains(content, "IdentityFile ~/.ssh/id_work_20261018") || !strings.Contains(content, "IdentityFile ~/.ssh/id_other") {
+		t.Errorf("Unexpected managed config:\n%s", content)
+	}
+	if !strings.Contains(content, "IdentityFile ~/.ssh/id_work\n") {
+		t.Errorf("Expected github-personal to keep the old key:\n%s", content)
+	}
+}
+
+// TestKeySharedWith tests finding other accounts that use a key
+func TestKeySharedWith(t *testing.T) {
+	home := setupSSHHome(t)
+	refs := []KeyReference{
+		{Account: "work", KeyPath: "~/.ssh/id_work"},
+		{Account: "personal", KeyPath: filepath.Join(home, ".ssh", "id_work")},
+		{Account: "other", KeyPath: "~/.ssh/id_other"},
+	}
+
+	shared := KeySharedWith(refs, "work", "~/.ssh/id_work")
+	if len(shared) != 1 || shared[0] != "personal" {
+		t.Errorf("Expected personal to share the key, got %v", shared)
+	}
+	if shared := KeySharedWith(refs, "other", "~/.ssh/id_other"); len(shared) != 0 {
+		t.Errorf("Expected no other users, got %v", shared)
+	}
+}
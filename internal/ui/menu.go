@@ -94,6 +94,9 @@ func SelectPlatform() (string, error) {
 		{Title: "🪣 Bitbucket", Value: "bitbucket"},
 		{Title: "🍵 Gitea", Value: "gitea"},
 		{Title: "🏔️ Codeberg", Value: "codeberg"},
+		{Title: "🔷 Azure DevOps", Value: "azure"},
+		{Title: "☁️ AWS CodeCommit", Value: "codecommit"},
+		{Title: "🛖 SourceHut", Value: "sourcehut"},
 		{Title: "🌐 Other", Value: "other"},
 	}
 
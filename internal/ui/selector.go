@@ -271,6 +271,9 @@ func SelectPlatformInteractive() (string, error) {
 		{Title: "🪣 Bitbucket", Description: "bitbucket.org", Value: "bitbucket"},
 		{Title: "🍵 Gitea", Description: "Self-hosted Gitea", Value: "gitea"},
 		{Title: "🏔️ Codeberg", Description: "codeberg.org", Value: "codeberg"},
+		{Title: "🔷 Azure DevOps", Description: "dev.azure.com", Value: "azure"},
+		{Title: "☁️ AWS CodeCommit", Description: "git-codecommit.<region>.amazonaws.com", Value: "codecommit"},
+		{Title: "🛖 SourceHut", Description: "git.sr.ht", Value: "sourcehut"},
 		{Title: "🌐 Other", Description: "Custom Git server", Value: "other"},
 	}
 
@@ -1,10 +1,13 @@
 package ui
 
 import (
+	"bufio"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
 )
 
 // ASCII art title for GHEX
@@ -159,10 +162,15 @@ func PromptWithDefault(message, defaultValue string) string {
 	return response
 }
 
-// PromptPassword prompts for password input (note: this doesn't hide input in basic implementation)
+// PromptPassword prompts for password input without echoing it on a terminal
 func PromptPassword(message string) string {
 	fmt.Printf("%s %s: ", PrimaryStyle.Render("◇"), TextStyle.Render(message))
-	var response string
-	_, _ = fmt.Scanln(&response)
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		password, _ := term.ReadPassword(fd)
+		fmt.Println()
+		return strings.TrimSpace(string(password))
+	}
+	// Piped input: read the whole line, spaces included
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
 	return strings.TrimSpace(response)
 }
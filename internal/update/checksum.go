@@ -3,22 +3,79 @@ package update
 import (
 	"bufio"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Hash algorithms supported for checksum verification
+const (
+	AlgorithmSHA256  = "sha256"
+	AlgorithmSHA512  = "sha512"
+	AlgorithmBLAKE2b = "blake2b"
 )
 
+// NewHash returns a hash for algorithm. size is the digest length in bytes
+// for BLAKE2b (0: 64 bytes, as b2sum) and ignored otherwise.
+func NewHash(algorithm string, size int) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case AlgorithmSHA256, "":
+		return sha256.New(), nil
+	case AlgorithmSHA512:
+		return sha512.New(), nil
+	case AlgorithmBLAKE2b, "blake2", "b2":
+		if size == 0 {
+			size = blake2b.Size
+		}
+		return blake2b.New(size, nil)
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+	}
+}
+
+// DetectAlgorithm guesses the algorithm of checksum from the name of the
+// file it came from (e.g. SHA512SUMS, B2SUMS) and falls back to its length
+func DetectAlgorithm(checksumFileName, checksum string) string {
+	name := strings.ToLower(filepath.Base(checksumFileName))
+	switch {
+	case strings.Contains(name, "sha512"):
+		return AlgorithmSHA512
+	case strings.Contains(name, "b2sum") || strings.Contains(name, "blake2"):
+		return AlgorithmBLAKE2b
+	case strings.Contains(name, "sha256"):
+		return AlgorithmSHA256
+	case len(checksum) == 2*sha512.Size:
+		return AlgorithmSHA512
+	default:
+		return AlgorithmSHA256
+	}
+}
+
 // CalculateChecksum computes SHA256 hash of a file
 func CalculateChecksum(filePath string) (string, error) {
+	return CalculateChecksumWith(filePath, AlgorithmSHA256, 0)
+}
+
+// CalculateChecksumWith computes the hash of a file with algorithm
+func CalculateChecksumWith(filePath, algorithm string, size int) (string, error) {
+	h, err := NewHash(algorithm, size)
+	if err != nil {
+		return "", err
+	}
+
 	f, err := os.Open(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %w", err)
 	}
 	defer f.Close()
 
-	h := sha256.New()
 	if _, err := io.Copy(h, f); err != nil {
 		return "", fmt.Errorf("failed to calculate checksum: %w", err)
 	}
@@ -43,7 +100,14 @@ func CalculateChecksumFromBytes(data []byte) string {
 
 // VerifyChecksum verifies file integrity using SHA256
 func VerifyChecksum(filePath string, expectedChecksum string) error {
-	actualChecksum, err := CalculateChecksum(filePath)
+	return VerifyChecksumWith(filePath, expectedChecksum, AlgorithmSHA256)
+}
+
+// VerifyChecksumWith verifies file integrity using algorithm. BLAKE2b
+// digests may be shorter than 64 bytes, as with b2sum -l.
+func VerifyChecksumWith(filePath, expectedChecksum, algorithm string) error {
+	expectedChecksum = strings.TrimSpace(expectedChecksum)
+	actualChecksum, err := CalculateChecksumWith(filePath, algorithm, len(expectedChecksum)/2)
 	if err != nil {
 		return err
 	}
@@ -59,14 +123,26 @@ func VerifyChecksum(filePath string, expectedChecksum string) error {
 }
 
 
+// IsChecksumFile reports whether a release asset name is a checksums file
+// such as checksums.txt, SHA256SUMS, B2SUMS or goreleaser's <name>_checksums.txt
+func IsChecksumFile(name string) bool {
+	name = strings.ToLower(name)
+	switch strings.TrimSuffix(name, ".txt") {
+	case "checksums", "sha256sums", "sha512sums", "b2sums":
+		return true
+	}
+	return strings.HasSuffix(name, "_checksums.txt") || strings.HasSuffix(name, "-checksums.txt")
+}
+
 // ChecksumEntry represents a single entry in a checksums file
 type ChecksumEntry struct {
-	Checksum string
-	Filename string
+	Checksum  string
+	Filename  string
+	Algorithm string // set by BSD-style lines, empty otherwise
 }
 
 // ParseChecksumFile parses a checksums.txt file
-// Format: "checksum  filename" or "checksum filename"
+// Format: "checksum  filename", "checksum *filename" or "ALGO (filename) = checksum"
 func ParseChecksumFile(content string) ([]ChecksumEntry, error) {
 	var entries []ChecksumEntry
 	scanner := bufio.NewScanner(strings.NewReader(content))
@@ -77,6 +153,11 @@ func ParseChecksumFile(content string) ([]ChecksumEntry, error) {
 			continue
 		}
 
+		if entry, ok := parseBSDChecksumLine(line); ok {
+			entries = append(entries, entry)
+			continue
+		}
+
 		// Split by whitespace (could be spaces or tabs)
 		parts := strings.Fields(line)
 		if len(parts) < 2 {
@@ -85,7 +166,7 @@ func ParseChecksumFile(content string) ([]ChecksumEntry, error) {
 
 		entries = append(entries, ChecksumEntry{
 			Checksum: strings.ToLower(parts[0]),
-			Filename: parts[len(parts)-1], // Last part is filename
+			Filename: strings.TrimPrefix(parts[len(parts)-1], "*"), // Last part is filename, * marks binary mode
 		})
 	}
 
@@ -96,12 +177,42 @@ func ParseChecksumFile(content string) ([]ChecksumEntry, error) {
 	return entries, nil
 }
 
+// parseBSDChecksumLine parses a "SHA256 (filename) = checksum" line as
+// written by shasum --tag and b2sum --tag
+func parseBSDChecksumLine(line string) (ChecksumEntry, bool) {
+	algo, rest, ok := strings.Cut(line, " (")
+	if !ok || strings.ContainsAny(algo, " \t") {
+		return ChecksumEntry{}, false
+	}
+	idx := strings.LastIndex(rest, ") = ")
+	if idx < 0 {
+		return ChecksumEntry{}, false
+	}
+
+	algorithm := strings.ToLower(strings.ReplaceAll(algo, "-", ""))
+	if strings.HasPrefix(algorithm, "blake2b") {
+		// BLAKE2b-256 and BLAKE2b-512 differ only in digest length
+		algorithm = AlgorithmBLAKE2b
+	}
+	return ChecksumEntry{
+		Checksum:  strings.ToLower(strings.TrimSpace(rest[idx+4:])),
+		Filename:  rest[:idx],
+		Algorithm: algorithm,
+	}, true
+}
+
 // FindChecksum finds the checksum for a specific filename
 func FindChecksum(entries []ChecksumEntry, filename string) (string, bool) {
+	entry, ok := FindChecksumEntry(entries, filename)
+	return entry.Checksum, ok
+}
+
+// FindChecksumEntry finds the entry for a specific filename
+func FindChecksumEntry(entries []ChecksumEntry, filename string) (ChecksumEntry, bool) {
 	for _, entry := range entries {
 		if entry.Filename == filename || strings.HasSuffix(entry.Filename, "/"+filename) {
-			return entry.Checksum, true
+			return entry, true
 		}
 	}
-	return "", false
+	return ChecksumEntry{}, false
 }
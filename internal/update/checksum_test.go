@@ -1,8 +1,10 @@
 package update
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -100,3 +102,84 @@ func TestFindChecksum(t *testing.T) {
 		t.Error("Expected not to find checksum")
 	}
 }
+
+func TestVerifyChecksumWith(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "abc.txt")
+	if err := os.WriteFile(testFile, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		algorithm string
+		checksum  string
+	}{
+		{AlgorithmSHA256, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{AlgorithmSHA512, "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"},
+		{AlgorithmBLAKE2b, "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+	}
+	for _, tt := range tests {
+		if err := VerifyChecksumWith(testFile, strings.ToUpper(tt.checksum), tt.algorithm); err != nil {
+			t.Errorf("%s: %v", tt.algorithm, err)
+		}
+		if err := VerifyChecksumWith(testFile, strings.Repeat("0", len(tt.checksum)), tt.algorithm); !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("%s: expected a mismatch, got %v", tt.algorithm, err)
+		}
+	}
+
+	if err := VerifyChecksumWith(testFile, tests[0].checksum, "md5"); err == nil {
+		t.Error("Expected an error for an unsupported algorithm")
+	}
+}
+
+func TestParseChecksumFileFormats(t *testing.T) {
+	content := `SHA512 (tool.tar.gz) = ABC123
+BLAKE2b-256 (tool file.zip) = def456
+789abc *tool.exe
+`
+	entries, err := ParseChecksumFile(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ChecksumEntry{
+		{Checksum: "abc123", Filename: "tool.tar.gz", Algorithm: AlgorithmSHA512},
+		{Checksum: "def456", Filename: "tool file.zip", Algorithm: AlgorithmBLAKE2b},
+		{Checksum: "789abc", Filename: "tool.exe"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), entries)
+	}
+	for i, e := range expected {
+		if entries[i] != e {
+			t.Errorf("Entry %d: got %+v, want %+v", i, entries[i], e)
+		}
+	}
+}
+
+func TestChecksumFileNames(t *testing.T) {
+	for _, name := range []string{"checksums.txt", "SHA256SUMS", "SHA512SUMS", "B2SUMS", "tool_1.0.0_checksums.txt"} {
+		if !IsChecksumFile(name) {
+			t.Errorf("Expected %s to be a checksums file", name)
+		}
+	}
+	for _, name := range []string{"tool.tar.gz", "checksums.txt.sig"} {
+		if IsChecksumFile(name) {
+			t.Errorf("Expected %s not to be a checksums file", name)
+		}
+	}
+
+	sha512Sum := strings.Repeat("a", 128)
+	tests := map[string]string{
+		"SHA512SUMS":    AlgorithmSHA512,
+		"B2SUMS":        AlgorithmBLAKE2b,
+		"checksums.txt": AlgorithmSHA256,
+	}
+	for name, want := range tests {
+		if got := DetectAlgorithm(name, strings.Repeat("a", 64)); got != want {
+			t.Errorf("DetectAlgorithm(%s) = %s, want %s", name, got, want)
+		}
+	}
+	if got := DetectAlgorithm("", sha512Sum); got != AlgorithmSHA512 {
+		t.Errorf("Expected sha512 for a 128 digit checksum, got %s", got)
+	}
+}
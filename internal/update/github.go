@@ -92,6 +92,48 @@ func (c *GitHubClient) GetLatestRelease(owner, repo string) (*ReleaseInfo, error
 	return &release, nil
 }
 
+// GetReleaseByTag fetches the release with the given tag from GitHub
+func (c *GitHubClient) GetReleaseByTag(owner, repo, tag string) (*ReleaseInfo, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", c.BaseURL, owner, repo, tag)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNetworkError, err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", "ghex-updater")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNetworkError, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("release %s not found for %s/%s", tag, owner, repo)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: HTTP %d", ErrNetworkError, resp.StatusCode)
+	}
+
+	var release ReleaseInfo
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse release info: %w", err)
+	}
+
+	// Parse version from tag
+	version, err := ParseVersion(release.TagName)
+	if err == nil {
+		release.Version = version.String()
+	} else {
+		release.Version = release.TagName
+	}
+
+	return &release, nil
+}
+
 // GetReleases fetches all releases from GitHub
 func (c *GitHubClient) GetReleases(owner, repo string, limit int) ([]ReleaseInfo, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/releases?per_page=%d", c.BaseURL, owner, repo, limit)
@@ -62,9 +62,125 @@ func SelectAssetForPlatform(release *ReleaseInfo, os, arch string) (*Asset, erro
 		}
 	}
 
+	// Other projects' names, e.g. tool-x86_64-unknown-linux-musl.tar.gz
+	if asset := MatchPlatformAsset(release.Assets, os, arch); asset != nil {
+		return asset, nil
+	}
+
 	return nil, fmt.Errorf("%w: %s/%s", ErrAssetNotFound, os, arch)
 }
 
+// osAliases lists the names release assets use for each OS
+var osAliases = map[string][]string{
+	"linux":   {"linux"},
+	"darwin":  {"darwin", "macos", "mac", "osx", "apple"},
+	"windows": {"windows", "win", "win64", "win32"},
+	"freebsd": {"freebsd"},
+}
+
+// archAliases lists the names release assets use for each architecture
+var archAliases = map[string][]string{
+	"amd64": {"amd64", "x86_64", "x64", "64bit", "x86-64"},
+	"arm64": {"arm64", "aarch64", "armv8"},
+	"386":   {"386", "i386", "i686", "32bit"},
+	"arm":   {"arm", "armv6", "armv7", "armhf"},
+}
+
+// nonBinaryExtensions are release assets that are never the program itself
+var nonBinaryExtensions = []string{
+	".sha256", ".sha512", ".sha256sum", ".md5", ".sig", ".asc", ".pem", ".cert", ".sbom", ".json",
+	".txt", ".deb", ".rpm", ".apk", ".msi", ".pkg", ".dmg", ".snap", ".flatpak", ".appimage",
+}
+
+// hasToken reports whether name contains token between non-alphanumeric
+// characters, so that "arm" doesn't match "arm64"
+func hasToken(name, token string) bool {
+	for i := 0; ; {
+		idx := strings.Index(name[i:], token)
+		if idx < 0 {
+			return false
+		}
+		start, end := i+idx, i+idx+len(token)
+		before := start == 0 || !isAlphaNum(name[start-1])
+		after := end == len(name) || !isAlphaNum(name[end])
+		if before && after {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+// isAlphaNum reports whether c is an ASCII letter or digit
+func isAlphaNum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+// hasAnyToken reports whether name contains one of tokens
+func hasAnyToken(name string, tokens []string) bool {
+	for _, t := range tokens {
+		if hasToken(name, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPlatformAsset picks the asset built for os/arch by the common naming
+// patterns (x86_64, aarch64, macos, ...). Assets without an architecture,
+// such as macOS universal builds, are used when nothing more specific fits.
+func MatchPlatformAsset(assets []Asset, os, arch string) *Asset {
+	var best *Asset
+	bestScore := 0
+	for i := range assets {
+		name := strings.ToLower(assets[i].Name)
+		if IsChecksumFile(name) || !hasAnyToken(name, osAliases[os]) {
+			continue
+		}
+		if hasSuffixAny(name, nonBinaryExtensions) {
+			continue
+		}
+
+		score := 0
+		switch {
+		case hasAnyToken(name, archAliases[arch]):
+			score = 10
+		case hasToken(name, "universal") && os == "darwin":
+			score = 5
+		case !hasAnyArch(name):
+			score = 3
+		default:
+			continue // built for another architecture
+		}
+		if strings.HasSuffix(name, GetArchiveExtension(os)) {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = &assets[i], score
+		}
+	}
+	return best
+}
+
+// hasAnyArch reports whether name mentions any known architecture
+func hasAnyArch(name string) bool {
+	for _, aliases := range archAliases {
+		if hasAnyToken(name, aliases) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSuffixAny reports whether name ends with one of suffixes
+func hasSuffixAny(name string, suffixes []string) bool {
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
 
 // IsSupportedPlatform checks if the given OS/Arch combination is supported
 func IsSupportedPlatform(os, arch string) bool {
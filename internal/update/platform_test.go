@@ -80,6 +80,45 @@ func TestSelectAssetForPlatform(t *testing.T) {
 	}
 }
 
+func TestMatchPlatformAsset(t *testing.T) {
+	assets := []Asset{
+		{Name: "tool-v1.2.0-x86_64-unknown-linux-musl.tar.gz"},
+		{Name: "tool-v1.2.0-aarch64-unknown-linux-musl.tar.gz"},
+		{Name: "tool-v1.2.0-x86_64-unknown-linux-musl.tar.gz.sha256"},
+		{Name: "tool_1.2.0_amd64.deb"},
+		{Name: "tool-v1.2.0-universal-apple-darwin.tar.gz"},
+		{Name: "tool-v1.2.0-x86_64-pc-windows-msvc.zip"},
+		{Name: "tool-v1.2.0-armv7-unknown-linux-gnueabihf.tar.gz"},
+		{Name: "checksums.txt"},
+	}
+
+	tests := []struct {
+		os       string
+		arch     string
+		expected string
+	}{
+		{"linux", "amd64", "tool-v1.2.0-x86_64-unknown-linux-musl.tar.gz"},
+		{"linux", "arm64", "tool-v1.2.0-aarch64-unknown-linux-musl.tar.gz"},
+		{"linux", "arm", "tool-v1.2.0-armv7-unknown-linux-gnueabihf.tar.gz"},
+		{"darwin", "arm64", "tool-v1.2.0-universal-apple-darwin.tar.gz"},
+		{"windows", "amd64", "tool-v1.2.0-x86_64-pc-windows-msvc.zip"},
+		{"windows", "arm64", ""},
+		{"linux", "386", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.os+"-"+tt.arch, func(t *testing.T) {
+			got := ""
+			if asset := MatchPlatformAsset(assets, tt.os, tt.arch); asset != nil {
+				got = asset.Name
+			}
+			if got != tt.expected {
+				t.Errorf("MatchPlatformAsset(%q, %q) = %q, want %q", tt.os, tt.arch, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestGetPlatformDisplayName(t *testing.T) {
 	tests := []struct {
 		os       string
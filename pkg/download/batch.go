@@ -0,0 +1,215 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dwirx/ghex/internal/platform"
+	"github.com/dwirx/ghex/internal/ui"
+	"github.com/dwirx/ghex/internal/update"
+)
+
+// BatchOptions configures downloading several URLs
+type BatchOptions struct {
+	Parallel     int           // concurrent downloads
+	Retries      int           // retries per URL after the first attempt
+	Backoff      time.Duration // delay before the first retry, doubled for each further one
+	FailuresFile string        // where failed URLs are written, one per line (optional)
+}
+
+// DefaultBatchOptions returns default batch options
+func DefaultBatchOptions() BatchOptions {
+	return BatchOptions{
+		Parallel: 4,
+		Retries:  2,
+		Backoff:  time.Second,
+	}
+}
+
+// BatchResult is the outcome of a batch download
+type BatchResult struct {
+	Succeeded []string
+	Skipped   []string // output file already existed
+	Failed    []string
+	Errors    map[string]error
+}
+
+// Item is one download of a batch
+type Item struct {
+	URL     string
+	Options Options
+	Entry   *ManifestEntry // manifest entry the item came from, if any
+}
+
+// errUpToDate means an existing file already matches the expected checksum
+var errUpToDate = errors.New("already downloaded and verified")
+
+// Batch downloads urls with the same options; see BatchItems
+func Batch(urls []string, opts Options, batch BatchOptions) (*BatchResult, error) {
+	items := make([]Item, len(urls))
+	for i, url := range urls {
+		items[i] = Item{URL: url, Options: opts}
+	}
+	return BatchItems(items, batch)
+}
+
+// BatchItems downloads items with a bounded worker pool, retrying failed
+// URLs with exponential backoff. Existing files are skipped, or replaced
+// when they don't match the item's checksum. It shows a summary and returns
+// the failures joined into one error.
+func BatchItems(items []Item, batch BatchOptions) (*BatchResult, error) {
+	parallel := min(max(batch.Parallel, 1), max(len(items), 1))
+
+	result := &BatchResult{Errors: make(map[string]error)}
+	errs := make([]error, len(items))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				url := items[i].URL
+				opts := items[i].Options
+				if parallel > 1 {
+					// Progress lines of concurrent downloads would overwrite each other
+					opts.ShowProgress = false
+				}
+				err := downloadItem(url, opts, batch)
+
+				mu.Lock()
+				switch {
+				case err == nil:
+					result.Succeeded = append(result.Succeeded, url)
+				case errors.Is(err, errUpToDate):
+					result.Skipped = append(result.Skipped, url)
+					ui.ShowInfo(fmt.Sprintf("Skipped %s: %v", url, err))
+				case errors.Is(err, ErrFileExists):
+					result.Skipped = append(result.Skipped, url)
+					ui.ShowWarning(fmt.Sprintf("Skipped %s: %v", url, err))
+				default:
+					result.Failed = append(result.Failed, url)
+					result.Errors[url] = err
+					errs[i] = fmt.Errorf("%s: %w", url, err)
+					ui.ShowError(fmt.Sprintf("Failed to download %s: %v", url, err))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	showBatchSummary(result)
+	if batch.FailuresFile != "" {
+		if err := writeFailuresFile(batch.FailuresFile, items, result); err != nil {
+			ui.ShowWarning(err.Error())
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// downloadItem downloads url unless the output file exists and matches
+// opts.Verify, in which case it returns errUpToDate
+func downloadItem(url string, opts Options, batch BatchOptions) error {
+	if filename := outputFilename(url, opts); filename != "" && opts.Verify != nil {
+		outputPath := filepath.Join(opts.OutputDir, filename)
+		if platform.FileExists(outputPath) {
+			checksum, algorithm, err := opts.Verify.expected(filename)
+			if err == nil && checksum != "" && update.VerifyChecksumWith(outputPath, checksum, algorithm) == nil {
+				return errUpToDate
+			}
+			// A stale or damaged copy is replaced
+			opts.Overwrite = true
+		}
+	}
+	return downloadWithRetry(url, opts, batch)
+}
+
+// downloadWithRetry downloads url, retrying errors that may be temporary
+func downloadWithRetry(url string, opts Options, batch BatchOptions) error {
+	delay := batch.Backoff
+	var err error
+	for attempt := 0; attempt <= batch.Retries; attempt++ {
+		if attempt > 0 {
+			ui.ShowInfo(fmt.Sprintf("Retrying %s in %s (%d/%d)", url, delay, attempt, batch.Retries))
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = FromURL(url, opts); err == nil || !isRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// isRetryable reports whether a failed download may succeed when retried
+func isRetryable(err error) bool {
+	if errors.Is(err, ErrFileExists) || errors.Is(err, ErrNoChecksum) || errors.Is(err, update.ErrChecksumMismatch) {
+		return false
+	}
+	var status *StatusError
+	if errors.As(err, &status) && status.Code >= 400 && status.Code < 500 {
+		return status.Code == http.StatusRequestTimeout || status.Code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// oneLine returns the message of err on a single line
+func oneLine(err error) string {
+	return strings.ReplaceAll(err.Error(), "\n", " ")
+}
+
+// showBatchSummary prints the counts of a batch download
+func showBatchSummary(result *BatchResult) {
+	ui.ShowSection("Download Summary")
+	ui.ShowKeyValue("Succeeded", fmt.Sprintf("%d", len(result.Succeeded)))
+	ui.ShowKeyValue("Skipped", fmt.Sprintf("%d", len(result.Skipped)))
+	ui.ShowKeyValue("Failed", fmt.Sprintf("%d", len(result.Failed)))
+}
+
+// writeFailuresFile writes the failed items in input order as a URL list, or
+// a manifest for items from one, that can be downloaded again. The file is
+// removed when nothing failed.
+func writeFailuresFile(path string, items []Item, result *BatchResult) error {
+	if len(result.Failed) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove failures file: %w", err)
+		}
+		return nil
+	}
+
+	header := fmt.Sprintf("Failed downloads, %s", time.Now().Format(time.RFC3339))
+	var data []byte
+	if len(items) > 0 && items[0].Entry != nil {
+		var err error
+		if data, err = failuresManifest(header, items, result); err != nil {
+			return fmt.Errorf("failed to write failures file: %w", err)
+		}
+	} else {
+		var b strings.Builder
+		fmt.Fprintf(&b, "# %s\n", header)
+		for _, item := range items {
+			if err, ok := result.Errors[item.URL]; ok {
+				fmt.Fprintf(&b, "# %s\n%s\n", oneLine(err), item.URL)
+			}
+		}
+		data = []byte(b.String())
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write failures file: %w", err)
+	}
+	ui.ShowInfo(fmt.Sprintf("Failed URLs written to %s", path))
+	return nil
+}
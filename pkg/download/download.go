@@ -1,6 +1,7 @@
 package download
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -23,7 +24,26 @@ type Options struct {
 	FollowRedirects bool
 	UserAgent       string
 	Headers         map[string]string
-	Timeout         time.Duration
+	Timeout         time.Duration   // limit for the whole request including the body (0: none)
+	Connections     int             // parallel range requests for large files (0 or 1: single connection)
+	Verify          *Verification   // checksum the file must match before it is moved into place
+	Extract         *ExtractOptions // unpack the downloaded archive when set
+}
+
+// Errors that retrying a download won't fix
+var (
+	ErrFileExists = errors.New("file already exists")
+	ErrNoChecksum = errors.New("no checksum for file")
+)
+
+// StatusError is an unexpected HTTP status from the server
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.Code, e.Status)
 }
 
 // DefaultOptions returns default download options
@@ -35,36 +55,44 @@ func DefaultOptions() Options {
 	}
 }
 
-// FromURL downloads a file from a URL
+// FromURL downloads a file from a URL. The file is written to
+// <output>.part and renamed when complete; an interrupted download is
+// resumed on the next call when the server supports range requests.
 func FromURL(url string, opts Options) error {
-	// Create HTTP client
-	client := &http.Client{
-		Timeout: opts.Timeout,
+	// Show info if requested
+	if opts.ShowInfo {
+		ui.ShowInfo(fmt.Sprintf("URL: %s", url))
 	}
 
-	if !opts.FollowRedirects {
-		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
+	if opts.Connections > 1 {
+		if handled, err := segmentedDownload(url, opts); handled {
+			return err
 		}
 	}
+	client := newHTTPClient(opts)
 
-	// Create request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	// The output path is known up front unless the name comes from Content-Disposition
+	outputPath := ""
+	if filename := outputFilename(url, opts); filename != "" {
+		var err error
+		if outputPath, err = prepareOutputPath(filename, opts); err != nil {
+			return err
+		}
 	}
 
-	// Set headers
-	if opts.UserAgent != "" {
-		req.Header.Set("User-Agent", opts.UserAgent)
-	}
-	for k, v := range opts.Headers {
-		req.Header.Set(k, v)
+	var meta *partMeta
+	var offset int64
+	if outputPath != "" {
+		meta, offset = loadPartial(outputPath, url)
 	}
 
-	// Show info if requested
-	if opts.ShowInfo {
-		ui.ShowInfo(fmt.Sprintf("URL: %s", url))
+	req, err := newRequest(url, opts)
+	if err != nil {
+		return err
+	}
+	if meta != nil {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", meta.validator())
 	}
 
 	// Execute request
@@ -74,63 +102,174 @@ func FromURL(url string, opts Options) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// A full response: no partial download, the server ignored the range, or the file changed
+		meta, offset = nil, 0
+	case http.StatusPartialContent:
+		if meta == nil || contentRangeStart(resp.Header.Get("Content-Range")) != offset {
+			return fmt.Errorf("unexpected partial response: %s", resp.Header.Get("Content-Range"))
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		if meta != nil && meta.Size == offset {
+			// Complete but not yet moved into place
+			return completeDownload(outputPath, opts, fmt.Sprintf("Downloaded: %s (%d bytes)", outputPath, offset))
+		}
+		if meta != nil {
+			removePartial(outputPath)
+			return FromURL(url, opts)
+		}
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	default:
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status}
 	}
 
-	// Determine output filename
-	filename := opts.Output
-	if filename == "" {
-		filename = getFilenameFromURL(url)
-		if filename == "" {
-			filename = getFilenameFromHeader(resp.Header.Get("Content-Disposition"))
-		}
+	// Determine output filename from the response
+	if outputPath == "" {
+		filename := getFilenameFromHeader(resp.Header.Get("Content-Disposition"))
 		if filename == "" {
 			filename = "download"
 		}
-	}
-
-	// Determine output path
-	outputPath := filename
-	if opts.OutputDir != "" {
-		if err := platform.EnsureDir(opts.OutputDir, 0755); err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
+		if outputPath, err = prepareOutputPath(filename, opts); err != nil {
+			return err
 		}
-		outputPath = filepath.Join(opts.OutputDir, filename)
 	}
 
-	// Check if file exists
-	if !opts.Overwrite && platform.FileExists(outputPath) {
-		return fmt.Errorf("file already exists: %s (use --overwrite to replace)", outputPath)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if meta != nil {
+		flags = os.O_WRONLY | os.O_APPEND
+		if opts.ShowProgress {
+			ui.ShowInfo(fmt.Sprintf("Resuming at %s", formatSize(offset)))
+		}
+	} else {
+		meta = &partMeta{
+			URL:          url,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Size:         max(resp.ContentLength, 0),
+		}
+		if err := savePartMeta(outputPath, meta); err != nil {
+			return err
+		}
 	}
 
-	// Create output file
-	out, err := os.Create(outputPath)
+	out, err := os.OpenFile(partPath(outputPath), flags, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer out.Close()
 
 	// Download with progress
 	var reader io.Reader = resp.Body
-	if opts.ShowProgress && resp.ContentLength > 0 {
+	if opts.ShowProgress && meta.Size > 0 {
 		reader = &progressReader{
-			reader: resp.Body,
-			total:  resp.ContentLength,
+			reader:  resp.Body,
+			total:   meta.Size,
+			current: offset,
 		}
 	}
 
 	written, err := io.Copy(out, reader)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
+	closeErr := out.Close()
 
 	if opts.ShowProgress {
 		fmt.Println() // New line after progress
 	}
 
-	ui.ShowSuccess(fmt.Sprintf("Downloaded: %s (%d bytes)", outputPath, written))
-	return nil
+	total := offset + written
+	if err != nil {
+		return fmt.Errorf("download interrupted after %s, run again to resume: %w", formatSize(total), err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to write file: %w", closeErr)
+	}
+	if meta.Size > 0 && total != meta.Size {
+		return fmt.Errorf("download incomplete (%d of %d bytes), run again to resume", total, meta.Size)
+	}
+
+	return completeDownload(outputPath, opts, fmt.Sprintf("Downloaded: %s (%d bytes)", outputPath, total))
+}
+
+// responseHeaderTimeout bounds waiting for a server to start responding, so
+// downloads without a Timeout don't hang on unresponsive servers
+const responseHeaderTimeout = 30 * time.Second
+
+// newHTTPClient creates the HTTP client for opts
+func newHTTPClient(opts Options) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+	}
+
+	if !opts.FollowRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		return client
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		// net/http drops Authorization on redirects to other hosts, but not
+		// token headers it doesn't know about
+		if req.URL.Host != via[0].URL.Host {
+			for _, h := range credentialHeaders {
+				req.Header.Del(h)
+			}
+		}
+		return nil
+	}
+	return client
+}
+
+// credentialHeaders are non-standard headers that carry tokens
+var credentialHeaders = []string{"Private-Token", "Job-Token", "Deploy-Token"}
+
+// newRequest creates a GET request with the configured headers
+func newRequest(url string, opts Options) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Byte offsets must refer to the file, not a compressed transfer of it
+	req.Header.Set("Accept-Encoding", "identity")
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
+	}
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// prepareOutputPath returns the output path for filename, creating the
+// output directory and refusing to replace files unless opts.Overwrite
+func prepareOutputPath(filename string, opts Options) (string, error) {
+	outputPath := filename
+	if opts.OutputDir != "" {
+		if err := platform.EnsureDir(opts.OutputDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create output directory: %w", err)
+		}
+		outputPath = filepath.Join(opts.OutputDir, filename)
+	}
+
+	// Check if file exists
+	if !opts.Overwrite && platform.FileExists(outputPath) {
+		return "", fmt.Errorf("%w: %s (use --overwrite to replace)", ErrFileExists, outputPath)
+	}
+	return outputPath, nil
+}
+
+// outputFilename returns the name to save url as, or "" when only the
+// response can tell
+func outputFilename(url string, opts Options) string {
+	if opts.Output != "" {
+		return opts.Output
+	}
+	return getFilenameFromURL(url)
 }
 
 // getFilenameFromURL extracts filename from URL
@@ -183,12 +322,8 @@ func (pr *progressReader) Read(p []byte) (int, error) {
 	return n, err
 }
 
-// Multiple downloads multiple files from URLs
+// Multiple downloads multiple files from URLs with the default batch options
 func Multiple(urls []string, opts Options) error {
-	for _, url := range urls {
-		if err := FromURL(url, opts); err != nil {
-			ui.ShowError(fmt.Sprintf("Failed to download %s: %v", url, err))
-		}
-	}
-	return nil
+	_, err := Batch(urls, opts, DefaultBatchOptions())
+	return err
 }
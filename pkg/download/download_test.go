@@ -0,0 +1,510 @@
+package download
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dwirx/ghex/internal/platform"
+)
+
+// testContent returns deterministic file content of n bytes
+func testContent(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+// serveFile returns a handler serving content with an ETag and range support
+func serveFile(content []byte, etag string) http.HandlerFunc {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "file.bin", modTime, bytes.NewReader(content))
+	}
+}
+
+// testOptions returns options writing into dir
+func testOptions(dir string) Options {
+	opts := DefaultOptions()
+	opts.OutputDir = dir
+	return opts
+}
+
+// TestFromURLResumesInterruptedDownload tests resuming after a dropped connection
+func TestFromURLResumesInterruptedDownload(t *testing.T) {
+	content := testContent(256 * 1024)
+	var requests, ranged atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			ranged.Add(1)
+		}
+		if requests.Add(1) == 1 {
+			// Drop the connection halfway through the first response
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", "262144")
+			w.Write(content[:100000])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		serveFile(content, `"v1"`)(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	url := server.URL + "/file.bin"
+	outputPath := filepath.Join(dir, "file.bin")
+
+	if err := FromURL(url, testOptions(dir)); err == nil || !strings.Contains(err.Error(), "run again to resume") {
+		t.Fatalf("Expected an interrupted download, got %v", err)
+	}
+	if info, err := os.Stat(partPath(outputPath)); err != nil || info.Size() != 100000 {
+		t.Fatalf("Expected a 100000 byte partial file, got %v %v", info, err)
+	}
+	if platform.FileExists(outputPath) {
+		t.Fatal("Expected no output file before completion")
+	}
+
+	if err := FromURL(url, testOptions(dir)); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(outputPath)
+	if !bytes.Equal(got, content) {
+		t.Errorf("Resumed file differs (%d bytes)", len(got))
+	}
+	if ranged.Load() != 1 {
+		t.Errorf("Expected one range request, got %d", ranged.Load())
+	}
+	if platform.FileExists(partPath(outputPath)) || platform.FileExists(partMetaPath(outputPath)) {
+		t.Error("Expected the partial file and metadata to be removed")
+	}
+}
+
+// TestFromURLRestartsWhenRangeIgnored tests servers without range support and changed files
+func TestFromURLRestartsWhenRangeIgnored(t *testing.T) {
+	content := testContent(64 * 1024)
+	tests := map[string]http.HandlerFunc{
+		"ignores ranges": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			w.Write(content)
+		},
+		"file changed": serveFile(content, `"v2"`),
+	}
+
+	for name, handler := range tests {
+		server := httptest.NewServer(handler)
+		dir := t.TempDir()
+		url := server.URL + "/file.bin"
+		outputPath := filepath.Join(dir, "file.bin")
+
+		// A stale partial download of an older version
+		os.WriteFile(partPath(outputPath), []byte("stale data"), 0644)
+		savePartMeta(outputPath, &partMeta{URL: url, ETag: `"v1"`, Size: int64(len(content))})
+
+		if err := FromURL(url, testOptions(dir)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, _ := os.ReadFile(outputPath)
+		if !bytes.Equal(got, content) {
+			t.Errorf("%s: expected a full download, got %d bytes", name, len(got))
+		}
+		server.Close()
+	}
+}
+
+// TestFromURLKeepsExistingFile tests that files are not replaced without Overwrite
+func TestFromURLKeepsExistingFile(t *testing.T) {
+	server := httptest.NewServer(serveFile(testContent(10), `"v1"`))
+	defer server.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "file.bin"), []byte("keep"), 0644)
+
+	if err := FromURL(server.URL+"/file.bin", testOptions(dir)); err == nil {
+		t.Error("Expected an error for an existing file")
+	}
+
+	opts := testOptions(dir)
+	opts.Overwrite = true
+	if err := FromURL(server.URL+"/file.bin", opts); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "file.bin")); len(got) != 10 {
+		t.Errorf("Expected the file to be replaced, got %q", got)
+	}
+}
+
+// TestFromURLSegmented tests fetching a large file over several connections
+func TestFromURLSegmented(t *testing.T) {
+	content := testContent(4 << 20)
+	var ranged, inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rng := r.Header.Get("Range"); rng != "" && rng != "bytes=0-0" {
+			ranged.Add(1)
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for m := maxInFlight.Load(); n > m && !maxInFlight.CompareAndSwap(m, n); m = maxInFlight.Load() {
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		serveFile(content, `"v1"`)(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	opts := testOptions(dir)
+	opts.Connections = 4
+	if err := FromURL(server.URL+"/big.bin", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	outputPath := filepath.Join(dir, "big.bin")
+	got, _ := os.ReadFile(outputPath)
+	if !bytes.Equal(got, content) {
+		t.Errorf("Segmented file differs (%d bytes)", len(got))
+	}
+	if ranged.Load() != 4 || maxInFlight.Load() < 2 {
+		t.Errorf("Expected 4 concurrent range requests, got %d (max %d at once)", ranged.Load(), maxInFlight.Load())
+	}
+	if platform.FileExists(partPath(outputPath)) || platform.FileExists(partMetaPath(outputPath)) {
+		t.Error("Expected the partial file and metadata to be removed")
+	}
+}
+
+// TestFromURLSegmentedRetriesAndResumes tests retrying a failed segment and
+// resuming one that failed every attempt
+func TestFromURLSegmentedRetriesAndResumes(t *testing.T) {
+	content := testContent(4 << 20)
+	var failing atomic.Bool
+	var failures atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The second segment drops after 1000 bytes while failing is set,
+		// and always on its first attempt
+		rng := r.Header.Get("Range")
+		if strings.HasSuffix(rng, "-2097151") && (failing.Load() || failures.Load() == 0) {
+			failures.Add(1)
+			start := contentRangeStart("bytes " + strings.TrimPrefix(rng, "bytes="))
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-2097151/4194304", start))
+			w.Header().Set("Content-Length", fmt.Sprint(2097152-start))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[start : start+1000])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		serveFile(content, `"v1"`)(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	url := server.URL + "/big.bin"
+	outputPath := filepath.Join(dir, "big.bin")
+	opts := testOptions(dir)
+	opts.Connections = 4
+
+	// Every attempt fails: the download stops with its progress saved
+	failing.Store(true)
+	if err := FromURL(url, opts); err == nil || !strings.Contains(err.Error(), "run again to resume") {
+		t.Fatalf("Expected an interrupted download, got %v", err)
+	}
+	if failures.Load() != segmentRetries+1 {
+		t.Errorf("Expected %d attempts, got %d", segmentRetries+1, failures.Load())
+	}
+	meta, err := readPartMeta(outputPath)
+	if err != nil || len(meta.Segments) != 4 || meta.Segments[1].Done != 1000*(segmentRetries+1) || meta.Segments[0].remaining() != 0 {
+		t.Fatalf("Expected saved segment progress, got %+v %v", meta, err)
+	}
+
+	// The next run resumes the failed segment only; its first attempt is retried
+	failing.Store(false)
+	failures.Store(0)
+	if err := FromURL(url, opts); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(outputPath)
+	if !bytes.Equal(got, content) {
+		t.Errorf("Resumed file differs (%d bytes)", len(got))
+	}
+}
+
+// TestFromURLSegmentedFallsBack tests servers without range support
+func TestFromURLSegmentedFallsBack(t *testing.T) {
+	content := testContent(4 << 20)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	opts := testOptions(dir)
+	opts.Connections = 4
+	if err := FromURL(server.URL+"/big.bin", opts); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "big.bin"))
+	if !bytes.Equal(got, content) {
+		t.Errorf("Expected a full download, got %d bytes", len(got))
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected a probe and one download, got %d requests", requests.Load())
+	}
+}
+
+// TestBatch tests concurrent downloads with retries, skips and failures
+func TestBatch(t *testing.T) {
+	var flaky atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing.bin":
+			http.NotFound(w, r)
+		case "/flaky.bin":
+			if flaky.Add(1) < 3 {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+			fallthrough
+		default:
+			serveFile(testContent(1000), `"v1"`)(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "existing.bin"), []byte("keep"), 0644)
+	urls := []string{server.URL + "/a.bin", server.URL + "/missing.bin", server.URL + "/flaky.bin", server.URL + "/existing.bin", server.URL + "/b.bin"}
+	failuresFile := filepath.Join(dir, "failed.txt")
+
+	result, err := Batch(urls, testOptions(dir), BatchOptions{Parallel: 3, Retries: 2, Backoff: time.Millisecond, FailuresFile: failuresFile})
+	if err == nil || !strings.Contains(err.Error(), "missing.bin") {
+		t.Fatalf("Expected an error for missing.bin, got %v", err)
+	}
+	if len(result.Succeeded) != 3 || len(result.Skipped) != 1 || len(result.Failed) != 1 {
+		t.Errorf("Expected 3 succeeded, 1 skipped, 1 failed, got %+v", result)
+	}
+	if flaky.Load() != 3 {
+		t.Errorf("Expected flaky.bin to be fetched 3 times, got %d", flaky.Load())
+	}
+
+	data, _ := os.ReadFile(failuresFile)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); lines[len(lines)-1] != urls[1] || strings.Contains(string(data), "flaky") {
+		t.Errorf("Expected only missing.bin in the failures file, got:\n%s", data)
+	}
+
+	// Skipped downloads are not failures, so this run removes the failures file
+	if _, err := Batch(urls[:1], testOptions(dir), BatchOptions{FailuresFile: failuresFile}); err != nil {
+		t.Fatal(err)
+	}
+	if platform.FileExists(failuresFile) {
+		t.Error("Expected the failures file to be removed")
+	}
+}
+
+// TestFromURLVerifiesChecksum tests that files failing verification are deleted
+func TestFromURLVerifiesChecksum(t *testing.T) {
+	content := testContent(1000)
+	server := httptest.NewServer(serveFile(content, `"v1"`))
+	defer server.Close()
+
+	sum := sha256.Sum256(content)
+	good := hex.EncodeToString(sum[:])
+	dir := t.TempDir()
+	checksums := filepath.Join(dir, "SHA256SUMS")
+	os.WriteFile(checksums, []byte(good+"  good.bin\n"+strings.Repeat("0", 64)+"  bad.bin\n"), 0644)
+
+	verify, err := LoadChecksums(checksums, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := testOptions(dir)
+	opts.Verify = verify
+
+	if err := FromURL(server.URL+"/good.bin", opts); err != nil {
+		t.Fatal(err)
+	}
+	if !platform.FileExists(filepath.Join(dir, "good.bin")) {
+		t.Error("Expected the verified file to be kept")
+	}
+
+	for _, name := range []string{"bad.bin", "unlisted.bin"} {
+		err := FromURL(server.URL+"/"+name, opts)
+		if err == nil {
+			t.Errorf("%s: expected a verification error", name)
+		}
+		outputPath := filepath.Join(dir, name)
+		if platform.FileExists(outputPath) || platform.FileExists(partPath(outputPath)) {
+			t.Errorf("%s: expected the download to be deleted", name)
+		}
+	}
+
+	opts.Verify = &Verification{Algorithm: "sha256", Checksum: good}
+	opts.Output = "single.bin"
+	if err := FromURL(server.URL+"/x", opts); err != nil {
+		t.Errorf("Expected --sha256 verification to pass: %v", err)
+	}
+}
+
+// TestLoadManifest tests the YAML, JSON and plain list formats
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"tools.yaml": `dir: tools
+headers: {X-Team: infra}
+downloads:
+  - https://example.com/a.bin
+  - url: https://example.com/b.tar.gz
+    output: b.tgz
+    dir: bin
+    sha256: abc
+    headers: {X-Extra: "1"}
+    account: work
+    extract: true
+`,
+		"tools.json": `[{"url": "https://example.com/a.bin", "dir": "tools"}, {"url": "https://example.com/b.tar.gz", "output": "b.tgz", "dir": "bin", "sha256": "abc", "account": "work", "extract": true}]`,
+		"tools.txt":  "# tools\nhttps://example.com/a.bin\n\nhttps://example.com/b.tar.gz\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0644)
+		m, err := LoadManifest(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(m.Downloads) != 2 || m.Downloads[0].URL != "https://example.com/a.bin" {
+			t.Fatalf("%s: unexpected entries %+v", name, m.Downloads)
+		}
+		if m.Plain != (name == "tools.txt") {
+			t.Errorf("%s: Plain = %v", name, m.Plain)
+		}
+		if name == "tools.txt" {
+			continue
+		}
+
+		a, b := m.Downloads[0], m.Downloads[1]
+		if a.Dir != "tools" || b.Dir != "bin" || b.Output != "b.tgz" || b.Account != "work" || !b.Extract {
+			t.Errorf("%s: unexpected entries %+v", name, m.Downloads)
+		}
+		if v := b.Verification(); v == nil || v.Checksum != "abc" {
+			t.Errorf("%s: expected a sha256 verification", name)
+		}
+	}
+
+	yamlManifest, _ := LoadManifest(filepath.Join(dir, "tools.yaml"))
+	if h := yamlManifest.Downloads[1].Headers; h["X-Team"] != "infra" || h["X-Extra"] != "1" {
+		t.Errorf("Expected merged headers, got %v", h)
+	}
+
+	// A misspelt checksum field is an error rather than an unverified download
+	typo := filepath.Join(dir, "typo.yml")
+	os.WriteFile(typo, []byte("- url: https://example.com/a.bin\n  sha265: abc\n"), 0644)
+	if _, err := LoadManifest(typo); err == nil || !strings.Contains(err.Error(), "sha265") {
+		t.Errorf("Expected an unknown field error, got %v", err)
+	}
+}
+
+// TestBatchItemsSkipsVerifiedFiles tests that manifests can be run repeatedly
+func TestBatchItemsSkipsVerifiedFiles(t *testing.T) {
+	content := testContent(1000)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		serveFile(content, `"v1"`)(w, r)
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256(content)
+	dir := t.TempDir()
+	opts := testOptions(dir)
+	opts.Verify = &Verification{Algorithm: "sha256", Checksum: hex.EncodeToString(sum[:])}
+	items := []Item{{URL: server.URL + "/tool.bin", Options: opts}}
+
+	for run := 1; run <= 2; run++ {
+		result, err := BatchItems(items, BatchOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run == 2 && len(result.Skipped) != 1 {
+			t.Errorf("Expected the verified file to be skipped, got %+v", result)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Expected one download, got %d", requests.Load())
+	}
+
+	// A damaged copy is downloaded again
+	os.WriteFile(filepath.Join(dir, "tool.bin"), []byte("damaged"), 0644)
+	if result, err := BatchItems(items, BatchOptions{}); err != nil || len(result.Succeeded) != 1 {
+		t.Fatalf("Expected the file to be replaced, got %+v %v", result, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "tool.bin")); !bytes.Equal(got, content) {
+		t.Error("Expected the damaged file to be replaced")
+	}
+}
+
+// TestAuthenticatedDownloads tests that tokens reach the API but not other hosts
+func TestAuthenticatedDownloads(t *testing.T) {
+	content := testContent(1000)
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Private-Token") != "" || r.Header.Get("Authorization") != "" {
+			http.Error(w, "token sent to storage", http.StatusBadRequest)
+			return
+		}
+		serveFile(content, `"v1"`)(w, r)
+	}))
+	defer storage.Close()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Private-Token") != "secret" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Path {
+		case "/repos/owner/private/contents/docs":
+			fmt.Fprintf(w, `[{"name":"a.md","path":"docs/a.md","type":"file","download_url":%q}]`, storage.URL+"/a.md")
+		case "/asset":
+			http.Redirect(w, r, storage.URL+"/asset.bin", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	headers := map[string]string{"PRIVATE-TOKEN": "secret"}
+	parsed := &ParsedGitURL{Platform: "github", APIBase: api.URL, Owner: "owner", Repo: "private", Branch: "main", FilePath: "docs"}
+	if _, err := fetchDirectoryContents(parsed, 1, nil); err == nil || !strings.Contains(err.Error(), "--account") {
+		t.Errorf("Expected an anonymous listing to fail with a hint, got %v", err)
+	}
+	files, err := fetchDirectoryContents(parsed, 1, headers)
+	if err != nil || len(files) != 1 || files[0].Path != "docs/a.md" {
+		t.Fatalf("Expected the private listing, got %v, %v", files, err)
+	}
+
+	// Authenticated asset downloads use the API endpoint, whose redirect
+	// must not carry the token along
+	asset := releaseAsset{Name: "asset.bin", URL: api.URL + "/asset", BrowserDownloadURL: storage.URL + "/public"}
+	url, assetHeaders := asset.request(headers)
+	if url != asset.URL || assetHeaders["Accept"] != "application/octet-stream" {
+		t.Errorf("Expected the API asset endpoint, got %s %v", url, assetHeaders)
+	}
+	opts := testOptions(t.TempDir())
+	opts.Headers = assetHeaders
+	if err := FromURL(url, opts); err != nil {
+		t.Fatal(err)
+	}
+	if url, _ := asset.request(nil); url != asset.BrowserDownloadURL {
+		t.Errorf("Expected the browser URL without authentication, got %s", url)
+	}
+}
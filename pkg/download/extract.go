@@ -0,0 +1,408 @@
+package download
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// ErrUnsafePath means an archive entry would be written outside the target directory
+var ErrUnsafePath = errors.New("unsafe path in archive")
+
+// ExtractOptions configures archive extraction
+type ExtractOptions struct {
+	Dir             string   // target directory (default: the archive's directory)
+	StripComponents int      // leading path elements removed from each entry
+	Include         []string // globs of entries to extract (default: all)
+	Exclude         []string // globs of entries to skip
+}
+
+// archiveFormats maps file extensions to archive formats, longest first
+var archiveFormats = []struct {
+	ext    string
+	format string
+}{
+	{".tar.gz", "tar.gz"}, {".tgz", "tar.gz"},
+	{".tar.xz", "tar.xz"}, {".txz", "tar.xz"},
+	{".tar.bz2", "tar.bz2"}, {".tbz2", "tar.bz2"}, {".tbz", "tar.bz2"},
+	{".tar.zst", "tar.zst"}, {".tzst", "tar.zst"},
+	{".tar", "tar"},
+	{".zip", "zip"},
+}
+
+// archiveFormat returns the archive format of a file name, or ""
+func archiveFormat(name string) string {
+	name = strings.ToLower(name)
+	for _, f := range archiveFormats {
+		if strings.HasSuffix(name, f.ext) {
+			return f.format
+		}
+	}
+	return ""
+}
+
+// IsArchive reports whether name has a supported archive extension
+func IsArchive(name string) bool {
+	return archiveFormat(name) != ""
+}
+
+// Extract unpacks a tar.gz, tar.xz, tar.bz2, tar.zst, tar or zip archive
+// and returns the paths it wrote. Entries that would land outside the target
+// directory, directly or through a symlink, are refused.
+func Extract(archivePath string, opts ExtractOptions) ([]string, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = filepath.Dir(archivePath)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	x := &extractor{dir: dir, realDir: realDir, opts: opts}
+
+	format := archiveFormat(archivePath)
+	if format == "zip" {
+		err := x.zip(archivePath)
+		return x.files, err
+	}
+	if format == "" {
+		return nil, fmt.Errorf("unsupported archive format: %s", filepath.Base(archivePath))
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	r, err := decompress(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s archive: %w", format, err)
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	err = x.tar(r)
+	return x.files, err
+}
+
+// decompress wraps r with the decompressor of a tar format
+func decompress(r io.Reader, format string) (io.Reader, error) {
+	switch format {
+	case "tar.gz":
+		return gzip.NewReader(r)
+	case "tar.xz":
+		return xz.NewReader(r)
+	case "tar.bz2":
+		return bzip2.NewReader(r), nil
+	case "tar.zst":
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+	return r, nil
+}
+
+// extractor writes archive entries below dir
+type extractor struct {
+	dir     string
+	realDir string // dir with its symlinks resolved
+	opts    ExtractOptions
+	files   []string
+}
+
+// tar extracts a tar stream
+func (x *extractor) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		target, ok, err := x.target(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = x.mkdir(target)
+		case tar.TypeReg:
+			err = x.writeFile(target, tr, hdr.FileInfo().Mode())
+		case tar.TypeSymlink:
+			err = x.symlink(target, hdr.Linkname)
+		case tar.TypeLink:
+			err = x.hardlink(target, hdr.Linkname)
+		default:
+			// Devices, FIFOs and the like have no place in a download
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// zip extracts a zip file
+func (x *extractor) zip(archivePath string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		target, ok, err := x.target(f.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.mkdir(target)
+		case mode&fs.ModeSymlink != 0:
+			err = x.zipSymlink(target, f)
+		default:
+			err = x.zipFile(target, f, mode)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipFile extracts one regular file of a zip
+func (x *extractor) zipFile(target string, f *zip.File, mode fs.FileMode) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	return x.writeFile(target, rc, mode)
+}
+
+// zipSymlink extracts a zip symlink, whose content is the link target
+func (x *extractor) zipSymlink(target string, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	link, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	return x.symlink(target, string(link))
+}
+
+// target returns where an entry is extracted to, or false when it is
+// stripped or filtered out
+func (x *extractor) target(name string) (string, bool, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", false, fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", false, fmt.Errorf("%w: %s", ErrUnsafePath, name)
+		}
+	}
+
+	parts := strings.Split(strings.Trim(path.Clean(name), "/"), "/")
+	if len(parts) <= x.opts.StripComponents || parts[0] == "." {
+		return "", false, nil
+	}
+	rel := path.Join(parts[x.opts.StripComponents:]...)
+	if !x.selected(rel) {
+		return "", false, nil
+	}
+
+	target := filepath.Join(x.dir, filepath.FromSlash(rel))
+	if err := x.checkParents(target); err != nil {
+		return "", false, err
+	}
+	return target, true, nil
+}
+
+// selected reports whether an entry passes the include and exclude globs.
+// Globs without a slash also match the entry's base name.
+func (x *extractor) selected(rel string) bool {
+	matches := func(patterns []string) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, rel); ok {
+				return true
+			}
+			if !strings.Contains(p, "/") {
+				if ok, _ := path.Match(p, path.Base(rel)); ok {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if matches(x.opts.Exclude) {
+		return false
+	}
+	return len(x.opts.Include) == 0 || matches(x.opts.Include)
+}
+
+// within reports whether target is dir or below it
+func (x *extractor) within(target string) bool {
+	rel, err := filepath.Rel(x.dir, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkParents refuses targets outside dir or below a symlink, which an
+// earlier entry may have pointed anywhere
+func (x *extractor) checkParents(target string) error {
+	if !x.within(target) {
+		return fmt.Errorf("%w: %s", ErrUnsafePath, target)
+	}
+	for p := filepath.Dir(target); p != x.dir && x.within(p); p = filepath.Dir(p) {
+		if info, err := os.Lstat(p); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s is below a symlink", ErrUnsafePath, target)
+		}
+	}
+	return nil
+}
+
+// mkdir creates a directory entry
+func (x *extractor) mkdir(target string) error {
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return nil
+}
+
+// writeFile creates a regular file entry, replacing whatever is at target
+func (x *extractor) writeFile(target string, r io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	// Never write through an existing symlink
+	os.Remove(target)
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode.Perm()|0600)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to extract %s: %w", target, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", target, err)
+	}
+	x.files = append(x.files, target)
+	return nil
+}
+
+// symlink creates a symlink entry whose target must stay inside dir
+func (x *extractor) symlink(target, link string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if filepath.IsAbs(link) || path.IsAbs(link) || !x.linkWithin(filepath.Dir(target), link) {
+		return fmt.Errorf("%w: %s links to %s", ErrUnsafePath, target, link)
+	}
+	// Replacing a directory would change where links through it resolve
+	if info, err := os.Lstat(target); err == nil && info.IsDir() {
+		return fmt.Errorf("%w: %s replaces a directory with a symlink", ErrUnsafePath, target)
+	}
+	os.Remove(target)
+	if err := os.Symlink(link, target); err != nil {
+		return fmt.Errorf("failed to create symlink: %w", err)
+	}
+	x.files = append(x.files, target)
+	return nil
+}
+
+// linkWithin reports whether link, relative to parent, stays inside dir
+// when followed through the entries already extracted. A ".." is only
+// allowed on an existing directory, as a later entry could otherwise turn
+// the component before it into a symlink.
+func (x *extractor) linkWithin(parent, link string) bool {
+	cur, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return false
+	}
+	for _, part := range strings.Split(strings.ReplaceAll(link, "\\", "/"), "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if info, err := os.Lstat(cur); err != nil || !info.IsDir() {
+				return false
+			}
+			cur = filepath.Dir(cur)
+		default:
+			cur = filepath.Join(cur, part)
+			if info, err := os.Lstat(cur); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+				if cur, err = filepath.EvalSymlinks(cur); err != nil {
+					return false
+				}
+			}
+		}
+		rel, err := filepath.Rel(x.realDir, cur)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return false
+		}
+	}
+	return true
+}
+
+// hardlink creates a hard link to an earlier entry of the archive
+func (x *extractor) hardlink(target, link string) error {
+	source, ok, err := x.target(link)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	if info, err := os.Lstat(source); err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s links to %s", ErrUnsafePath, target, link)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	os.Remove(target)
+	if err := os.Link(source, target); err != nil {
+		return fmt.Errorf("failed to create link: %w", err)
+	}
+	x.files = append(x.files, target)
+	return nil
+}
@@ -0,0 +1,193 @@
+package download
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// tarEntry is an entry of a test archive; Link makes it a symlink
+type tarEntry struct {
+	Name, Body, Link string
+}
+
+// writeTar writes entries as a tar archive compressed for name's extension
+func writeTar(t *testing.T, name string, entries []tarEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.Name, Mode: 0755, Size: int64(len(e.Body)), Typeflag: tar.TypeReg}
+		if e.Link != "" {
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.Link, 0
+		} else if strings.HasSuffix(e.Name, "/") {
+			hdr.Typeflag = tar.TypeDir
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(tw, e.Body)
+	}
+	tw.Close()
+
+	var out bytes.Buffer
+	var w io.WriteCloser
+	switch archiveFormat(name) {
+	case "tar.gz":
+		w = gzip.NewWriter(&out)
+	case "tar.xz":
+		w, _ = xz.NewWriter(&out)
+	case "tar.zst":
+		w, _ = zstd.NewWriter(&out)
+	default:
+		t.Fatalf("no writer for %s", name)
+	}
+	w.Write(buf.Bytes())
+	w.Close()
+
+	path := filepath.Join(t.TempDir(), name)
+	os.WriteFile(path, out.Bytes(), 0644)
+	return path
+}
+
+// writeZip writes entries as a zip archive
+func writeZip(t *testing.T, entries []tarEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.Name}
+		body := e.Body
+		if e.Link != "" {
+			hdr.SetMode(os.ModeSymlink | 0777)
+			body = e.Link
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, body)
+	}
+	zw.Close()
+
+	path := filepath.Join(t.TempDir(), "test.zip")
+	os.WriteFile(path, buf.Bytes(), 0644)
+	return path
+}
+
+// relFiles returns files relative to dir, sorted
+func relFiles(dir string, files []string) string {
+	var rel []string
+	for _, f := range files {
+		r, _ := filepath.Rel(dir, f)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	sort.Strings(rel)
+	return strings.Join(rel, ",")
+}
+
+// TestExtractFormats tests every compression with strip and filters
+func TestExtractFormats(t *testing.T) {
+	entries := []tarEntry{
+		{Name: "tool-1.0/"},
+		{Name: "tool-1.0/bin/tool", Body: "binary"},
+		{Name: "tool-1.0/README.md", Body: "readme"},
+		{Name: "tool-1.0/docs/guide.md", Body: "guide"},
+	}
+	archives := []string{
+		writeTar(t, "tool.tar.gz", entries),
+		writeTar(t, "tool.tar.xz", entries),
+		writeTar(t, "tool.tar.zst", entries),
+		writeZip(t, entries),
+	}
+
+	for _, archive := range archives {
+		dir := t.TempDir()
+		files, err := Extract(archive, ExtractOptions{Dir: dir, StripComponents: 1, Exclude: []string{"docs/*"}})
+		if err != nil {
+			t.Fatalf("%s: %v", filepath.Base(archive), err)
+		}
+		if got := relFiles(dir, files); got != "README.md,bin/tool" {
+			t.Errorf("%s: extracted %s", filepath.Base(archive), got)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "bin", "tool")); string(data) != "binary" {
+			t.Errorf("%s: bin/tool contains %q", filepath.Base(archive), data)
+		}
+
+		// Globs without a slash match base names
+		dir = t.TempDir()
+		files, _ = Extract(archive, ExtractOptions{Dir: dir, Include: []string{"*.md"}})
+		if got := relFiles(dir, files); got != "tool-1.0/README.md,tool-1.0/docs/guide.md" {
+			t.Errorf("%s: include extracted %s", filepath.Base(archive), got)
+		}
+	}
+}
+
+// TestExtractRejectsEscapes tests zip-slip and symlink escapes
+func TestExtractRejectsEscapes(t *testing.T) {
+	tests := map[string][]tarEntry{
+		"parent path":       {{Name: "../evil", Body: "x"}},
+		"nested parent":     {{Name: "a/../../evil", Body: "x"}},
+		"absolute path":     {{Name: "/tmp/evil", Body: "x"}},
+		"absolute symlink":  {{Name: "link", Link: "/etc"}},
+		"escaping symlink":  {{Name: "a/link", Link: "../../outside"}},
+		"write via symlink": {{Name: "up", Link: "."}, {Name: "up/sub", Link: ".."}, {Name: "up/sub/evil", Body: "x"}},
+		"chained symlink":   {{Name: "y", Link: "."}, {Name: "z", Link: "y/.."}},
+		"later symlink":     {{Name: "z", Link: "w/.."}, {Name: "w", Link: "."}},
+		"replaced dir":      {{Name: "a/file", Body: "x"}, {Name: "z", Link: "a/.."}, {Name: "a", Link: "."}},
+	}
+
+	for name, entries := range tests {
+		for _, archive := range []string{writeTar(t, "evil.tar.gz", entries), writeZip(t, entries)} {
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "target")
+			_, err := Extract(archive, ExtractOptions{Dir: dir})
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("%s (%s): expected ErrUnsafePath, got %v", name, filepath.Base(archive), err)
+			}
+			if _, err := os.Lstat(filepath.Join(parent, "evil")); err == nil {
+				t.Errorf("%s (%s): file written outside the target", name, filepath.Base(archive))
+			}
+			realDir, _ := filepath.EvalSymlinks(dir)
+			if resolved, err := filepath.EvalSymlinks(filepath.Join(dir, "z")); err == nil && !strings.HasPrefix(resolved+"/", realDir+"/") {
+				t.Errorf("%s (%s): symlink left pointing at %s", name, filepath.Base(archive), resolved)
+			}
+		}
+	}
+
+	// Symlinks that stay inside the target are fine
+	archive := writeTar(t, "ok.tar.gz", []tarEntry{{Name: "bin/tool", Body: "x"}, {Name: "tool", Link: "bin/tool"}, {Name: "bin/self", Link: "../bin/tool"}})
+	if _, err := Extract(archive, ExtractOptions{Dir: t.TempDir()}); err != nil {
+		t.Errorf("Expected an internal symlink to be allowed: %v", err)
+	}
+}
+
+// TestFromURLExtracts tests extracting a downloaded archive next to it
+func TestFromURLExtracts(t *testing.T) {
+	archive := writeTar(t, "tool.tar.gz", []tarEntry{{Name: "tool-1.0/tool", Body: "binary"}})
+	data, _ := os.ReadFile(archive)
+	server := httptest.NewServer(serveFile(data, `"v1"`))
+	defer server.Close()
+
+	dir := t.TempDir()
+	opts := testOptions(dir)
+	opts.Extract = &ExtractOptions{StripComponents: 1}
+	if err := FromURL(server.URL+"/tool.tar.gz", opts); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "tool")); string(got) != "binary" {
+		t.Errorf("Expected the archive to be extracted, got %q", got)
+	}
+}
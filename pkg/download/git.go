@@ -2,15 +2,19 @@ package download
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	neturl "net/url"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/dwirx/ghex/internal/config"
+	"github.com/dwirx/ghex/internal/git"
 	"github.com/dwirx/ghex/internal/platform"
 	"github.com/dwirx/ghex/internal/ui"
+	"github.com/dwirx/ghex/internal/update"
 )
 
 // GitOptions configures git download behavior
@@ -20,6 +24,7 @@ type GitOptions struct {
 	OutputDir string
 	Depth     int
 	Overwrite bool
+	Headers   map[string]string // authentication headers for the platform's API and files
 }
 
 // ReleaseOptions configures release download behavior
@@ -28,16 +33,32 @@ type ReleaseOptions struct {
 	Asset     string
 	OutputDir string
 	ListOnly  bool
+	Verify    *Verification     // checksums to verify assets against; detected from the release when nil
+	Extract   *ExtractOptions   // unpack downloaded archives when set
+	Headers   map[string]string // authentication headers for the platform's API and assets
+}
+
+// releaseAsset is a release asset as listed by the GitHub API
+type releaseAsset struct {
+	Name               string `json:"name"`
+	Size               int64  `json:"size"`
+	URL                string `json:"url"` // API endpoint, the only one serving private assets
+	BrowserDownloadURL string `json:"browser_download_url"`
 }
 
 // ParsedGitURL represents a parsed git URL
 type ParsedGitURL struct {
-	Platform    string // github, gitlab, bitbucket
+	Platform    string // github, gitlab, gitea, bitbucket (flavor for custom platforms)
+	Host        string // web host for self-hosted and custom platforms
+	APIBase     string // API base URL (default: https://api.github.com)
 	Owner       string
 	Repo        string
-	Branch      string
+	Branch      string // branch, tag or commit; the default branch when empty
 	FilePath    string
 	IsDirectory bool
+	Tag         string // release tag of a release URL
+	Commit      string // commit the ref resolved to
+	refPath     string // "<ref>/<path>" of the URL, split by resolveRef as refs may contain slashes
 }
 
 // GitFile downloads a single file from a git repository
@@ -47,16 +68,19 @@ func GitFile(url string, opts GitOptions) error {
 		return err
 	}
 
-	if opts.Branch != "" {
-		parsed.Branch = opts.Branch
-	}
-
 	if parsed.IsDirectory {
 		ui.ShowWarning("This appears to be a directory. Use GitDirectory instead.")
 		return nil
 	}
 
+	setRef(parsed, opts.Branch, opts.Headers)
+
 	rawURL := toRawURL(parsed)
+	headers := opts.Headers
+	if len(opts.Headers) > 0 {
+		// Private files are only served through the API
+		rawURL, headers = apiRawRequest(parsed, opts.Headers)
+	}
 	filename := opts.Output
 	if filename == "" {
 		filename = filepath.Base(parsed.FilePath)
@@ -64,7 +88,7 @@ func GitFile(url string, opts GitOptions) error {
 
 	ui.ShowSection("Downloading File")
 	ui.ShowKeyValue("Repository", fmt.Sprintf("%s/%s", parsed.Owner, parsed.Repo))
-	ui.ShowKeyValue("Branch", parsed.Branch)
+	showRef(parsed)
 	ui.ShowKeyValue("File", parsed.FilePath)
 	fmt.Println()
 
@@ -74,6 +98,7 @@ func GitFile(url string, opts GitOptions) error {
 		Overwrite:       opts.Overwrite,
 		ShowProgress:    true,
 		FollowRedirects: true,
+		Headers:         headers,
 	}
 
 	return FromURL(rawURL, downloadOpts)
@@ -86,24 +111,21 @@ func GitDirectory(url string, opts GitOptions) error {
 		return err
 	}
 
-	if opts.Branch != "" {
-		parsed.Branch = opts.Branch
-	}
-
-	if parsed.Platform != "github" {
-		return fmt.Errorf("directory download only supported for GitHub")
-	}
+	setRef(parsed, opts.Branch, opts.Headers)
 
 	ui.ShowSection("Downloading Directory")
 	ui.ShowKeyValue("Repository", fmt.Sprintf("%s/%s", parsed.Owner, parsed.Repo))
-	ui.ShowKeyValue("Branch", parsed.Branch)
+	showRef(parsed)
 	ui.ShowKeyValue("Path", parsed.FilePath)
 	fmt.Println()
 
-	// Fetch directory contents
-	files, err := fetchDirectoryContents(parsed, opts.Depth)
-	if err != nil {
-		return err
+	// Fetch directory contents; a partial listing is still downloaded
+	files, listErr := fetchDirectoryContents(parsed, opts.Depth, opts.Headers)
+	if listErr != nil {
+		if len(files) == 0 {
+			return listErr
+		}
+		ui.ShowWarning(fmt.Sprintf("Some subdirectories could not be listed: %v", listErr))
 	}
 
 	if len(files) == 0 {
@@ -119,6 +141,7 @@ func GitDirectory(url string, opts GitOptions) error {
 		outputDir = parsed.Repo
 	}
 
+	errs := []error{listErr}
 	successful := 0
 	for _, file := range files {
 		relPath := file.Path
@@ -129,76 +152,66 @@ func GitDirectory(url string, opts GitOptions) error {
 		outputPath := filepath.Join(outputDir, relPath)
 		dir := filepath.Dir(outputPath)
 		if err := platform.EnsureDir(dir, 0755); err != nil {
-			ui.ShowError(fmt.Sprintf("Failed to create directory: %v", err))
+			errs = append(errs, fmt.Errorf("failed to create directory: %w", err))
 			continue
 		}
 
+		fileURL, headers := file.URL, opts.Headers
+		if len(opts.Headers) > 0 {
+			fileParsed := *parsed
+			fileParsed.FilePath = file.Path
+			fileURL, headers = apiRawRequest(&fileParsed, opts.Headers)
+		}
+
 		downloadOpts := Options{
 			Output:          filepath.Base(outputPath),
 			OutputDir:       dir,
 			Overwrite:       opts.Overwrite,
 			ShowProgress:    false,
 			FollowRedirects: true,
+			Headers:         headers,
 		}
 
-		if err := FromURL(file.URL, downloadOpts); err != nil {
-			ui.ShowError(fmt.Sprintf("Failed to download %s: %v", file.Path, err))
+		if err := FromURL(fileURL, downloadOpts); err != nil {
+			errs = append(errs, fmt.Errorf("failed to download %s: %w", file.Path, err))
 		} else {
 			successful++
 		}
 	}
 
+	if err := errors.Join(errs...); err != nil {
+		ui.ShowWarning(fmt.Sprintf("Downloaded %d/%d files to %s", successful, len(files), outputDir))
+		return fmt.Errorf("directory download incomplete: %w", err)
+	}
 	ui.ShowSuccess(fmt.Sprintf("Downloaded %d/%d files to %s", successful, len(files), outputDir))
 	return nil
 }
 
-// GitRelease downloads release assets from GitHub
+// GitRelease downloads release assets from GitHub, GitLab, Gitea or, as
+// Bitbucket has no releases, a Bitbucket repository's downloads
 func GitRelease(url string, opts ReleaseOptions) error {
 	parsed, err := parseGitURL(url)
 	if err != nil {
 		return err
 	}
 
-	if parsed.Platform != "github" {
-		return fmt.Errorf("release download only supported for GitHub")
+	version := opts.Version
+	if version == "" {
+		version = parsed.Tag
 	}
 
-	ui.ShowSection("GitHub Release")
+	ui.ShowSection("Release")
 	ui.ShowKeyValue("Repository", fmt.Sprintf("%s/%s", parsed.Owner, parsed.Repo))
 
-	// Fetch release info
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", parsed.Owner, parsed.Repo)
-	if opts.Version != "" {
-		apiURL = fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/tags/%s", parsed.Owner, parsed.Repo, opts.Version)
-	}
-
-	resp, err := http.Get(apiURL)
+	release, err := fetchRelease(parsed, version, opts.Headers)
 	if err != nil {
 		return fmt.Errorf("failed to fetch release: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("release not found: %s", resp.Status)
-	}
-
-	var release struct {
-		TagName     string `json:"tag_name"`
-		Name        string `json:"name"`
-		PublishedAt string `json:"published_at"`
-		Assets      []struct {
-			Name               string `json:"name"`
-			Size               int64  `json:"size"`
-			BrowserDownloadURL string `json:"browser_download_url"`
-		} `json:"assets"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return fmt.Errorf("failed to parse release: %w", err)
-	}
 
 	ui.ShowKeyValue("Version", release.TagName)
-	ui.ShowKeyValue("Published", release.PublishedAt[:10])
+	if len(release.PublishedAt) >= 10 {
+		ui.ShowKeyValue("Published", release.PublishedAt[:10])
+	}
 	fmt.Println()
 
 	if len(release.Assets) == 0 {
@@ -209,11 +222,7 @@ func GitRelease(url string, opts ReleaseOptions) error {
 	// Filter assets
 	assets := release.Assets
 	if opts.Asset != "" {
-		var filtered []struct {
-			Name               string `json:"name"`
-			Size               int64  `json:"size"`
-			BrowserDownloadURL string `json:"browser_download_url"`
-		}
+		var filtered []releaseAsset
 		for _, a := range assets {
 			if strings.Contains(strings.ToLower(a.Name), strings.ToLower(opts.Asset)) {
 				filtered = append(filtered, a)
@@ -230,8 +239,12 @@ func GitRelease(url string, opts ReleaseOptions) error {
 	// List assets
 	fmt.Println(ui.Primary("Available assets:"))
 	for i, asset := range assets {
-		size := formatSize(asset.Size)
-		fmt.Printf("  %s %s (%s)\n", ui.Dim(fmt.Sprintf("[%d]", i+1)), asset.Name, size)
+		if asset.Size > 0 {
+			fmt.Printf("  %s %s (%s)\n", ui.Dim(fmt.Sprintf("[%d]", i+1)), asset.Name, formatSize(asset.Size))
+		} else {
+			// GitLab release links don't report a size
+			fmt.Printf("  %s %s\n", ui.Dim(fmt.Sprintf("[%d]", i+1)), asset.Name)
+		}
 	}
 	fmt.Println()
 
@@ -245,11 +258,7 @@ func GitRelease(url string, opts ReleaseOptions) error {
 		return nil
 	}
 
-	var toDownload []struct {
-		Name               string `json:"name"`
-		Size               int64  `json:"size"`
-		BrowserDownloadURL string `json:"browser_download_url"`
-	}
+	var toDownload []releaseAsset
 
 	if choice == "all" {
 		toDownload = assets
@@ -262,168 +271,395 @@ func GitRelease(url string, opts ReleaseOptions) error {
 		toDownload = append(toDownload, assets[idx-1])
 	}
 
+	verify := opts.Verify
+	if verify == nil {
+		for _, asset := range release.Assets {
+			if !update.IsChecksumFile(asset.Name) {
+				continue
+			}
+			url, headers := asset.request(opts.Headers)
+			if verify, err = releaseChecksums(asset.Name, url, headers); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
 	// Download selected assets
+	var errs []error
 	for _, asset := range toDownload {
+		url, headers := asset.request(opts.Headers)
 		downloadOpts := Options{
 			Output:          asset.Name,
 			OutputDir:       opts.OutputDir,
 			ShowProgress:    true,
 			FollowRedirects: true,
+			Headers:         headers,
+		}
+		if !update.IsChecksumFile(asset.Name) {
+			downloadOpts.Verify = verify
+		}
+		if IsArchive(asset.Name) {
+			downloadOpts.Extract = opts.Extract
 		}
 
-		if err := FromURL(asset.BrowserDownloadURL, downloadOpts); err != nil {
-			ui.ShowError(fmt.Sprintf("Failed to download %s: %v", asset.Name, err))
+		if err := FromURL(url, downloadOpts); err != nil {
+			errs = append(errs, fmt.Errorf("failed to download %s: %w", asset.Name, err))
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-// parseGitURL parses a git repository URL
+// parseGitURL parses a repository, file, directory or release URL of
+// GitHub, GitLab, Gitea/Forgejo/Codeberg, Bitbucket, a configured custom
+// platform or the custom domain of a configured account
 func parseGitURL(url string) (*ParsedGitURL, error) {
-	parsed := &ParsedGitURL{
-		Branch: "main",
+	rawURL := url
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := neturl.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("unsupported URL format: %s", url)
 	}
 
-	// GitHub patterns
-	githubBlobPattern := regexp.MustCompile(`github\.com/([^/]+)/([^/]+)/blob/([^/]+)/(.+)`)
-	githubTreePattern := regexp.MustCompile(`github\.com/([^/]+)/([^/]+)/tree/([^/]+)/(.+)`)
-	githubRepoPattern := regexp.MustCompile(`github\.com/([^/]+)/([^/]+)`)
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	hp, ok := lookupHost(host)
+	if !ok {
+		return nil, fmt.Errorf("unsupported URL format: %s", url)
+	}
+	parsed := &ParsedGitURL{
+		Platform:    hp.Flavor,
+		Host:        host,
+		APIBase:     hp.APIBase,
+		IsDirectory: true,
+	}
 
-	if matches := githubBlobPattern.FindStringSubmatch(url); matches != nil {
-		parsed.Platform = "github"
-		parsed.Owner = matches[1]
-		parsed.Repo = matches[2]
-		parsed.Branch = matches[3]
-		parsed.FilePath = matches[4]
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if host == "raw.githubusercontent.com" {
+		// raw.githubusercontent.com/<owner>/<repo>/<branch>/<path>
+		if len(segments) < 4 {
+			return nil, fmt.Errorf("unsupported URL format: %s", url)
+		}
+		parsed.Host = "github.com"
+		parsed.Owner, parsed.Repo = segments[0], segments[1]
+		parsed.Branch, parsed.FilePath = segments[2], strings.Join(segments[3:], "/")
+		parsed.refPath = strings.Join(segments[2:], "/")
 		parsed.IsDirectory = false
 		return parsed, nil
 	}
 
-	if matches := githubTreePattern.FindStringSubmatch(url); matches != nil {
-		parsed.Platform = "github"
-		parsed.Owner = matches[1]
-		parsed.Repo = matches[2]
-		parsed.Branch = matches[3]
-		parsed.FilePath = matches[4]
+	if !parseRepoPath(parsed, segments) {
+		return nil, fmt.Errorf("unsupported URL format: %s", url)
+	}
+	if strings.HasSuffix(u.Path, "/") && parsed.FilePath != "" {
 		parsed.IsDirectory = true
-		return parsed, nil
 	}
+	return parsed, nil
+}
 
-	if matches := githubRepoPattern.FindStringSubmatch(url); matches != nil {
-		parsed.Platform = "github"
-		parsed.Owner = matches[1]
-		parsed.Repo = strings.TrimSuffix(matches[2], ".git")
-		parsed.IsDirectory = true
-		return parsed, nil
+// hostPlatform is the platform flavor and API of a host
+type hostPlatform struct {
+	Flavor  string
+	APIBase string
+}
+
+// publicHosts are the hosted platforms known without configuration
+var publicHosts = map[string]hostPlatform{
+	"github.com":                {git.FlavorGitHub, "https://api.github.com"},
+	"raw.githubusercontent.com": {git.FlavorGitHub, "https://api.github.com"},
+	"gitlab.com":                {git.FlavorGitLab, "https://gitlab.com/api/v4"},
+	"codeberg.org":              {git.FlavorGitea, "https://codeberg.org/api/v1"},
+	"bitbucket.org":             {git.FlavorBitbucket, "https://api.bitbucket.org/2.0"},
+}
+
+// accountHosts are the custom domains of configured accounts
+var accountHosts = map[string]hostPlatform{}
+
+// RegisterAccountHosts makes URLs on the custom domains of accounts, such as
+// a self-hosted GitLab, parse as the account's platform
+func RegisterAccountHosts(accounts []config.Account) {
+	accountHosts = map[string]hostPlatform{}
+	for _, acc := range accounts {
+		if acc.Platform == nil || acc.Platform.Domain == "" {
+			continue
+		}
+		flavor := git.GetPlatformFlavor(acc.Platform.Type)
+		if flavor == git.FlavorGeneric {
+			continue
+		}
+		host := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(acc.Platform.Domain, "https://"), "/"))
+		apiBase := strings.TrimSuffix(acc.Platform.ApiUrl, "/")
+		if apiBase == "" {
+			apiBase = customAPIBase(acc.Platform.Type, flavor, host)
+		}
+		accountHosts[host] = hostPlatform{Flavor: flavor, APIBase: apiBase}
 	}
+}
 
-	// GitLab patterns
-	gitlabBlobPattern := regexp.MustCompile(`gitlab\.com/([^/]+)/([^/]+)/-/blob/([^/]+)/(.+)`)
-	gitlabTreePattern := regexp.MustCompile(`gitlab\.com/([^/]+)/([^/]+)/-/tree/([^/]+)/(.+)`)
+// lookupHost returns the platform serving host: a public one, a custom
+// platform from the config file or an account's custom domain
+func lookupHost(host string) (hostPlatform, bool) {
+	if hp, ok := publicHosts[host]; ok {
+		return hp, true
+	}
+	if name, ok := git.MatchCustomPlatform(host); ok {
+		flavor := git.GetPlatformFlavor(name)
+		return hostPlatform{Flavor: flavor, APIBase: customAPIBase(name, flavor, host)}, true
+	}
+	hp, ok := accountHosts[host]
+	return hp, ok
+}
 
-	if matches := gitlabBlobPattern.FindStringSubmatch(url); matches != nil {
-		parsed.Platform = "gitlab"
-		parsed.Owner = matches[1]
-		parsed.Repo = matches[2]
-		parsed.Branch = matches[3]
-		parsed.FilePath = matches[4]
-		parsed.IsDirectory = false
-		return parsed, nil
+// parseRepoPath fills the repository, ref, path and release tag from the
+// path segments of a URL in the layout of the parsed platform
+func parseRepoPath(parsed *ParsedGitURL, segments []string) bool {
+	// GitLab separates the (possibly nested) project path from the rest with "/-/"
+	if parsed.Platform == git.FlavorGitLab {
+		dash := len(segments)
+		for i, seg := range segments {
+			if seg == "-" {
+				dash = i
+				break
+			}
+		}
+		if dash < 2 {
+			return false
+		}
+		parsed.Owner = strings.Join(segments[:dash-1], "/")
+		parsed.Repo = strings.TrimSuffix(segments[dash-1], ".git")
+		if dash < len(segments) {
+			rest := segments[dash+1:]
+			if len(rest) > 0 && rest[0] == "releases" {
+				// /-/releases/<tag>
+				if len(rest) > 1 && rest[1] != "permalink" {
+					parsed.Tag = rest[1]
+				}
+				return true
+			}
+			applyRefPath(parsed, rest, []string{"blob", "raw"}, []string{"tree"})
+		}
+		return true
 	}
 
-	if matches := gitlabTreePattern.FindStringSubmatch(url); matches != nil {
-		parsed.Platform = "gitlab"
-		parsed.Owner = matches[1]
-		parsed.Repo = matches[2]
-		parsed.Branch = matches[3]
-		parsed.FilePath = matches[4]
-		parsed.IsDirectory = true
-		return parsed, nil
+	if len(segments) < 2 || segments[0] == "" {
+		return false
 	}
+	parsed.Owner = segments[0]
+	parsed.Repo = strings.TrimSuffix(segments[1], ".git")
+	rest := segments[2:]
 
-	return nil, fmt.Errorf("unsupported URL format: %s", url)
+	switch {
+	case len(rest) > 0 && (rest[0] == "releases" || rest[0] == "downloads"):
+		// /releases/tag/<tag> and /releases/download/<tag>/<asset>
+		if len(rest) > 2 && (rest[1] == "tag" || rest[1] == "download") {
+			parsed.Tag = rest[2]
+		}
+	case parsed.Platform == git.FlavorGitea:
+		// Gitea: /owner/repo/src/branch/<branch>/<path>
+		if len(rest) > 1 && (rest[1] == "branch" || rest[1] == "tag" || rest[1] == "commit") {
+			rest = append([]string{rest[0]}, rest[2:]...)
+		}
+		applyRefPath(parsed, rest, []string{"raw"}, []string{"src"})
+	case parsed.Platform == git.FlavorBitbucket:
+		// Bitbucket serves files and directories alike from /src/<ref>/<path>;
+		// parseGitURL takes a trailing slash to mean a directory
+		applyRefPath(parsed, rest, []string{"raw", "src"}, nil)
+		if parsed.FilePath == "" {
+			parsed.IsDirectory = true
+		}
+	default:
+		applyRefPath(parsed, rest, []string{"blob", "raw"}, []string{"tree"})
+	}
+	return true
 }
 
-// toRawURL converts a parsed URL to raw download URL
-func toRawURL(parsed *ParsedGitURL) string {
-	switch parsed.Platform {
-	case "github":
-		return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s",
-			parsed.Owner, parsed.Repo, parsed.Branch, parsed.FilePath)
-	case "gitlab":
-		return fmt.Sprintf("https://gitlab.com/%s/%s/-/raw/%s/%s",
-			parsed.Owner, parsed.Repo, parsed.Branch, parsed.FilePath)
+// applyRefPath fills branch and file path from "<kind>/<branch>/<path>"
+// segments, where fileKinds mark files and dirKinds directories. The branch
+// is taken to be one segment until resolveRef asks the platform.
+func applyRefPath(parsed *ParsedGitURL, segments []string, fileKinds, dirKinds []string) {
+	if len(segments) < 2 {
+		return
+	}
+	switch {
+	case slices.Contains(fileKinds, segments[0]):
+		parsed.IsDirectory = false
+	case slices.Contains(dirKinds, segments[0]):
+		parsed.IsDirectory = true
 	default:
-		return ""
+		return
+	}
+	parsed.Branch = segments[1]
+	parsed.FilePath = strings.Join(segments[2:], "/")
+	if len(segments) > 2 {
+		parsed.refPath = strings.Join(segments[1:], "/")
 	}
 }
 
-type fileInfo struct {
-	Path string
-	URL  string
+// setRef applies a ref given by the user, or else resolves the URL's ref,
+// warning when the platform can't be asked
+func setRef(parsed *ParsedGitURL, ref string, headers map[string]string) {
+	if ref != "" {
+		parsed.Branch, parsed.refPath = ref, ""
+	}
+	if err := resolveRef(parsed, headers); err != nil {
+		ui.ShowWarning(fmt.Sprintf("Could not resolve ref: %v", err))
+	}
 }
 
-// fetchDirectoryContents fetches all files in a directory
-func fetchDirectoryContents(parsed *ParsedGitURL, maxDepth int) ([]fileInfo, error) {
-	var files []fileInfo
+// showRef shows the ref of a download and the commit it resolved to
+func showRef(parsed *ParsedGitURL) {
+	ui.ShowKeyValue("Ref", parsed.Branch)
+	if parsed.Commit != "" {
+		ui.ShowKeyValue("Commit", parsed.Commit)
+	}
+}
 
-	var fetchRecursive func(path string, depth int) error
-	fetchRecursive = func(path string, depth int) error {
-		if depth > maxDepth {
-			return nil
-		}
+// customAPIBase returns the API base URL for a user-defined platform or a
+// self-hosted instance of a known one
+func customAPIBase(name, flavor, host string) string {
+	if apiURL := git.GetPlatformAPIURL(name); apiURL != "" {
+		return strings.TrimSuffix(apiURL, "/")
+	}
+	switch flavor {
+	case git.FlavorGitHub:
+		return fmt.Sprintf("https://%s/api/v3", host)
+	case git.FlavorGitLab:
+		return fmt.Sprintf("https://%s/api/v4", host)
+	case git.FlavorGitea:
+		return fmt.Sprintf("https://%s/api/v1", host)
+	case git.FlavorBitbucket:
+		return "https://api.bitbucket.org/2.0"
+	default:
+		return ""
+	}
+}
 
-		apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s?ref=%s",
-			parsed.Owner, parsed.Repo, path, parsed.Branch)
+// apiBase returns the API base URL, defaulting to the public GitHub or
+// GitLab API
+func (p *ParsedGitURL) apiBase() string {
+	if p.APIBase != "" {
+		return p.APIBase
+	}
+	if p.Platform == "gitlab" {
+		return "https://gitlab.com/api/v4"
+	}
+	return "https://api.github.com"
+}
 
-		resp, err := http.Get(apiURL)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
+// request returns the URL and headers to download the asset with. An
+// authenticated download uses the API endpoint, which also serves private
+// assets.
+func (a releaseAsset) request(headers map[string]string) (string, map[string]string) {
+	if len(headers) == 0 || a.URL == "" {
+		return a.BrowserDownloadURL, headers
+	}
+	return a.URL, withHeader(headers, "Accept", "application/octet-stream")
+}
 
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("API error: %s", resp.Status)
-		}
+// apiRawRequest returns the API URL and headers serving a file's raw
+// content, which unlike the web raw URLs accept token authentication
+func apiRawRequest(parsed *ParsedGitURL, headers map[string]string) (string, map[string]string) {
+	switch parsed.Platform {
+	case "github":
+		return fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s", parsed.apiBase(), parsed.Owner, parsed.Repo,
+			parsed.FilePath, neturl.QueryEscape(parsed.Branch)), withHeader(headers, "Accept", "application/vnd.github.raw")
+	case "gitlab":
+		return fmt.Sprintf("%s/projects/%s/repository/files/%s/raw?ref=%s", parsed.apiBase(),
+			neturl.PathEscape(parsed.Owner+"/"+parsed.Repo), neturl.PathEscape(parsed.FilePath),
+			neturl.QueryEscape(parsed.Branch)), headers
+	case "gitea":
+		return fmt.Sprintf("%s/repos/%s/%s/raw/%s?ref=%s", parsed.apiBase(), parsed.Owner, parsed.Repo,
+			parsed.FilePath, neturl.QueryEscape(parsed.Branch)), headers
+	case "bitbucket":
+		return fmt.Sprintf("%s/repositories/%s/%s/src/%s/%s", parsed.apiBase(), parsed.Owner, parsed.Repo,
+			neturl.PathEscape(parsed.Branch), parsed.FilePath), headers
+	default:
+		return toRawURL(parsed), headers
+	}
+}
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
+// withHeader returns a copy of headers with key set to value
+func withHeader(headers map[string]string, key, value string) map[string]string {
+	out := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
 
-		var contents []struct {
-			Name        string `json:"name"`
-			Path        string `json:"path"`
-			Type        string `json:"type"`
-			DownloadURL string `json:"download_url"`
-		}
+// getJSON fetches an API URL with the authentication headers and decodes
+// the JSON response into out
+func getJSON(url string, headers map[string]string, out interface{}) error {
+	_, err := fetchJSON(url, headers, out)
+	return err
+}
 
-		if err := json.Unmarshal(body, &contents); err != nil {
-			return err
-		}
+// fetchJSON is getJSON returning the response headers, through which some
+// APIs paginate
+func fetchJSON(url string, headers map[string]string, out interface{}) (http.Header, error) {
+	opts := DefaultOptions()
+	opts.Headers = headers
+	req, err := newRequest(url, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := newHTTPClient(opts).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-		for _, item := range contents {
-			if item.Type == "file" {
-				files = append(files, fileInfo{
-					Path: item.Path,
-					URL:  item.DownloadURL,
-				})
-			} else if item.Type == "dir" {
-				if err := fetchRecursive(item.Path, depth+1); err != nil {
-					// Continue on error
-				}
+	if resp.StatusCode != http.StatusOK {
+		err := &StatusError{Code: resp.StatusCode, Status: resp.Status}
+		switch resp.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			if len(headers) == 0 {
+				// Private repositories look missing, and anonymous requests are rate limited
+				return nil, fmt.Errorf("%w (private repository or rate limit? authenticate with --account)", err)
 			}
 		}
-
-		return nil
-	}
-
-	if err := fetchRecursive(parsed.FilePath, 0); err != nil {
 		return nil, err
 	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return resp.Header, nil
+}
 
-	return files, nil
+// toRawURL converts a parsed URL to raw download URL
+func toRawURL(parsed *ParsedGitURL) string {
+	switch parsed.Platform {
+	case "github":
+		if parsed.Host != "" && parsed.Host != "github.com" {
+			return fmt.Sprintf("https://%s/%s/%s/raw/%s/%s",
+				parsed.Host, parsed.Owner, parsed.Repo, parsed.Branch, parsed.FilePath)
+		}
+		return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s",
+			parsed.Owner, parsed.Repo, parsed.Branch, parsed.FilePath)
+	case "gitlab":
+		host := parsed.Host
+		if host == "" {
+			host = "gitlab.com"
+		}
+		return fmt.Sprintf("https://%s/%s/%s/-/raw/%s/%s",
+			host, parsed.Owner, parsed.Repo, parsed.Branch, parsed.FilePath)
+	case "gitea":
+		// Gitea's raw URLs name the kind of ref, which only a commit settles
+		if parsed.Commit != "" {
+			return fmt.Sprintf("https://%s/%s/%s/raw/commit/%s/%s",
+				parsed.Host, parsed.Owner, parsed.Repo, parsed.Commit, parsed.FilePath)
+		}
+		return fmt.Sprintf("https://%s/%s/%s/raw/branch/%s/%s",
+			parsed.Host, parsed.Owner, parsed.Repo, parsed.Branch, parsed.FilePath)
+	case "bitbucket":
+		return fmt.Sprintf("https://%s/%s/%s/raw/%s/%s",
+			parsed.Host, parsed.Owner, parsed.Repo, parsed.Branch, parsed.FilePath)
+	default:
+		return ""
+	}
 }
 
 func formatSize(bytes int64) string {
@@ -0,0 +1,260 @@
+package download
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dwirx/ghex/internal/config"
+)
+
+// TestParseGitURL tests the URL layouts of each platform
+func TestParseGitURL(t *testing.T) {
+	RegisterAccountHosts([]config.Account{
+		{Name: "work", Platform: &config.PlatformConfig{Type: "gitlab", Domain: "git.company.com"}},
+		{Name: "forge", Platform: &config.PlatformConfig{Type: "gitea", Domain: "forge.example.org", ApiUrl: "https://forge.example.org/api/v1/"}},
+	})
+	defer RegisterAccountHosts(nil)
+
+	tests := []struct {
+		url      string
+		expected string // platform owner repo branch path isDir tag apiBase
+	}{
+		{"https://github.com/user/repo", "github user repo   true  https://api.github.com"},
+		{"https://github.com/user/repo/blob/dev/cmd/main.go", "github user repo dev cmd/main.go false  https://api.github.com"},
+		{"github.com/user/repo/tree/v1.0/docs", "github user repo v1.0 docs true  https://api.github.com"},
+		{"https://github.com/user/repo/releases/tag/v2.0.0", "github user repo   true v2.0.0 https://api.github.com"},
+		{"https://raw.githubusercontent.com/user/repo/main/README.md", "github user repo main README.md false  https://api.github.com"},
+		{"https://gitlab.com/group/sub/project/-/blob/main/src/app.go", "gitlab group/sub project main src/app.go false  https://gitlab.com/api/v4"},
+		{"https://gitlab.com/group/sub/project/-/tree/main/src", "gitlab group/sub project main src true  https://gitlab.com/api/v4"},
+		{"https://gitlab.com/group/project/-/releases/v1.2", "gitlab group project   true v1.2 https://gitlab.com/api/v4"},
+		{"https://git.company.com/team/infra/tools/-/raw/main/install.sh", "gitlab team/infra tools main install.sh false  https://git.company.com/api/v4"},
+		{"https://codeberg.org/user/repo/src/branch/main/docs", "gitea user repo main docs true  https://codeberg.org/api/v1"},
+		{"https://codeberg.org/user/repo/raw/tag/v1/LICENSE", "gitea user repo v1 LICENSE false  https://codeberg.org/api/v1"},
+		{"https://codeberg.org/user/repo/releases/tag/v3", "gitea user repo   true v3 https://codeberg.org/api/v1"},
+		{"https://forge.example.org/user/repo/src/branch/dev/lib", "gitea user repo dev lib true  https://forge.example.org/api/v1"},
+		{"https://bitbucket.org/team/repo/src/main/setup.py", "bitbucket team repo main setup.py false  https://api.bitbucket.org/2.0"},
+		{"https://bitbucket.org/team/repo/src/main/docs/", "bitbucket team repo main docs true  https://api.bitbucket.org/2.0"},
+		{"https://bitbucket.org/team/repo/downloads", "bitbucket team repo   true  https://api.bitbucket.org/2.0"},
+	}
+
+	for _, tt := range tests {
+		p, err := parseGitURL(tt.url)
+		if err != nil {
+			t.Errorf("parseGitURL(%q): %v", tt.url, err)
+			continue
+		}
+		got := fmt.Sprintf("%s %s %s %s %s %v %s %s", p.Platform, p.Owner, p.Repo, p.Branch, p.FilePath, p.IsDirectory, p.Tag, p.APIBase)
+		if got != tt.expected {
+			t.Errorf("parseGitURL(%q)\n got  %s\n want %s", tt.url, got, tt.expected)
+		}
+	}
+
+	if _, err := parseGitURL("https://unknown.example.com/user/repo"); err == nil {
+		t.Error("Expected an unknown host to be rejected")
+	}
+}
+
+// filePaths returns the sorted paths of files
+func filePaths(files []fileInfo) string {
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+// TestPlatformAPIs tests directory listings and releases of GitLab, Gitea
+// and Bitbucket against fake APIs
+func TestPlatformAPIs(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query := r.URL.EscapedPath(), r.URL.Query()
+		switch {
+		// GitLab, two pages of a recursive tree
+		case path == "/projects/group%2Fsub%2Fproject/repository/tree" && query.Get("page") == "1":
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprint(w, `[{"path":"src/a.go","type":"blob"},{"path":"src/pkg","type":"tree"}]`)
+		case path == "/projects/group%2Fsub%2Fproject/repository/tree":
+			fmt.Fprint(w, `[{"path":"src/pkg/b.go","type":"blob"},{"path":"src/pkg/deep/c.go","type":"blob"}]`)
+		case path == "/projects/group%2Fsub%2Fproject/releases":
+			fmt.Fprint(w, `[{"tag_name":"v1.0","released_at":"2024-05-01T00:00:00Z","assets":{
+				"links":[{"name":"tool-linux.tar.gz","url":"https://x/l","direct_asset_url":"https://x/d"}],
+				"sources":[{"format":"zip","url":"https://x/s.zip"}]}}]`)
+
+		// Gitea mirrors GitHub's APIs
+		case path == "/repos/user/repo/contents/docs":
+			fmt.Fprint(w, `[{"path":"docs/a.md","type":"file","download_url":"https://x/a.md"},{"path":"docs/gone","type":"dir"}]`)
+		case path == "/repos/user/repo/releases/tags/v2":
+			fmt.Fprint(w, `{"tag_name":"v2","published_at":"2024-06-01T00:00:00Z","assets":[{"name":"a.zip","size":10,"browser_download_url":"https://x/a.zip"}]}`)
+
+		// Bitbucket, with a paged directory
+		case path == "/repositories/team/repo/src/main/docs/" && query.Get("page") == "":
+			fmt.Fprintf(w, `{"values":[{"path":"docs/a.md","type":"commit_file"}],"next":%q}`, server.URL+path+"?page=2")
+		case path == "/repositories/team/repo/src/main/docs/":
+			fmt.Fprint(w, `{"values":[{"path":"docs/sub","type":"commit_directory"}]}`)
+		case path == "/repositories/team/repo/src/main/docs/sub/":
+			fmt.Fprint(w, `{"values":[{"path":"docs/sub/b.md","type":"commit_file"}]}`)
+		case path == "/repositories/team/repo/downloads":
+			fmt.Fprint(w, `{"values":[{"name":"tool-1.0.zip","size":5,"created_on":"2024-01-01","links":{"self":{"href":"https://x/t1"}}},
+				{"name":"tool-2.0.zip","size":6,"created_on":"2024-02-01","links":{"self":{"href":"https://x/t2"}}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	gitlab := &ParsedGitURL{Platform: "gitlab", Host: "gitlab.com", APIBase: server.URL, Owner: "group/sub", Repo: "project", Branch: "main", FilePath: "src"}
+	files, err := fetchDirectoryContents(gitlab, 1, nil)
+	if err != nil || filePaths(files) != "src/a.go,src/pkg/b.go" {
+		t.Errorf("GitLab tree: %s, %v", filePaths(files), err)
+	}
+	if len(files) > 0 && files[0].URL != "https://gitlab.com/group/sub/project/-/raw/main/src/a.go" {
+		t.Errorf("GitLab raw URL: %s", files[0].URL)
+	}
+	release, err := fetchRelease(gitlab, "", nil)
+	if err != nil || release.TagName != "v1.0" || len(release.Assets) != 2 ||
+		release.Assets[0].BrowserDownloadURL != "https://x/d" || release.Assets[1].Name != "project-v1.0.zip" {
+		t.Errorf("GitLab release: %+v, %v", release, err)
+	}
+
+	gitea := &ParsedGitURL{Platform: "gitea", Host: "codeberg.org", APIBase: server.URL, Owner: "user", Repo: "repo", Branch: "main", FilePath: "docs"}
+	// Subdirectories that fail to list are reported, not dropped silently
+	if files, err := fetchDirectoryContents(gitea, 1, nil); err == nil || !strings.Contains(err.Error(), "docs/gone") || filePaths(files) != "docs/a.md" {
+		t.Errorf("Gitea contents: %s, %v", filePaths(files), err)
+	}
+	if release, err := fetchRelease(gitea, "v2", nil); err != nil || release.TagName != "v2" || len(release.Assets) != 1 {
+		t.Errorf("Gitea release: %+v, %v", release, err)
+	}
+
+	bitbucket := &ParsedGitURL{Platform: "bitbucket", Host: "bitbucket.org", APIBase: server.URL, Owner: "team", Repo: "repo", Branch: "main", FilePath: "docs"}
+	if files, err := fetchDirectoryContents(bitbucket, 1, nil); err != nil || filePaths(files) != "docs/a.md,docs/sub/b.md" {
+		t.Errorf("Bitbucket src: %s, %v", filePaths(files), err)
+	}
+	release, err = fetchRelease(bitbucket, "v2.0", nil)
+	if err != nil || len(release.Assets) != 1 || release.Assets[0].Name != "tool-2.0.zip" {
+		t.Errorf("Bitbucket downloads: %+v, %v", release, err)
+	}
+}
+
+// TestResolveRef tests default branches, refs containing slashes, tags and
+// commits against fake APIs
+func TestResolveRef(t *testing.T) {
+	commits := map[string]string{"master": "aaa", "feature/login": "bbb", "v1.0": "ccc", "0123abc": "0123abcdef"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.EscapedPath()
+		switch {
+		case path == "/repos/user/repo" || path == "/projects/group%2Fproject":
+			fmt.Fprint(w, `{"default_branch":"master"}`)
+		case path == "/repositories/team/repo":
+			fmt.Fprint(w, `{"mainbranch":{"name":"master"}}`)
+		case path == "/repos/user/repo/commits" && commits[r.URL.Query().Get("sha")] != "":
+			fmt.Fprintf(w, `[{"sha":%q}]`, commits[r.URL.Query().Get("sha")])
+		case strings.HasPrefix(path, "/projects/group%2Fproject/repository/commits/"):
+			ref, _ := neturl.PathUnescape(strings.TrimPrefix(path, "/projects/group%2Fproject/repository/commits/"))
+			if commits[ref] == "" {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, `{"id":%q}`, commits[ref])
+		case strings.HasPrefix(path, "/repositories/team/repo/commit/"):
+			fmt.Fprintf(w, `{"hash":%q}`, commits[strings.TrimPrefix(path, "/repositories/team/repo/commit/")])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		platform, owner, repo string
+		branch, refPath       string
+		expected              string // branch path commit
+	}{
+		{"github", "user", "repo", "", "", "master  aaa"},
+		{"github", "user", "repo", "feature", "feature/login/src/app.go", "feature/login src/app.go bbb"},
+		{"github", "user", "repo", "v1.0", "", "v1.0  ccc"},
+		{"gitea", "user", "repo", "0123abc", "0123abc/docs", "0123abc docs 0123abcdef"},
+		{"gitlab", "group", "project", "", "", "master  aaa"},
+		{"gitlab", "group", "project", "feature", "feature/login", "feature/login  bbb"},
+		{"bitbucket", "team", "repo", "", "", "master  aaa"},
+	}
+	for _, tt := range tests {
+		parsed := &ParsedGitURL{Platform: tt.platform, APIBase: server.URL, Owner: tt.owner, Repo: tt.repo, Branch: tt.branch, refPath: tt.refPath}
+		if err := resolveRef(parsed, nil); err != nil {
+			t.Errorf("resolveRef(%s %s): %v", tt.platform, tt.refPath, err)
+			continue
+		}
+		if got := parsed.Branch + " " + parsed.FilePath + " " + parsed.Commit; got != tt.expected {
+			t.Errorf("resolveRef(%s %s) = %q, want %q", tt.platform, tt.refPath, got, tt.expected)
+		}
+	}
+
+	// An unknown ref keeps the guess and reports the failure
+	parsed := &ParsedGitURL{Platform: "github", APIBase: server.URL, Owner: "user", Repo: "repo", Branch: "nope", FilePath: "a", refPath: "nope/a"}
+	if err := resolveRef(parsed, nil); err == nil || parsed.Branch != "nope" || parsed.Commit != "" {
+		t.Errorf("Expected an unresolved ref, got %+v, %v", parsed, err)
+	}
+
+	// Gitea's raw URLs pin the resolved commit
+	gitea := &ParsedGitURL{Platform: "gitea", Host: "codeberg.org", Owner: "user", Repo: "repo", Branch: "v1.0", Commit: "ccc", FilePath: "a.md"}
+	if url := toRawURL(gitea); url != "https://codeberg.org/user/repo/raw/commit/ccc/a.md" {
+		t.Errorf("Unexpected Gitea raw URL %s", url)
+	}
+}
+
+// TestGitReleaseReportsFailedAssets tests that assets failing verification
+// make the release download fail
+func TestGitReleaseReportsFailedAssets(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/tool/releases/tags/v1":
+			fmt.Fprintf(w, `{"tag_name":"v1","assets":[
+				{"name":"good.bin","browser_download_url":%q},
+				{"name":"bad.bin","browser_download_url":%q},
+				{"name":"checksums.txt","browser_download_url":%q}]}`,
+				server.URL+"/good.bin", server.URL+"/bad.bin", server.URL+"/checksums.txt")
+		case "/good.bin", "/bad.bin":
+			fmt.Fprint(w, "content")
+		case "/checksums.txt":
+			// bad.bin doesn't match its checksum
+			fmt.Fprintf(w, "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73  good.bin\n%064x  bad.bin\n", 0)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	RegisterAccountHosts([]config.Account{{Name: "test", Platform: &config.PlatformConfig{Type: "github", Domain: host, ApiUrl: server.URL}}})
+	defer RegisterAccountHosts(nil)
+
+	// Answer the asset prompt with "all"
+	stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin.WriteString("all\n")
+	stdin.Seek(0, 0)
+	defer func(orig *os.File) { os.Stdin = orig }(os.Stdin)
+	os.Stdin = stdin
+
+	dir := t.TempDir()
+	err = GitRelease(server.URL+"/owner/tool/releases/tag/v1", ReleaseOptions{OutputDir: dir})
+	if err == nil || !strings.Contains(err.Error(), "bad.bin") || strings.Contains(err.Error(), "good.bin") {
+		t.Errorf("Expected only bad.bin to fail, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "good.bin")); err != nil {
+		t.Errorf("Expected good.bin to be downloaded: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bad.bin")); !os.IsNotExist(err) {
+		t.Error("Expected bad.bin to be removed")
+	}
+}
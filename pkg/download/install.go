@@ -0,0 +1,437 @@
+package download
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dwirx/ghex/internal/platform"
+	"github.com/dwirx/ghex/internal/ui"
+	"github.com/dwirx/ghex/internal/update"
+)
+
+// ErrNotInstalled means a tool isn't in the registry of installed tools
+var ErrNotInstalled = errors.New("tool is not installed")
+
+// ErrAlreadyExists means installing would replace a binary ghex didn't
+// install from the same repository
+var ErrAlreadyExists = errors.New("binary already exists")
+
+// toolsRegistryName is the file name of the installed tools registry
+const toolsRegistryName = "tools.json"
+
+// InstallOptions configures installing a tool from a GitHub release
+type InstallOptions struct {
+	Version string // release tag (default: latest)
+	BinDir  string // directory the binary is placed in (default: ~/.local/bin)
+	Binary  string // name of the binary (default: the repository name)
+	Asset   string // asset name filter, instead of matching the platform
+	Force   bool   // reinstall the same version or replace a foreign binary
+}
+
+// InstalledTool is a registry entry of a tool installed by ghex
+type InstalledTool struct {
+	Name        string    `json:"name"`
+	Repo        string    `json:"repo"`
+	Version     string    `json:"version"`
+	Asset       string    `json:"asset"`
+	AssetFilter string    `json:"asset_filter,omitempty"` // --asset given at install, reused on upgrade
+	Path        string    `json:"path"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// Registry records the tools installed with dlx install
+type Registry struct {
+	path  string
+	Tools []InstalledTool `json:"tools"`
+}
+
+// GetToolsRegistryPath returns the path of the installed tools registry
+func GetToolsRegistryPath() string {
+	return filepath.Join(platform.GetConfigDir("ghe"), toolsRegistryName)
+}
+
+// LoadRegistry reads the registry at path; a missing file is an empty registry
+func LoadRegistry(path string) (*Registry, error) {
+	r := &Registry{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tools registry: %w", err)
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse tools registry: %w", err)
+	}
+	return r, nil
+}
+
+// Save writes the registry
+func (r *Registry) Save() error {
+	if err := platform.EnsureDir(filepath.Dir(r.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tools registry: %w", err)
+	}
+	return nil
+}
+
+// Find returns the installed tool called name, or nil
+func (r *Registry) Find(name string) *InstalledTool {
+	for i := range r.Tools {
+		if r.Tools[i].Name == name {
+			return &r.Tools[i]
+		}
+	}
+	return nil
+}
+
+// Put adds tool, replacing an entry with the same name
+func (r *Registry) Put(tool InstalledTool) {
+	if existing := r.Find(tool.Name); existing != nil {
+		*existing = tool
+		return
+	}
+	r.Tools = append(r.Tools, tool)
+	sort.Slice(r.Tools, func(i, j int) bool { return r.Tools[i].Name < r.Tools[j].Name })
+}
+
+// Remove deletes the entry called name and reports whether it existed
+func (r *Registry) Remove(name string) bool {
+	for i := range r.Tools {
+		if r.Tools[i].Name == name {
+			r.Tools = append(r.Tools[:i], r.Tools[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Installer installs binaries from GitHub releases
+type Installer struct {
+	Client   *update.GitHubClient
+	Registry *Registry
+	OS       string
+	Arch     string
+}
+
+// NewInstaller creates an installer for the current platform using the
+// default registry
+func NewInstaller() (*Installer, error) {
+	registry, err := LoadRegistry(GetToolsRegistryPath())
+	if err != nil {
+		return nil, err
+	}
+	return &Installer{
+		Client:   update.NewGitHubClient(),
+		Registry: registry,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+	}, nil
+}
+
+// DefaultBinDir returns the directory tools are installed to by default
+func DefaultBinDir() string {
+	return filepath.Join(platform.GetHomeDir(), ".local", "bin")
+}
+
+// parseRepo splits owner/repo or a GitHub repository URL
+func parseRepo(repo string) (string, string, error) {
+	repo = strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+	for _, prefix := range []string{"https://", "http://", "github.com/"} {
+		repo = strings.TrimPrefix(repo, prefix)
+	}
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid repository %q, expected owner/repo", repo)
+	}
+	return parts[0], parts[1], nil
+}
+
+// Install downloads the release asset of repo built for the installer's
+// platform, verifies it against the release checksums, extracts the binary
+// and records it in the registry
+func (i *Installer) Install(repo string, opts InstallOptions) (*InstalledTool, error) {
+	owner, name, err := parseRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+	binary := opts.Binary
+	if binary == "" {
+		binary = name
+	}
+	binDir := opts.BinDir
+	if binDir == "" {
+		binDir = DefaultBinDir()
+	}
+	target := filepath.Join(binDir, i.executableName(binary))
+
+	existing := i.Registry.Find(binary)
+	if !opts.Force {
+		if existing != nil && existing.Repo != owner+"/"+name {
+			return nil, fmt.Errorf("%w: %s is installed from %s (use --force to replace it)", ErrAlreadyExists, binary, existing.Repo)
+		}
+		if platform.FileExists(target) && (existing == nil || existing.Path != target) {
+			return nil, fmt.Errorf("%w: %s was not installed by ghex (use --force to replace it)", ErrAlreadyExists, target)
+		}
+	}
+
+	var release *update.ReleaseInfo
+	if opts.Version != "" {
+		release, err = i.Client.GetReleaseByTag(owner, name, opts.Version)
+	} else {
+		release, err = i.Client.GetLatestRelease(owner, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil && !opts.Force &&
+		existing.Version == release.TagName && platform.FileExists(existing.Path) {
+		ui.ShowInfo(fmt.Sprintf("%s %s is already installed", binary, release.TagName))
+		return existing, nil
+	}
+
+	asset, err := i.selectAsset(release, opts.Asset)
+	if err != nil {
+		return nil, err
+	}
+
+	ui.ShowSection("Installing " + binary)
+	ui.ShowKeyValue("Repository", owner+"/"+name)
+	ui.ShowKeyValue("Version", release.TagName)
+	ui.ShowKeyValue("Asset", asset.Name)
+	fmt.Println()
+
+	var verify *Verification
+	for _, a := range release.Assets {
+		if update.IsChecksumFile(a.Name) {
+			if verify, err = releaseChecksums(a.Name, a.DownloadURL, nil); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	if verify == nil {
+		ui.ShowWarning("The release publishes no checksums, the download is not verified")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "ghex-install-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	downloadOpts := Options{
+		Output:          asset.Name,
+		OutputDir:       tmpDir,
+		ShowProgress:    true,
+		FollowRedirects: true,
+		Verify:          verify,
+	}
+	if err := FromURL(asset.DownloadURL, downloadOpts); err != nil {
+		return nil, err
+	}
+
+	source, err := i.findBinary(filepath.Join(tmpDir, asset.Name), binary)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := installBinary(source, target); err != nil {
+		return nil, err
+	}
+
+	tool := InstalledTool{
+		Name:        binary,
+		Repo:        owner + "/" + name,
+		Version:     release.TagName,
+		Asset:       asset.Name,
+		AssetFilter: opts.Asset,
+		Path:        target,
+		InstalledAt: time.Now().UTC(),
+	}
+	i.Registry.Put(tool)
+	if err := i.Registry.Save(); err != nil {
+		return nil, err
+	}
+
+	ui.ShowSuccess(fmt.Sprintf("Installed %s %s to %s", binary, release.TagName, target))
+	if !inPath(binDir) {
+		ui.ShowWarning(fmt.Sprintf("%s is not in your PATH", binDir))
+	}
+	return i.Registry.Find(binary), nil
+}
+
+// Upgrade installs the latest release of an installed tool if it is newer.
+// It reports whether the tool was upgraded.
+func (i *Installer) Upgrade(name string) (bool, error) {
+	tool := i.Registry.Find(name)
+	if tool == nil {
+		return false, fmt.Errorf("%w: %s", ErrNotInstalled, name)
+	}
+	owner, repo, err := parseRepo(tool.Repo)
+	if err != nil {
+		return false, err
+	}
+
+	release, err := i.Client.GetLatestRelease(owner, repo)
+	if err != nil {
+		return false, err
+	}
+	// Tags that aren't semantic versions can only be told apart
+	newer := release.TagName != tool.Version
+	if cmp, err := update.CompareVersionStrings(release.TagName, tool.Version); err == nil {
+		newer = cmp > 0
+	}
+	if !newer {
+		ui.ShowInfo(fmt.Sprintf("%s is up to date (%s, latest %s)", name, tool.Version, release.TagName))
+		return false, nil
+	}
+
+	opts := InstallOptions{
+		Version: release.TagName,
+		BinDir:  filepath.Dir(tool.Path),
+		Binary:  tool.Name,
+		Asset:   tool.AssetFilter,
+		Force:   true,
+	}
+	if _, err := i.Install(tool.Repo, opts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Uninstall deletes an installed tool's binary and its registry entry
+func (i *Installer) Uninstall(name string) error {
+	tool := i.Registry.Find(name)
+	if tool == nil {
+		return fmt.Errorf("%w: %s", ErrNotInstalled, name)
+	}
+	if err := os.Remove(tool.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", tool.Path, err)
+	}
+	path := tool.Path
+	i.Registry.Remove(name)
+	if err := i.Registry.Save(); err != nil {
+		return err
+	}
+	ui.ShowSuccess(fmt.Sprintf("Uninstalled %s from %s", name, path))
+	return nil
+}
+
+// selectAsset picks the release asset to install, by filter or platform
+func (i *Installer) selectAsset(release *update.ReleaseInfo, filter string) (*update.Asset, error) {
+	if filter == "" {
+		return update.SelectAssetForPlatform(release, i.OS, i.Arch)
+	}
+	for idx := range release.Assets {
+		asset := &release.Assets[idx]
+		if !update.IsChecksumFile(asset.Name) && strings.Contains(strings.ToLower(asset.Name), strings.ToLower(filter)) {
+			return asset, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no asset matching %q", update.ErrAssetNotFound, filter)
+}
+
+// executableName returns the file name of binary on the installer's OS
+func (i *Installer) executableName(binary string) string {
+	if i.OS == "windows" && !strings.HasSuffix(strings.ToLower(binary), ".exe") {
+		return binary + ".exe"
+	}
+	return binary
+}
+
+// findBinary returns the program in a downloaded asset: the asset itself, or
+// the file of an archive named like the binary, or its only executable
+func (i *Installer) findBinary(assetPath, binary string) (string, error) {
+	if !IsArchive(assetPath) {
+		return assetPath, nil
+	}
+
+	dir := filepath.Join(filepath.Dir(assetPath), "extracted")
+	files, err := Extract(assetPath, ExtractOptions{Dir: dir})
+	if err != nil {
+		return "", fmt.Errorf("failed to extract %s: %w", filepath.Base(assetPath), err)
+	}
+
+	want := i.executableName(binary)
+	var executables []string
+	for _, f := range files {
+		info, err := os.Lstat(f)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if filepath.Base(f) == want {
+			return f, nil
+		}
+		if info.Mode()&0111 != 0 || strings.HasSuffix(strings.ToLower(f), ".exe") {
+			executables = append(executables, f)
+		}
+	}
+	if len(executables) == 1 {
+		return executables[0], nil
+	}
+	return "", fmt.Errorf("binary %s not found in %s, choose it with --binary", want, filepath.Base(assetPath))
+}
+
+// installBinary copies source to target through a temporary file, so a
+// running copy of the old binary is replaced rather than overwritten
+func installBinary(source, target string) error {
+	if err := platform.EnsureDir(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create bin directory: %w", err)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := target + ".new"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+	if err := update.SetExecutable(tmp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to make binary executable: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+	return nil
+}
+
+// inPath reports whether dir is one of the PATH directories
+func inPath(dir string) bool {
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if p != "" && filepath.Clean(p) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dwirx/ghex/internal/update"
+)
+
+// fakeRelease serves a GitHub API with one release of owner/tool whose
+// archive contains tool-<tag>/tool printing body
+type fakeRelease struct {
+	tag, body string
+	checksum  string // overrides the published checksum when set
+}
+
+func (f *fakeRelease) serve(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		archiveName := "tool_" + f.tag + "_linux_x86_64.tar.gz"
+		archive, _ := os.ReadFile(writeTar(t, archiveName, []tarEntry{
+			{Name: "tool-" + f.tag + "/README.md", Body: "readme"},
+			{Name: "tool-" + f.tag + "/tool", Body: f.body},
+		}))
+		sum := sha256.Sum256(archive)
+		checksum := hex.EncodeToString(sum[:])
+		if f.checksum != "" {
+			checksum = f.checksum
+		}
+
+		switch r.URL.Path {
+		case "/repos/owner/tool/releases/latest", "/repos/owner/tool/releases/tags/" + f.tag:
+			json.NewEncoder(w).Encode(update.ReleaseInfo{
+				TagName: f.tag,
+				Assets: []update.Asset{
+					{Name: "tool_" + f.tag + "_darwin_arm64.tar.gz", DownloadURL: server.URL + "/dl/darwin"},
+					{Name: archiveName, DownloadURL: server.URL + "/dl/" + archiveName},
+					{Name: "checksums.txt", DownloadURL: server.URL + "/dl/checksums.txt"},
+				},
+			})
+		case "/dl/" + archiveName:
+			w.Write(archive)
+		case "/dl/checksums.txt":
+			fmt.Fprintf(w, "%s  %s\n", checksum, archiveName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server
+}
+
+// testInstaller returns a linux/amd64 installer using server as the GitHub API
+func testInstaller(t *testing.T, server *httptest.Server) *Installer {
+	registry, _ := LoadRegistry(filepath.Join(t.TempDir(), toolsRegistryName))
+	return &Installer{
+		Client:   &update.GitHubClient{HTTPClient: server.Client(), BaseURL: server.URL},
+		Registry: registry,
+		OS:       "linux",
+		Arch:     "amd64",
+	}
+}
+
+// TestInstallUpgradeUninstall tests the lifecycle of an installed tool
+func TestInstallUpgradeUninstall(t *testing.T) {
+	release := &fakeRelease{tag: "v1.0.0", body: "one"}
+	server := release.serve(t)
+	defer server.Close()
+
+	installer := testInstaller(t, server)
+	binDir := t.TempDir()
+	tool, err := installer.Install("owner/tool", InstallOptions{BinDir: binDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(binDir, "tool")
+	if tool.Path != target || tool.Version != "v1.0.0" || tool.Asset != "tool_v1.0.0_linux_x86_64.tar.gz" {
+		t.Errorf("Unexpected registry entry %+v", tool)
+	}
+	info, err := os.Stat(target)
+	if err != nil || info.Mode()&0100 == 0 {
+		t.Fatalf("Expected an executable at %s: %v", target, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "one" {
+		t.Errorf("Installed binary contains %q", data)
+	}
+
+	// The registry is persisted
+	registry, _ := LoadRegistry(installer.Registry.path)
+	if registry.Find("tool") == nil {
+		t.Error("Expected the tool in the saved registry")
+	}
+
+	if upgraded, err := installer.Upgrade("tool"); err != nil || upgraded {
+		t.Errorf("Expected no upgrade for the same version, got %v, %v", upgraded, err)
+	}
+
+	release.tag, release.body = "v1.1.0", "two"
+	if upgraded, err := installer.Upgrade("tool"); err != nil || !upgraded {
+		t.Fatalf("Expected an upgrade, got %v, %v", upgraded, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "two" {
+		t.Errorf("Upgraded binary contains %q", data)
+	}
+	if v := installer.Registry.Find("tool").Version; v != "v1.1.0" {
+		t.Errorf("Expected v1.1.0 in the registry, got %s", v)
+	}
+
+	// A latest release older than the installed one is no upgrade
+	release.tag, release.body = "v1.0.5", "older"
+	if upgraded, err := installer.Upgrade("tool"); err != nil || upgraded {
+		t.Errorf("Expected no downgrade, got %v, %v", upgraded, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "two" {
+		t.Errorf("Expected the binary to be kept, got %q", data)
+	}
+
+	if err := installer.Uninstall("tool"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Error("Expected the binary to be removed")
+	}
+	if err := installer.Uninstall("tool"); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("Expected ErrNotInstalled, got %v", err)
+	}
+}
+
+// TestInstallRefusesForeignBinary tests that binaries ghex didn't install
+// from the same repository are only replaced with Force
+func TestInstallRefusesForeignBinary(t *testing.T) {
+	release := &fakeRelease{tag: "v1.0.0", body: "one"}
+	server := release.serve(t)
+	defer server.Close()
+
+	installer := testInstaller(t, server)
+	binDir := t.TempDir()
+	target := filepath.Join(binDir, "tool")
+	if err := os.WriteFile(target, []byte("foreign"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := installer.Install("owner/tool", InstallOptions{BinDir: binDir}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists for an unknown binary, got %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "foreign" {
+		t.Errorf("Expected the binary to be kept, got %q", data)
+	}
+
+	if _, err := installer.Install("owner/tool", InstallOptions{BinDir: binDir, Force: true}); err != nil {
+		t.Fatalf("Expected --force to replace the binary: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "one" {
+		t.Errorf("Installed binary contains %q", data)
+	}
+
+	// A binary of the same name from another repository
+	installer.Registry.Find("tool").Repo = "other/tool"
+	if _, err := installer.Install("owner/tool", InstallOptions{BinDir: binDir}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists for another repository, got %v", err)
+	}
+	if repo := installer.Registry.Find("tool").Repo; repo != "other/tool" {
+		t.Errorf("Expected the registry entry to be kept, got %s", repo)
+	}
+}
+
+// TestInstallRejectsChecksumMismatch tests that nothing is installed when
+// the asset doesn't match the release checksums
+func TestInstallRejectsChecksumMismatch(t *testing.T) {
+	release := &fakeRelease{tag: "v1.0.0", body: "one", checksum: fmt.Sprintf("%064x", 0)}
+	server := release.serve(t)
+	defer server.Close()
+
+	installer := testInstaller(t, server)
+	binDir := t.TempDir()
+	_, err := installer.Install("owner/tool", InstallOptions{BinDir: binDir})
+	if !errors.Is(err, update.ErrChecksumMismatch) {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(binDir, "tool")); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be installed")
+	}
+	if len(installer.Registry.Tools) != 0 {
+		t.Error("Expected an empty registry")
+	}
+}
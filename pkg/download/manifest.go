@@ -0,0 +1,214 @@
+package download
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dwirx/ghex/internal/update"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is a list of downloads with per-entry options. Top-level fields
+// are defaults for every entry.
+type Manifest struct {
+	Dir       string            `yaml:"dir,omitempty"`
+	Headers   map[string]string `yaml:"headers,omitempty"`
+	Account   string            `yaml:"account,omitempty"`
+	Downloads []ManifestEntry   `yaml:"downloads"`
+	Plain     bool              `yaml:"-"` // read from a plain URL list
+}
+
+// ManifestEntry is one download of a manifest
+type ManifestEntry struct {
+	URL     string            `yaml:"url"`
+	Output  string            `yaml:"output,omitempty"`
+	Dir     string            `yaml:"dir,omitempty"`
+	SHA256  string            `yaml:"sha256,omitempty"`
+	SHA512  string            `yaml:"sha512,omitempty"`
+	BLAKE2b string            `yaml:"blake2b,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Account string            `yaml:"account,omitempty"` // configured account to authenticate as
+	Extract bool              `yaml:"extract,omitempty"`
+}
+
+// LoadManifest reads a download manifest. YAML and JSON manifests are
+// recognised by their extension or content; anything else is read as a
+// plain list of URLs, one per line, with # comments.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file list: %w", err)
+	}
+
+	var manifest *Manifest
+	if isStructuredManifest(path, string(data)) {
+		manifest, err = parseStructuredManifest(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	} else {
+		manifest = parsePlainManifest(string(data))
+	}
+
+	if len(manifest.Downloads) == 0 {
+		return nil, fmt.Errorf("no URLs found in file list")
+	}
+	for i := range manifest.Downloads {
+		entry := &manifest.Downloads[i]
+		if entry.URL == "" {
+			return nil, fmt.Errorf("entry %d of %s has no url", i+1, path)
+		}
+		manifest.applyDefaults(entry)
+	}
+	return manifest, nil
+}
+
+// isStructuredManifest reports whether a manifest is YAML or JSON
+func isStructuredManifest(path, content string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// URLs never start like a YAML or JSON document
+		return strings.HasPrefix(line, "{") || strings.HasPrefix(line, "[") ||
+			strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "downloads:")
+	}
+	return false
+}
+
+// parseStructuredManifest parses a YAML or JSON manifest, which is either a
+// mapping with a downloads list or the list itself
+func parseStructuredManifest(data []byte) (*Manifest, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if len(root.Content) == 0 {
+		return &manifest, nil
+	}
+	doc := root.Content[0]
+	if doc.Kind == yaml.SequenceNode {
+		err := doc.Decode(&manifest.Downloads)
+		return &manifest, err
+	}
+	if err := checkFields(doc, "dir", "headers", "account", "downloads"); err != nil {
+		return nil, err
+	}
+	err := doc.Decode(&manifest)
+	return &manifest, err
+}
+
+// UnmarshalYAML accepts a bare URL as well as a mapping of options
+func (e *ManifestEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		e.URL = node.Value
+		return nil
+	}
+	// A misspelt checksum field must not silently skip verification
+	if err := checkFields(node, "url", "output", "dir", "sha256", "sha512", "blake2b", "headers", "account", "extract"); err != nil {
+		return err
+	}
+	type plain ManifestEntry
+	return node.Decode((*plain)(e))
+}
+
+// checkFields returns an error for keys of a mapping node not in known
+func checkFields(node *yaml.Node, known ...string) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !slices.Contains(known, key.Value) {
+			return fmt.Errorf("line %d: unknown field %q", key.Line, key.Value)
+		}
+	}
+	return nil
+}
+
+// parsePlainManifest parses one URL per line
+func parsePlainManifest(content string) *Manifest {
+	manifest := Manifest{Plain: true}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			manifest.Downloads = append(manifest.Downloads, ManifestEntry{URL: line})
+		}
+	}
+	return &manifest
+}
+
+// applyDefaults fills unset entry fields from the manifest's defaults
+func (m *Manifest) applyDefaults(entry *ManifestEntry) {
+	if entry.Dir == "" {
+		entry.Dir = m.Dir
+	}
+	if entry.Account == "" {
+		entry.Account = m.Account
+	}
+	if len(m.Headers) > 0 {
+		headers := make(map[string]string, len(m.Headers)+len(entry.Headers))
+		for k, v := range m.Headers {
+			headers[k] = v
+		}
+		for k, v := range entry.Headers {
+			headers[k] = v
+		}
+		entry.Headers = headers
+	}
+}
+
+// Verification returns the checksum the entry's file must match, or nil
+func (e *ManifestEntry) Verification() *Verification {
+	switch {
+	case e.SHA256 != "":
+		return &Verification{Algorithm: update.AlgorithmSHA256, Checksum: e.SHA256}
+	case e.SHA512 != "":
+		return &Verification{Algorithm: update.AlgorithmSHA512, Checksum: e.SHA512}
+	case e.BLAKE2b != "":
+		return &Verification{Algorithm: update.AlgorithmBLAKE2b, Checksum: e.BLAKE2b}
+	}
+	return nil
+}
+
+// failuresManifest encodes the failed items as a manifest, each entry
+// preceded by a comment with its error
+func failuresManifest(header string, items []Item, result *BatchResult) ([]byte, error) {
+	list := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, item := range items {
+		err, ok := result.Errors[item.URL]
+		if !ok {
+			continue
+		}
+		var node yaml.Node
+		if err := node.Encode(item.Entry); err != nil {
+			return nil, err
+		}
+		node.HeadComment = oneLine(err)
+		list.Content = append(list.Content, &node)
+	}
+
+	doc := &yaml.Node{
+		Kind:        yaml.MappingNode,
+		HeadComment: header,
+		Content:     []*yaml.Node{{Kind: yaml.ScalarNode, Value: "downloads"}, list},
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), enc.Close()
+}
@@ -0,0 +1,418 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"strings"
+
+	"github.com/dwirx/ghex/internal/git"
+)
+
+type fileInfo struct {
+	Path string
+	URL  string
+}
+
+// gitRelease is a release with its downloadable assets
+type gitRelease struct {
+	TagName     string         `json:"tag_name"`
+	Name        string         `json:"name"`
+	PublishedAt string         `json:"published_at"`
+	Assets      []releaseAsset `json:"assets"`
+}
+
+// fetchDirectoryContents fetches all files in a directory, up to maxDepth
+// levels of subdirectories deep. Files may be returned with an error when
+// only some subdirectories could be listed.
+func fetchDirectoryContents(parsed *ParsedGitURL, maxDepth int, headers map[string]string) ([]fileInfo, error) {
+	switch parsed.Platform {
+	case git.FlavorGitHub, git.FlavorGitea:
+		// Gitea's contents API mirrors GitHub's
+		return fetchContentsTree(parsed, maxDepth, headers)
+	case git.FlavorGitLab:
+		return fetchGitLabTree(parsed, maxDepth, headers)
+	case git.FlavorBitbucket:
+		return fetchBitbucketTree(parsed, maxDepth, headers)
+	default:
+		return nil, fmt.Errorf("directory download not supported for %s", parsed.Platform)
+	}
+}
+
+// fetchContentsTree lists a directory with the GitHub/Gitea contents API.
+// Subdirectories that fail to list are skipped and their errors returned
+// along with the files found.
+func fetchContentsTree(parsed *ParsedGitURL, maxDepth int, headers map[string]string) ([]fileInfo, error) {
+	var files []fileInfo
+	var errs []error
+
+	var fetchRecursive func(path string, depth int) error
+	fetchRecursive = func(path string, depth int) error {
+		if depth > maxDepth {
+			return nil
+		}
+
+		apiURL := fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s",
+			parsed.apiBase(), parsed.Owner, parsed.Repo, path, neturl.QueryEscape(parsed.Branch))
+
+		var contents []struct {
+			Name        string `json:"name"`
+			Path        string `json:"path"`
+			Type        string `json:"type"`
+			DownloadURL string `json:"download_url"`
+		}
+
+		if err := getJSON(apiURL, headers, &contents); err != nil {
+			return fmt.Errorf("API error: %w", err)
+		}
+
+		for _, item := range contents {
+			if item.Type == "file" {
+				files = append(files, fileInfo{
+					Path: item.Path,
+					URL:  item.DownloadURL,
+				})
+			} else if item.Type == "dir" {
+				if err := fetchRecursive(item.Path, depth+1); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", item.Path, err))
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if err := fetchRecursive(parsed.FilePath, 0); err != nil {
+		return nil, err
+	}
+
+	return files, errors.Join(errs...)
+}
+
+// gitlabProject returns the URL-encoded project path GitLab's API takes as ID
+func gitlabProject(parsed *ParsedGitURL) string {
+	return neturl.PathEscape(parsed.Owner + "/" + parsed.Repo)
+}
+
+// fetchGitLabTree lists a directory with GitLab's recursive tree API
+func fetchGitLabTree(parsed *ParsedGitURL, maxDepth int, headers map[string]string) ([]fileInfo, error) {
+	var files []fileInfo
+	for page := "1"; page != ""; {
+		apiURL := fmt.Sprintf("%s/projects/%s/repository/tree?recursive=true&per_page=100&page=%s&ref=%s&path=%s",
+			parsed.apiBase(), gitlabProject(parsed), page, neturl.QueryEscape(parsed.Branch), neturl.QueryEscape(parsed.FilePath))
+
+		var entries []struct {
+			Path string `json:"path"`
+			Type string `json:"type"` // blob or tree
+		}
+		header, err := fetchJSON(apiURL, headers, &entries)
+		if err != nil {
+			return nil, fmt.Errorf("API error: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.Type != "blob" || depthBelow(parsed.FilePath, entry.Path) > maxDepth {
+				continue
+			}
+			file := *parsed
+			file.FilePath = entry.Path
+			files = append(files, fileInfo{Path: entry.Path, URL: toRawURL(&file)})
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return files, nil
+}
+
+// fetchBitbucketTree lists a directory with Bitbucket's src API, skipping
+// subdirectories that fail like fetchContentsTree
+func fetchBitbucketTree(parsed *ParsedGitURL, maxDepth int, headers map[string]string) ([]fileInfo, error) {
+	var files []fileInfo
+	var errs []error
+
+	var fetchRecursive func(path string, depth int) error
+	fetchRecursive = func(path string, depth int) error {
+		if depth > maxDepth {
+			return nil
+		}
+
+		apiURL := fmt.Sprintf("%s/repositories/%s/%s/src/%s/%s",
+			parsed.apiBase(), parsed.Owner, parsed.Repo, neturl.PathEscape(parsed.Branch), path)
+		if path != "" {
+			apiURL += "/"
+		}
+		apiURL += "?pagelen=100"
+
+		for apiURL != "" {
+			var page struct {
+				Values []struct {
+					Path string `json:"path"`
+					Type string `json:"type"` // commit_file or commit_directory
+				} `json:"values"`
+				Next string `json:"next"`
+			}
+			if err := getJSON(apiURL, headers, &page); err != nil {
+				return fmt.Errorf("API error: %w", err)
+			}
+
+			for _, item := range page.Values {
+				switch item.Type {
+				case "commit_file":
+					file := *parsed
+					file.FilePath = item.Path
+					files = append(files, fileInfo{Path: item.Path, URL: toRawURL(&file)})
+				case "commit_directory":
+					if err := fetchRecursive(item.Path, depth+1); err != nil {
+						errs = append(errs, fmt.Errorf("%s: %w", item.Path, err))
+					}
+				}
+			}
+			apiURL = page.Next
+		}
+		return nil
+	}
+
+	if err := fetchRecursive(parsed.FilePath, 0); err != nil {
+		return nil, err
+	}
+	return files, errors.Join(errs...)
+}
+
+// depthBelow returns how many directories deep path is below dir
+func depthBelow(dir, path string) int {
+	if dir != "" {
+		path = strings.TrimPrefix(path, dir+"/")
+	}
+	return strings.Count(path, "/")
+}
+
+// fetchRelease fetches the release tagged version, or the latest one
+func fetchRelease(parsed *ParsedGitURL, version string, headers map[string]string) (*gitRelease, error) {
+	switch parsed.Platform {
+	case git.FlavorGitHub, git.FlavorGitea:
+		// Gitea's releases API mirrors GitHub's
+		apiURL := fmt.Sprintf("%s/repos/%s/%s/releases/latest", parsed.apiBase(), parsed.Owner, parsed.Repo)
+		if version != "" {
+			apiURL = fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", parsed.apiBase(), parsed.Owner, parsed.Repo, neturl.PathEscape(version))
+		}
+		var release gitRelease
+		if err := getJSON(apiURL, headers, &release); err != nil {
+			return nil, err
+		}
+		return &release, nil
+	case git.FlavorGitLab:
+		return fetchGitLabRelease(parsed, version, headers)
+	case git.FlavorBitbucket:
+		return fetchBitbucketDownloads(parsed, version, headers)
+	default:
+		return nil, fmt.Errorf("release download not supported for %s", parsed.Platform)
+	}
+}
+
+// gitlabRelease is a release as returned by GitLab's API
+type gitlabRelease struct {
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name"`
+	ReleasedAt string `json:"released_at"`
+	Assets     struct {
+		Links []struct {
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+		} `json:"links"`
+		Sources []struct {
+			Format string `json:"format"`
+			URL    string `json:"url"`
+		} `json:"sources"`
+	} `json:"assets"`
+}
+
+// fetchGitLabRelease fetches a GitLab release; its assets are the release
+// links followed by the source archives
+func fetchGitLabRelease(parsed *ParsedGitURL, version string, headers map[string]string) (*gitRelease, error) {
+	var release gitlabRelease
+	if version != "" {
+		apiURL := fmt.Sprintf("%s/projects/%s/releases/%s", parsed.apiBase(), gitlabProject(parsed), neturl.PathEscape(version))
+		if err := getJSON(apiURL, headers, &release); err != nil {
+			return nil, err
+		}
+	} else {
+		// Releases are listed newest first
+		apiURL := fmt.Sprintf("%s/projects/%s/releases?per_page=1", parsed.apiBase(), gitlabProject(parsed))
+		var releases []gitlabRelease
+		if err := getJSON(apiURL, headers, &releases); err != nil {
+			return nil, err
+		}
+		if len(releases) == 0 {
+			return nil, fmt.Errorf("no releases found for %s/%s", parsed.Owner, parsed.Repo)
+		}
+		release = releases[0]
+	}
+
+	result := &gitRelease{TagName: release.TagName, Name: release.Name, PublishedAt: release.ReleasedAt}
+	for _, link := range release.Assets.Links {
+		url := link.DirectAssetURL
+		if url == "" {
+			url = link.URL
+		}
+		result.Assets = append(result.Assets, releaseAsset{Name: link.Name, BrowserDownloadURL: url})
+	}
+	for _, source := range release.Assets.Sources {
+		name := fmt.Sprintf("%s-%s.%s", parsed.Repo, release.TagName, source.Format)
+		result.Assets = append(result.Assets, releaseAsset{Name: name, BrowserDownloadURL: source.URL})
+	}
+	return result, nil
+}
+
+// fetchBitbucketDownloads lists a Bitbucket repository's downloads, which
+// stand in for release assets. Downloads aren't versioned, so a version
+// filters them by name.
+func fetchBitbucketDownloads(parsed *ParsedGitURL, version string, headers map[string]string) (*gitRelease, error) {
+	release := &gitRelease{TagName: "downloads", Name: "Downloads"}
+	apiURL := fmt.Sprintf("%s/repositories/%s/%s/downloads?pagelen=100", parsed.apiBase(), parsed.Owner, parsed.Repo)
+	for apiURL != "" {
+		var page struct {
+			Values []struct {
+				Name      string `json:"name"`
+				Size      int64  `json:"size"`
+				CreatedOn string `json:"created_on"`
+				Links     struct {
+					Self struct {
+						Href string `json:"href"`
+					} `json:"self"`
+				} `json:"links"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		if err := getJSON(apiURL, headers, &page); err != nil {
+			return nil, err
+		}
+
+		for _, d := range page.Values {
+			if version != "" && !strings.Contains(d.Name, strings.TrimPrefix(version, "v")) {
+				continue
+			}
+			if release.PublishedAt == "" || d.CreatedOn > release.PublishedAt {
+				release.PublishedAt = d.CreatedOn
+			}
+			release.Assets = append(release.Assets, releaseAsset{Name: d.Name, Size: d.Size, BrowserDownloadURL: d.Links.Self.Href})
+		}
+		apiURL = page.Next
+	}
+	if version != "" {
+		release.TagName = version
+	}
+	return release, nil
+}
+
+// resolveRef settles the ref of parsed through the platform's API: the
+// default branch when the URL names none, and where a ref containing
+// slashes ends and the path begins. It records the commit the ref points at.
+func resolveRef(parsed *ParsedGitURL, headers map[string]string) error {
+	if parsed.Branch == "" {
+		parsed.Branch = "main" // assumed when the platform can't be asked
+		branch, err := fetchDefaultBranch(parsed, headers)
+		if err != nil {
+			return fmt.Errorf("failed to fetch the default branch, assuming %s: %w", parsed.Branch, err)
+		}
+		parsed.Branch = branch
+	}
+
+	if parsed.refPath != "" {
+		// A ref can't also be the prefix of another, so the shortest
+		// existing prefix is the ref
+		segments := strings.Split(parsed.refPath, "/")
+		for i := 1; i <= len(segments); i++ {
+			ref := strings.Join(segments[:i], "/")
+			if commit, err := fetchCommit(parsed, ref, headers); err == nil {
+				parsed.Branch, parsed.FilePath, parsed.Commit = ref, strings.Join(segments[i:], "/"), commit
+				return nil
+			}
+		}
+		return fmt.Errorf("no branch, tag or commit found in %s", parsed.refPath)
+	}
+
+	commit, err := fetchCommit(parsed, parsed.Branch, headers)
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", parsed.Branch, err)
+	}
+	parsed.Commit = commit
+	return nil
+}
+
+// fetchDefaultBranch fetches the default branch of a repository
+func fetchDefaultBranch(parsed *ParsedGitURL, headers map[string]string) (string, error) {
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+		MainBranch    struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"` // Bitbucket
+	}
+
+	var apiURL string
+	switch parsed.Platform {
+	case git.FlavorGitHub, git.FlavorGitea:
+		apiURL = fmt.Sprintf("%s/repos/%s/%s", parsed.apiBase(), parsed.Owner, parsed.Repo)
+	case git.FlavorGitLab:
+		apiURL = fmt.Sprintf("%s/projects/%s", parsed.apiBase(), gitlabProject(parsed))
+	case git.FlavorBitbucket:
+		apiURL = fmt.Sprintf("%s/repositories/%s/%s", parsed.apiBase(), parsed.Owner, parsed.Repo)
+	default:
+		return "", fmt.Errorf("not supported for %s", parsed.Platform)
+	}
+	if err := getJSON(apiURL, headers, &repo); err != nil {
+		return "", err
+	}
+
+	branch := repo.DefaultBranch
+	if branch == "" {
+		branch = repo.MainBranch.Name
+	}
+	if branch == "" {
+		return "", fmt.Errorf("no default branch for %s/%s", parsed.Owner, parsed.Repo)
+	}
+	return branch, nil
+}
+
+// fetchCommit fetches the commit SHA a branch, tag or commit points at
+func fetchCommit(parsed *ParsedGitURL, ref string, headers map[string]string) (string, error) {
+	var commit struct {
+		SHA  string `json:"sha"`
+		ID   string `json:"id"`   // GitLab
+		Hash string `json:"hash"` // Bitbucket
+	}
+
+	switch parsed.Platform {
+	case git.FlavorGitHub, git.FlavorGitea:
+		// Both list commits from any ref; limit is Gitea's per_page
+		apiURL := fmt.Sprintf("%s/repos/%s/%s/commits?sha=%s&per_page=1&limit=1",
+			parsed.apiBase(), parsed.Owner, parsed.Repo, neturl.QueryEscape(ref))
+		var commits []struct {
+			SHA string `json:"sha"`
+		}
+		if err := getJSON(apiURL, headers, &commits); err != nil {
+			return "", err
+		}
+		if len(commits) > 0 {
+			commit.SHA = commits[0].SHA
+		}
+	case git.FlavorGitLab:
+		apiURL := fmt.Sprintf("%s/projects/%s/repository/commits/%s", parsed.apiBase(), gitlabProject(parsed), neturl.PathEscape(ref))
+		if err := getJSON(apiURL, headers, &commit); err != nil {
+			return "", err
+		}
+	case git.FlavorBitbucket:
+		apiURL := fmt.Sprintf("%s/repositories/%s/%s/commit/%s", parsed.apiBase(), parsed.Owner, parsed.Repo, neturl.PathEscape(ref))
+		if err := getJSON(apiURL, headers, &commit); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("not supported for %s", parsed.Platform)
+	}
+
+	for _, sha := range []string{commit.SHA, commit.ID, commit.Hash} {
+		if sha != "" {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("ref %s not found", ref)
+}
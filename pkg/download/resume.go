@@ -0,0 +1,112 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// partMeta is the sidecar metadata of a partial download, used to check
+// that a resumed download still refers to the same remote file
+type partMeta struct {
+	URL          string     `json:"url"`
+	ETag         string     `json:"etag,omitempty"`
+	LastModified string     `json:"lastModified,omitempty"`
+	Size         int64      `json:"size,omitempty"`     // expected total size, 0 if unknown
+	Segments     []*segment `json:"segments,omitempty"` // byte ranges of a segmented download
+}
+
+// partPath returns the file a download is written to until it completes
+func partPath(outputPath string) string {
+	return outputPath + ".part"
+}
+
+// partMetaPath returns the sidecar metadata file of a partial download
+func partMetaPath(outputPath string) string {
+	return outputPath + ".part.json"
+}
+
+// validator returns the If-Range value for the partial download, preferring
+// a strong ETag; weak ETags can't be used with If-Range
+func (m *partMeta) validator() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+// loadPartial returns the metadata and size of a partial download of url
+// that can be resumed, or nil when there is none
+func loadPartial(outputPath, url string) (*partMeta, int64) {
+	meta, err := readPartMeta(outputPath)
+	// A segmented download is preallocated, so its size says nothing about progress
+	if err != nil || meta.URL != url || meta.validator() == "" || len(meta.Segments) > 0 {
+		return nil, 0
+	}
+
+	info, err := os.Stat(partPath(outputPath))
+	if err != nil || info.Size() == 0 || (meta.Size > 0 && info.Size() > meta.Size) {
+		return nil, 0
+	}
+	return meta, info.Size()
+}
+
+// readPartMeta reads the sidecar metadata of a partial download
+func readPartMeta(outputPath string) (*partMeta, error) {
+	data, err := os.ReadFile(partMetaPath(outputPath))
+	if err != nil {
+		return nil, err
+	}
+	var meta partMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// savePartMeta writes the sidecar metadata of a partial download
+func savePartMeta(outputPath string, meta *partMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(partMetaPath(outputPath), data, 0644); err != nil {
+		return fmt.Errorf("failed to write download metadata: %w", err)
+	}
+	return nil
+}
+
+// removePartial deletes a partial download and its metadata
+func removePartial(outputPath string) {
+	os.Remove(partPath(outputPath))
+	os.Remove(partMetaPath(outputPath))
+}
+
+// finishPartial moves a completed partial download into place
+func finishPartial(outputPath string) error {
+	if err := os.Rename(partPath(outputPath), outputPath); err != nil {
+		return fmt.Errorf("failed to move download into place: %w", err)
+	}
+	os.Remove(partMetaPath(outputPath))
+	return nil
+}
+
+// contentRangeStart returns the first byte of a "bytes start-end/total"
+// Content-Range header, or -1 if it can't be parsed
+func contentRangeStart(header string) int64 {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,313 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dwirx/ghex/internal/ui"
+)
+
+// minSegmentSize is the smallest range worth its own connection
+const minSegmentSize = 1 << 20
+
+// segmentRetries is how often a failed segment is retried
+const segmentRetries = 3
+
+// errFileChanged means the remote file changed during a segmented download
+var errFileChanged = errors.New("remote file changed")
+
+// segment is a byte range [Start, End] of a segmented download. Done counts
+// the bytes already written from Start and is updated atomically.
+type segment struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  int64 `json:"done"`
+}
+
+// remaining returns the number of bytes still to fetch
+func (s *segment) remaining() int64 {
+	return s.End - s.Start + 1 - atomic.LoadInt64(&s.Done)
+}
+
+// rangeInfo is what a range probe learned about a remote file
+type rangeInfo struct {
+	Size         int64
+	ETag         string
+	LastModified string
+}
+
+// splitSegments divides size bytes into at most n segments of at least minSize
+func splitSegments(size int64, n int, minSize int64) []*segment {
+	n = int(min(int64(n), max(size/minSize, 1)))
+	segments := make([]*segment, 0, n)
+	step := size / int64(n)
+	for i := 0; i < n; i++ {
+		start := int64(i) * step
+		end := start + step - 1
+		if i == n-1 {
+			end = size - 1
+		}
+		segments = append(segments, &segment{Start: start, End: end})
+	}
+	return segments
+}
+
+// probeRanges asks for the first byte to learn whether the server supports
+// range requests and the file's size and validators
+func probeRanges(client *http.Client, url string, opts Options) (*rangeInfo, bool) {
+	req, err := newRequest(url, opts)
+	if err != nil {
+		return nil, false
+	}
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, false
+	}
+	_, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/")
+	size, err := strconv.ParseInt(total, 10, 64)
+	if !ok || err != nil || size <= 0 {
+		return nil, false
+	}
+	return &rangeInfo{
+		Size:         size,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, true
+}
+
+// segmentedDownload fetches url over several connections into one
+// preallocated file. It reports false when the download should fall back to
+// a single connection, e.g. when the server doesn't support ranges.
+func segmentedDownload(url string, opts Options) (bool, error) {
+	filename := outputFilename(url, opts)
+	if filename == "" {
+		return false, nil
+	}
+
+	client := newHTTPClient(opts)
+	info, ok := probeRanges(client, url, opts)
+	if !ok || info.Size < 2*minSegmentSize {
+		return false, nil
+	}
+
+	outputPath, err := prepareOutputPath(filename, opts)
+	if err != nil {
+		return true, err
+	}
+
+	meta := loadSegmentedPartial(outputPath, url, info)
+	if meta == nil {
+		removePartial(outputPath)
+		meta = &partMeta{
+			URL:          url,
+			ETag:         info.ETag,
+			LastModified: info.LastModified,
+			Size:         info.Size,
+			Segments:     splitSegments(info.Size, opts.Connections, minSegmentSize),
+		}
+	} else if opts.ShowProgress {
+		ui.ShowInfo(fmt.Sprintf("Resuming at %s", formatSize(meta.completed())))
+	}
+	if err := savePartMeta(outputPath, meta.snapshot()); err != nil {
+		return true, err
+	}
+
+	out, err := os.OpenFile(partPath(outputPath), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return true, fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := out.Truncate(info.Size); err != nil {
+		out.Close()
+		return true, fmt.Errorf("failed to allocate file: %w", err)
+	}
+
+	stop := make(chan struct{})
+	var reporter sync.WaitGroup
+	reporter.Add(1)
+	go func() {
+		defer reporter.Done()
+		reportSegments(outputPath, meta, len(meta.Segments), opts.ShowProgress, stop)
+	}()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(meta.Segments))
+	for i, seg := range meta.Segments {
+		if seg.remaining() == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, seg *segment) {
+			defer wg.Done()
+			errs[i] = fetchSegment(client, url, opts, meta.validator(), seg, out)
+		}(i, seg)
+	}
+	wg.Wait()
+	close(stop)
+	reporter.Wait()
+
+	closeErr := out.Close()
+	saveErr := savePartMeta(outputPath, meta.snapshot())
+
+	if err := errors.Join(errs...); err != nil {
+		if errors.Is(err, errFileChanged) {
+			removePartial(outputPath)
+			return false, nil
+		}
+		if saveErr != nil {
+			removePartial(outputPath)
+			return true, fmt.Errorf("download failed: %w", err)
+		}
+		return true, fmt.Errorf("download interrupted after %s, run again to resume: %w", formatSize(meta.completed()), err)
+	}
+	if closeErr != nil {
+		return true, fmt.Errorf("failed to write file: %w", closeErr)
+	}
+	return true, completeDownload(outputPath, opts, fmt.Sprintf("Downloaded: %s (%d bytes, %d connections)", outputPath, info.Size, len(meta.Segments)))
+}
+
+// loadSegmentedPartial returns the metadata of a segmented partial download
+// of the same remote file, or nil when there is none
+func loadSegmentedPartial(outputPath, url string, info *rangeInfo) *partMeta {
+	meta, err := readPartMeta(outputPath)
+	if err != nil || meta.URL != url || len(meta.Segments) == 0 || meta.Size != info.Size {
+		return nil
+	}
+	if meta.ETag != info.ETag || meta.LastModified != info.LastModified || meta.validator() == "" {
+		return nil
+	}
+	if s, err := os.Stat(partPath(outputPath)); err != nil || s.Size() != info.Size {
+		return nil
+	}
+	return meta
+}
+
+// fetchSegment downloads the rest of a segment, retrying with backoff
+func fetchSegment(client *http.Client, url string, opts Options, validator string, seg *segment, out *os.File) error {
+	var err error
+	for attempt := 0; attempt <= segmentRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt*attempt) * 250 * time.Millisecond)
+		}
+		if err = fetchSegmentOnce(client, url, opts, validator, seg, out); err == nil || errors.Is(err, errFileChanged) {
+			return err
+		}
+	}
+	return fmt.Errorf("bytes %d-%d: %w", seg.Start, seg.End, err)
+}
+
+// fetchSegmentOnce requests the rest of a segment and writes it in place
+func fetchSegmentOnce(client *http.Client, url string, opts Options, validator string, seg *segment, out *os.File) error {
+	offset := seg.Start + atomic.LoadInt64(&seg.Done)
+	if offset > seg.End {
+		return nil
+	}
+
+	req, err := newRequest(url, opts)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, seg.End))
+	if validator != "" {
+		req.Header.Set("If-Range", validator)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return errFileChanged
+	case resp.StatusCode != http.StatusPartialContent:
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	case contentRangeStart(resp.Header.Get("Content-Range")) != offset:
+		return fmt.Errorf("unexpected Content-Range: %s", resp.Header.Get("Content-Range"))
+	}
+
+	buf := make([]byte, 32*1024)
+	for offset <= seg.End {
+		n, readErr := resp.Body.Read(buf[:min(int64(len(buf)), seg.End-offset+1)])
+		if n > 0 {
+			if _, err := out.WriteAt(buf[:n], offset); err != nil {
+				return err
+			}
+			offset += int64(n)
+			atomic.AddInt64(&seg.Done, int64(n))
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	if offset <= seg.End {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// reportSegments shows aggregate progress and saves the segment state
+// until stop is closed
+func reportSegments(outputPath string, meta *partMeta, connections int, showProgress bool, stop <-chan struct{}) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	show := func() {
+		if showProgress {
+			current := meta.completed()
+			percent := float64(current) / float64(meta.Size) * 100
+			fmt.Printf("\rDownloading: %.1f%% (%d/%d bytes, %d connections)", percent, current, meta.Size, connections)
+		}
+	}
+	for {
+		select {
+		case <-stop:
+			show()
+			if showProgress {
+				fmt.Println()
+			}
+			return
+		case <-ticker.C:
+			show()
+			_ = savePartMeta(outputPath, meta.snapshot())
+		}
+	}
+}
+
+// completed returns the bytes written across all segments
+func (m *partMeta) completed() int64 {
+	var total int64
+	for _, s := range m.Segments {
+		total += atomic.LoadInt64(&s.Done)
+	}
+	return total
+}
+
+// snapshot returns a copy of m that is safe to encode while segments are written
+func (m *partMeta) snapshot() *partMeta {
+	c := *m
+	c.Segments = make([]*segment, len(m.Segments))
+	for i, s := range m.Segments {
+		c.Segments[i] = &segment{Start: s.Start, End: s.End, Done: atomic.LoadInt64(&s.Done)}
+	}
+	return &c
+}
@@ -0,0 +1,150 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dwirx/ghex/internal/ui"
+	"github.com/dwirx/ghex/internal/update"
+)
+
+// Verification describes how downloaded files are checked
+type Verification struct {
+	Algorithm    string                 // sha256, sha512 or blake2b; detected when empty
+	Checksum     string                 // expected digest of a single file
+	Checksums    []update.ChecksumEntry // expected digests by file name
+	Source       string                 // checksums file the entries came from
+	AllowMissing bool                   // files not listed in Checksums are not an error
+}
+
+// LoadChecksums reads a checksums file from a URL or a local path
+func LoadChecksums(source string, opts Options) (*Verification, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		data, err = fetchChecksums(source, opts)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	entries, err := update.ParseChecksumFile(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no checksums found in %s", source)
+	}
+	return &Verification{Checksums: entries, Source: source}, nil
+}
+
+// releaseChecksums loads the checksums asset name of a release
+func releaseChecksums(name, url string, headers map[string]string) (*Verification, error) {
+	opts := DefaultOptions()
+	opts.Headers = headers
+	verify, err := LoadChecksums(url, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", name, err)
+	}
+	verify.Source = name
+	// Signatures and similar extras are usually not listed
+	verify.AllowMissing = true
+	ui.ShowInfo(fmt.Sprintf("Verifying assets with %s", name))
+	return verify, nil
+}
+
+// fetchChecksums downloads a checksums file into memory
+func fetchChecksums(url string, opts Options) ([]byte, error) {
+	req, err := newRequest(url, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := newHTTPClient(opts).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	// Checksums files are small; anything larger is not one
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// expected returns the digest and algorithm a file named name must match.
+// An empty checksum means the file isn't listed and may be skipped.
+func (v *Verification) expected(name string) (string, string, error) {
+	if v.Checksum != "" {
+		algorithm := v.Algorithm
+		if algorithm == "" {
+			algorithm = update.DetectAlgorithm("", v.Checksum)
+		}
+		return v.Checksum, algorithm, nil
+	}
+
+	entry, ok := update.FindChecksumEntry(v.Checksums, name)
+	if !ok {
+		if v.AllowMissing {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("%w: %s not listed in %s", ErrNoChecksum, name, v.Source)
+	}
+	algorithm := entry.Algorithm
+	if algorithm == "" {
+		algorithm = v.Algorithm
+	}
+	if algorithm == "" {
+		algorithm = update.DetectAlgorithm(v.Source, entry.Checksum)
+	}
+	return entry.Checksum, algorithm, nil
+}
+
+// verifyFile checks path, which holds the download named name
+func (v *Verification) verifyFile(path, name string) error {
+	checksum, algorithm, err := v.expected(name)
+	if err != nil {
+		return err
+	}
+	if checksum == "" {
+		ui.ShowWarning(fmt.Sprintf("No checksum for %s, not verified", name))
+		return nil
+	}
+
+	if err := update.VerifyChecksumWith(path, checksum, algorithm); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	ui.ShowSuccess(fmt.Sprintf("Verified %s (%s)", name, algorithm))
+	return nil
+}
+
+// completeDownload verifies a finished partial download, moves it into
+// place, shows message and extracts it if requested. A download that fails
+// verification is deleted.
+func completeDownload(outputPath string, opts Options, message string) error {
+	if opts.Verify != nil {
+		if err := opts.Verify.verifyFile(partPath(outputPath), filepath.Base(outputPath)); err != nil {
+			removePartial(outputPath)
+			return err
+		}
+	}
+	if err := finishPartial(outputPath); err != nil {
+		return err
+	}
+	ui.ShowSuccess(message)
+
+	if opts.Extract != nil {
+		files, err := Extract(outputPath, *opts.Extract)
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", filepath.Base(outputPath), err)
+		}
+		ui.ShowSuccess(fmt.Sprintf("Extracted %d files from %s", len(files), filepath.Base(outputPath)))
+	}
+	return nil
+}